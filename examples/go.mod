@@ -6,7 +6,6 @@ replace github.com/seqsense/aws-iot-device-sdk-go/v6 => ../
 
 require (
 	github.com/at-wat/mqtt-go v0.19.6
-	github.com/go-viper/mapstructure/v2 v2.5.0
 	github.com/seqsense/aws-iot-device-sdk-go/v6 v6.0.0-00010101000000-000000000000
 )
 
@@ -21,7 +21,6 @@ import (
 	"time"
 
 	"github.com/at-wat/mqtt-go"
-	"github.com/go-viper/mapstructure/v2"
 	awsiotdev "github.com/seqsense/aws-iot-device-sdk-go/v6"
 	"github.com/seqsense/aws-iot-device-sdk-go/v6/shadow"
 )
@@ -88,6 +87,9 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	defer func() {
+		_ = s.Close()
+	}()
 	s.OnError(func(err error) {
 		fmt.Printf("async error: %v\n", err)
 	})
@@ -129,13 +131,13 @@ func main() {
 	fmt.Printf("document:%s", prettyDump(doc))
 
 	// Document stores thing state as map[string]interface{}.
-	// You may want to use github.com/go-viper/mapstructure/v2 to
-	// converts state to the given struct.
-	var typedState sampleState
-	if err := mapstructure.Decode(doc.State.Desired, &typedState); err != nil {
+	// TypedShadow converts state to the given struct.
+	typed := shadow.NewTyped[sampleState, sampleState](s)
+	typedDoc, err := typed.Document()
+	if err != nil {
 		panic(err)
 	}
-	fmt.Printf("\ndocument.State.Desired (typed): %+v\n", typedState)
+	fmt.Printf("\ndocument.State.Desired (typed): %+v\n", typedDoc.Desired)
 
 	time.Sleep(time.Second)
 
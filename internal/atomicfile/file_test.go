@@ -0,0 +1,58 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+
+	for _, data := range []string{"first", "second"} {
+		if err := WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != data {
+			t.Errorf("Expected %q, got %q", data, string(b))
+		}
+		if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+			t.Errorf("Temporary file must be renamed, got: %v", err)
+		}
+	}
+
+	if err := Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("File must be removed, got: %v", err)
+	}
+	if err := Remove(path); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got: %v", err)
+	}
+}
+
+func TestWriteFile_Error(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dir", "file")
+	if err := WriteFile(path, []byte("data"), 0600); err == nil {
+		t.Fatal("Expected error")
+	}
+}
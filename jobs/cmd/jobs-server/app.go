@@ -0,0 +1,107 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+
+	mqtt "github.com/at-wat/mqtt-go"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/jobs/server"
+)
+
+func app(ctx context.Context, args []string) error {
+	f := flag.NewFlagSet(args[0], flag.ExitOnError)
+	var (
+		mqttURL       = f.String("mqtt-url", "mqtt://localhost:1883", "URL of the MQTT broker")
+		apiAddr       = f.String("api-addr", ":80", "Address and port of API endpoint")
+		checkInterval = f.Duration("timeout-check-interval", time.Second, "Interval to check job execution timeouts")
+	)
+	f.Parse(args[1:])
+
+	cli, err := mqtt.NewReconnectClient(
+		&mqtt.URLDialer{
+			URL: *mqttURL,
+			Options: []mqtt.DialOption{
+				mqtt.WithConnStateHandler(func(s mqtt.ConnState, err error) {
+					log.Printf("info: MQTT connection state changed (%s)", s)
+				}),
+			},
+		},
+		mqtt.WithReconnectWait(50*time.Millisecond, 2*time.Second),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create MQTT client: %w", err)
+	}
+
+	ctxConnect, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := cli.Connect(ctxConnect,
+		fmt.Sprintf("jobs-server-%d", rand.Int()),
+		mqtt.WithKeepAlive(30),
+	); err != nil {
+		return fmt.Errorf("failed to start MQTT reconnect client: %w", err)
+	}
+
+	ctx, cancelServer := context.WithCancel(ctx)
+	defer cancelServer()
+
+	srv, err := server.New(ctx, cli, server.WithTimeoutCheckInterval(*checkInterval))
+	if err != nil {
+		return fmt.Errorf("failed to start jobs server: %w", err)
+	}
+	cli.Handle(srv)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", server.NewAPIHandler(srv))
+	mux.HandleFunc("/healthcheck", func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, "ok")
+	})
+	s := &http.Server{
+		Addr:         *apiAddr,
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	chErr := make(chan error, 1)
+	go func() {
+		chErr <- s.ListenAndServe()
+	}()
+
+	select {
+	case err := <-chErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Println("error:", err)
+		}
+	case <-ctx.Done():
+		if err := s.Close(); err != nil {
+			log.Println("error:", err)
+		}
+	}
+	if err := cli.Disconnect(context.Background()); err != nil {
+		log.Println("error:", err)
+	}
+	return nil
+}
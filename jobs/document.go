@@ -0,0 +1,170 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+// DocumentError is returned if the job document can not be decoded
+// to the Go type.
+type DocumentError struct {
+	// Kind is a discriminator value of the document.
+	// Empty if the document is decoded without DocumentRegistry.
+	Kind string
+	// Field is a dot separated path of the field failed to decode.
+	// Empty if the error is not related to a specific field.
+	Field string
+	Err   error
+}
+
+// Error implements error interface.
+func (e *DocumentError) Error() string {
+	msg := "invalid job document"
+	if e.Kind != "" {
+		msg += fmt.Sprintf(" of kind %q", e.Kind)
+	}
+	if e.Field != "" {
+		msg += fmt.Sprintf(" at field %q", e.Field)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying decode error.
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// Is implements errors.Is interface.
+// DocumentError matches ErrInvalidDocument.
+func (e *DocumentError) Is(target error) bool {
+	return target == ErrInvalidDocument
+}
+
+func newDocumentError(kind string, err error) *DocumentError {
+	e := &DocumentError{Kind: kind, Err: err}
+	var ute *json.UnmarshalTypeError
+	if errors.As(err, &ute) {
+		e.Field = ute.Field
+	}
+	return e
+}
+
+// DocumentRegistry maps the discriminator field of the job document
+// to the Go type.
+// Documents of the unregistered kinds are returned as json.RawMessage.
+type DocumentRegistry struct {
+	field string
+
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+}
+
+// NewDocumentRegistry creates DocumentRegistry using the given top-level
+// field of the document (e.g. "operation") as a discriminator.
+func NewDocumentRegistry(field string) *DocumentRegistry {
+	return &DocumentRegistry{
+		field: field,
+		types: make(map[string]reflect.Type),
+	}
+}
+
+// Register registers the type of the document of the kind.
+// Document is decoded to a pointer of the type of v.
+// e.g. to receive the document of {"operation": "reboot", ...} as *RebootDocument:
+//
+//	r.Register("reboot", RebootDocument{})
+func (r *DocumentRegistry) Register(kind string, v interface{}) {
+	r.mu.Lock()
+	r.types[kind] = reflect.TypeOf(v)
+	r.mu.Unlock()
+}
+
+// Decode decodes the job document to the registered type.
+// json.RawMessage is returned if the kind is not registered or
+// the document doesn't have the discriminator field.
+func (r *DocumentRegistry) Decode(b []byte) (interface{}, error) {
+	raw := append(json.RawMessage{}, b...)
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return raw, nil
+	}
+	var kind string
+	if err := json.Unmarshal(fields[r.field], &kind); err != nil {
+		return raw, nil
+	}
+	r.mu.RLock()
+	t, ok := r.types[kind]
+	r.mu.RUnlock()
+	if !ok {
+		return raw, nil
+	}
+	v := reflect.New(t).Interface()
+	if err := json.Unmarshal(b, v); err != nil {
+		return nil, newDocumentError(kind, err)
+	}
+	return v, nil
+}
+
+// DocumentAs returns the job document of the execution as *T.
+// The document already decoded as *T is returned as is and
+// the others are converted through JSON.
+func DocumentAs[T any](je *JobExecution) (*T, error) {
+	var b []byte
+	switch d := je.JobDocument.(type) {
+	case *T:
+		return d, nil
+	case nil:
+		return nil, ioterr.New(ErrInvalidDocument, "converting empty job document")
+	case json.RawMessage:
+		b = d
+	case *json.RawMessage:
+		b = *d
+	default:
+		var err error
+		if b, err = json.Marshal(d); err != nil {
+			return nil, ioterr.New(err, "marshaling job document")
+		}
+	}
+	v := new(T)
+	if err := json.Unmarshal(b, v); err != nil {
+		return nil, newDocumentError("", err)
+	}
+	return v, nil
+}
+
+// Describe calls DescribeJob and returns the job document as *T.
+// nil is returned if NextJobID is specified and there is no pending job.
+func Describe[T any](ctx context.Context, j Jobs, id string, opt ...DescribeJobOption) (*JobExecution, *T, error) {
+	je, err := j.DescribeJob(ctx, id, opt...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if je == nil {
+		return nil, nil, nil
+	}
+	doc, err := DocumentAs[T](je)
+	if err != nil {
+		return nil, nil, err
+	}
+	return je, doc, nil
+}
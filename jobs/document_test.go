@@ -0,0 +1,201 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type rebootDocument struct {
+	Operation string `json:"operation"`
+	Delay     int    `json:"delay"`
+}
+
+type updateDocument struct {
+	Operation string `json:"operation"`
+	Package   struct {
+		Name    string `json:"name"`
+		Version int    `json:"version"`
+	} `json:"package"`
+}
+
+func TestDocumentRegistry(t *testing.T) {
+	r := NewDocumentRegistry("operation")
+	r.Register("reboot", rebootDocument{})
+	r.Register("update", updateDocument{})
+
+	update := &updateDocument{Operation: "update"}
+	update.Package.Name = "app"
+	update.Package.Version = 2
+
+	testCases := map[string]struct {
+		input    string
+		expected interface{}
+		kind     string
+		field    string
+	}{
+		"Reboot": {
+			input:    `{"operation":"reboot","delay":10}`,
+			expected: &rebootDocument{Operation: "reboot", Delay: 10},
+		},
+		"Update": {
+			input:    `{"operation":"update","package":{"name":"app","version":2}}`,
+			expected: update,
+		},
+		"UnknownKind": {
+			input:    `{"operation":"unknown","a":1}`,
+			expected: json.RawMessage(`{"operation":"unknown","a":1}`),
+		},
+		"NoDiscriminator": {
+			input:    `{"a":1}`,
+			expected: json.RawMessage(`{"a":1}`),
+		},
+		"NonStringDiscriminator": {
+			input:    `{"operation":1}`,
+			expected: json.RawMessage(`{"operation":1}`),
+		},
+		"NonObject": {
+			input:    `"reboot"`,
+			expected: json.RawMessage(`"reboot"`),
+		},
+		"InvalidField": {
+			input: `{"operation":"reboot","delay":"10"}`,
+			kind:  "reboot",
+			field: "delay",
+		},
+		"InvalidNestedField": {
+			input: `{"operation":"update","package":{"name":"app","version":"2"}}`,
+			kind:  "update",
+			field: "package.version",
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			doc, err := r.Decode([]byte(tt.input))
+			if tt.field != "" {
+				var de *DocumentError
+				if !errors.As(err, &de) {
+					t.Fatalf("Expected DocumentError, got: %v", err)
+				}
+				if !errors.Is(err, ErrInvalidDocument) {
+					t.Errorf("Expected to match %v", ErrInvalidDocument)
+				}
+				if de.Kind != tt.kind || de.Field != tt.field {
+					t.Errorf("Expected kind %q, field %q, got: %q, %q", tt.kind, tt.field, de.Kind, de.Field)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.expected, doc) {
+				t.Errorf("Expected: %#v, got: %#v", tt.expected, doc)
+			}
+		})
+	}
+}
+
+func TestDocumentAs(t *testing.T) {
+	typed := &rebootDocument{Operation: "reboot", Delay: 1}
+
+	testCases := map[string]struct {
+		document interface{}
+		expected *rebootDocument
+		err      error
+		field    string
+	}{
+		"Typed": {
+			document: typed,
+			expected: typed,
+		},
+		"RawMessage": {
+			document: json.RawMessage(`{"operation":"reboot","delay":1}`),
+			expected: typed,
+		},
+		"Map": {
+			document: map[string]interface{}{"operation": "reboot", "delay": 1},
+			expected: typed,
+		},
+		"Empty": {
+			err: ErrInvalidDocument,
+		},
+		"InvalidField": {
+			document: map[string]interface{}{"operation": "reboot", "delay": "1"},
+			err:      ErrInvalidDocument,
+			field:    "delay",
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			doc, err := DocumentAs[rebootDocument](&JobExecution{JobDocument: tt.document})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error: %v, got: %v", tt.err, err)
+			}
+			if tt.field != "" {
+				var de *DocumentError
+				if !errors.As(err, &de) || de.Field != tt.field {
+					t.Errorf("Expected error at field %q, got: %v", tt.field, err)
+				}
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(tt.expected, doc) {
+				t.Errorf("Expected: %+v, got: %+v", tt.expected, doc)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	j := newStubJobs(&JobExecution{
+		JobID:       "job1",
+		JobDocument: map[string]interface{}{"operation": "reboot", "delay": 5},
+	})
+	je, doc, err := Describe[rebootDocument](ctx, j, "job1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if je.JobID != "job1" {
+		t.Errorf("Expected job ID: job1, got: %s", je.JobID)
+	}
+	if expected := (&rebootDocument{Operation: "reboot", Delay: 5}); !reflect.DeepEqual(expected, doc) {
+		t.Errorf("Expected: %+v, got: %+v", expected, doc)
+	}
+
+	if _, _, err := Describe[rebootDocument](ctx, j, "job2"); err == nil {
+		t.Error("Expected error")
+	}
+
+	t.Run("NoNextJob", func(t *testing.T) {
+		je, doc, err := Describe[rebootDocument](ctx, j, NextJobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if je != nil || doc != nil {
+			t.Errorf("Expected nil, got: %+v, %+v", je, doc)
+		}
+	})
+}
@@ -18,3 +18,14 @@ import "errors"
 
 // ErrInvalidResponse is returned if failed to parse response from AWS IoT.
 var ErrInvalidResponse = errors.New("invalid response from AWS IoT")
+
+// ErrJobRejected should be wrapped and returned by JobHandler to reject the job.
+var ErrJobRejected = errors.New("job rejected")
+
+// ErrVersionMismatch is matched by ErrorResponse if the expected version
+// of the job execution doesn't match.
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// ErrInvalidDocument is matched by DocumentError and returned if
+// the job document can not be converted to the Go type.
+var ErrInvalidDocument = errors.New("invalid job document")
@@ -18,9 +18,11 @@ package jobs
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"sync"
+	"sync/atomic"
 
 	"github.com/at-wat/mqtt-go"
 
@@ -38,9 +40,21 @@ type Jobs interface {
 	// GetPendingJobs gets list of pending jobs.
 	GetPendingJobs(ctx context.Context) (map[JobExecutionState][]JobExecutionSummary, error)
 	// DescribeJob gets details of specific job.
-	DescribeJob(ctx context.Context, id string) (*JobExecution, error)
+	// NextJobID can be used as id to get the next pending job.
+	// nil is returned if NextJobID is specified and there is no pending job.
+	DescribeJob(ctx context.Context, id string, opt ...DescribeJobOption) (*JobExecution, error)
 	// UpdateJob updates job status.
+	// Status and VersionNumber of the given JobExecution are updated to
+	// the latest ones on success and on ErrVersionMismatch.
+	// Use WithRetryOnVersionMismatch to retry the update on version mismatch.
 	UpdateJob(ctx context.Context, j *JobExecution, s JobExecutionState, opt ...UpdateJobOption) error
+	// StartNextPendingJob gets and starts the next pending job.
+	// Status details and step timeout can be set by UpdateJobOption.
+	// nil is returned if there is no pending job.
+	StartNextPendingJob(ctx context.Context, opt ...UpdateJobOption) (*JobExecution, error)
+	// OnNextJobChange sets handler for the change of the next pending job.
+	// nil is passed if there is no pending job.
+	OnNextJobChange(func(*JobExecution))
 }
 
 type jobs struct {
@@ -51,13 +65,14 @@ type jobs struct {
 	chResps     map[string]chan interface{}
 	onError     func(err error)
 	onJobChange func(map[JobExecutionState][]JobExecutionSummary)
-	msgToken    int
+	onNextJob   func(*JobExecution)
+	msgToken    uint32
 	opts        Options
 }
 
 func (j *jobs) token() string {
-	j.msgToken++
-	return fmt.Sprintf("%x", j.msgToken)
+	token := atomic.AddUint32(&j.msgToken, 1)
+	return fmt.Sprintf("%x", token)
 }
 
 func (j *jobs) topic(operation string) string {
@@ -86,12 +101,15 @@ func NewWithOptions(ctx context.Context, cli awsiotdev.Device, opts ...Option) (
 		handler mqtt.Handler
 	}{
 		{j.topic("notify"), mqtt.HandlerFunc(j.notify)},
+		{j.topic("notify-next"), mqtt.HandlerFunc(j.notifyNext)},
 		{j.topic("+/get/accepted"), mqtt.HandlerFunc(j.getJobAccepted)},
 		{j.topic("+/get/rejected"), mqtt.HandlerFunc(j.rejected)},
 		{j.topic("+/update/accepted"), mqtt.HandlerFunc(j.updateJobAccepted)},
 		{j.topic("+/update/rejected"), mqtt.HandlerFunc(j.rejected)},
 		{j.topic("get/accepted"), mqtt.HandlerFunc(j.getAccepted)},
 		{j.topic("get/rejected"), mqtt.HandlerFunc(j.rejected)},
+		{j.topic("start-next/accepted"), mqtt.HandlerFunc(j.startNextAccepted)},
+		{j.topic("start-next/rejected"), mqtt.HandlerFunc(j.rejected)},
 	} {
 		if err := j.ServeMux.Handle(sub.topic, sub.handler); err != nil {
 			return nil, ioterr.New(err, "registering message handlers")
@@ -100,8 +118,11 @@ func NewWithOptions(ctx context.Context, cli awsiotdev.Device, opts ...Option) (
 
 	_, err := cli.Subscribe(ctx,
 		mqtt.Subscription{Topic: j.topic("notify"), QoS: mqtt.QoS1},
+		mqtt.Subscription{Topic: j.topic("notify-next"), QoS: mqtt.QoS1},
+		mqtt.Subscription{Topic: j.topic("start-next/#"), QoS: mqtt.QoS1},
 		mqtt.Subscription{Topic: j.topic("get/#"), QoS: mqtt.QoS1},
 		mqtt.Subscription{Topic: j.topic("+/get/#"), QoS: mqtt.QoS1},
+		mqtt.Subscription{Topic: j.topic("+/update/#"), QoS: mqtt.QoS1},
 	)
 	if err != nil {
 		return nil, ioterr.New(err, "subscribing jobs topics")
@@ -124,6 +145,71 @@ func (j *jobs) notify(msg *mqtt.Message) {
 	}
 }
 
+func (j *jobs) notifyNext(msg *mqtt.Message) {
+	m := &nextJobExecutionChangedMessage{}
+	if err := json.Unmarshal(msg.Payload, m); err != nil {
+		j.handleError(ioterr.New(err, "unmarshaling next job execution changed message"))
+		return
+	}
+	je, err := j.jobExecution(m.Execution)
+	if err != nil {
+		j.handleError(ioterr.New(err, "unmarshaling next job execution"))
+		return
+	}
+	j.mu.Lock()
+	cb := j.onNextJob
+	j.mu.Unlock()
+
+	if cb != nil {
+		go cb(je)
+	}
+}
+
+// jobExecution unmarshals JobExecution.
+// nil is returned if the execution is empty.
+func (j *jobs) jobExecution(b json.RawMessage) (*JobExecution, error) {
+	if len(b) == 0 || string(b) == "null" {
+		return nil, nil
+	}
+	je := &JobExecution{JobDocument: j.newJobDocument()}
+	if err := json.Unmarshal(b, je); err != nil {
+		return nil, err
+	}
+	if err := j.decodeJobDocument(je); err != nil {
+		return nil, err
+	}
+	return je, nil
+}
+
+// newJobDocument returns the value to unmarshal the job document into.
+func (j *jobs) newJobDocument() interface{} {
+	switch {
+	case j.opts.DocumentRegistry != nil:
+		return &json.RawMessage{}
+	case j.opts.JobDocumentType != nil:
+		return reflect.New(reflect.TypeOf(j.opts.JobDocumentType)).Interface()
+	}
+	return nil
+}
+
+// decodeJobDocument decodes the job document by DocumentRegistry.
+func (j *jobs) decodeJobDocument(je *JobExecution) error {
+	if j.opts.DocumentRegistry == nil {
+		return nil
+	}
+	raw, _ := je.JobDocument.(*json.RawMessage)
+	if raw == nil || len(*raw) == 0 {
+		je.JobDocument = nil
+		return nil
+	}
+	doc, err := j.opts.DocumentRegistry.Decode(*raw)
+	if err != nil {
+		return err
+	}
+	je.JobDocument = doc
+	return nil
+}
+
 func (j *jobs) GetPendingJobs(ctx context.Context) (map[JobExecutionState][]JobExecutionSummary, error) {
 	req := &simpleRequest{ClientToken: j.token()}
 	ch := make(chan interface{}, 1)
@@ -168,9 +254,14 @@ func (j *jobs) GetPendingJobs(ctx context.Context) (map[JobExecutionState][]JobE
 	}
 }
 
-func (j *jobs) DescribeJob(ctx context.Context, id string) (*JobExecution, error) {
+func (j *jobs) DescribeJob(ctx context.Context, id string, opt ...DescribeJobOption) (*JobExecution, error) {
+	opts := &DescribeJobOptions{}
+	for _, o := range opt {
+		o(opts)
+	}
 	req := &describeJobExecutionRequest{
-		IncludeJobDocument: true,
+		ExecutionNumber:    opts.ExecutionNumber,
+		IncludeJobDocument: !opts.ExcludeJobDocument,
 		ClientToken:        j.token(),
 	}
 	ch := make(chan interface{}, 1)
@@ -203,6 +294,10 @@ func (j *jobs) DescribeJob(ctx context.Context, id string) (*JobExecution, error
 	case res := <-ch:
 		switch r := res.(type) {
 		case *describeJobExecutionResponse:
+			if r.Execution.JobID == "" {
+				// Execution is omitted if there is no next pending job.
+				return nil, nil
+			}
 			return &r.Execution, nil
 		case *ErrorResponse:
 			return nil, r
@@ -221,12 +316,51 @@ func (j *jobs) UpdateJob(ctx context.Context, je *JobExecution, s JobExecutionSt
 	for _, o := range opt {
 		o(opts)
 	}
+	for i := 0; ; i++ {
+		err := j.updateJob(ctx, je, s, opts)
+		if err == nil || !errors.Is(err, ErrVersionMismatch) {
+			return err
+		}
+		if rerr := j.refresh(ctx, je, err); rerr != nil {
+			return err
+		}
+		if i >= opts.RetryOnVersionMismatch || (je.Status != Queued && je.Status != InProgress) {
+			return err
+		}
+	}
+}
+
+// refresh updates the job execution by the execution state of the
+// version mismatch error.
+func (j *jobs) refresh(ctx context.Context, je *JobExecution, err error) error {
+	var er *ErrorResponse
+	if errors.As(err, &er) && er.ExecutionState.VersionNumber > 0 {
+		je.Status = er.ExecutionState.Status
+		je.StatusDetails = er.ExecutionState.StatusDetails
+		je.VersionNumber = er.ExecutionState.VersionNumber
+		return nil
+	}
+	latest, derr := j.DescribeJob(ctx, je.JobID)
+	if derr != nil {
+		return derr
+	}
+	if latest == nil {
+		return ioterr.New(ErrInvalidResponse, "describing job")
+	}
+	je.Status = latest.Status
+	je.StatusDetails = latest.StatusDetails
+	je.VersionNumber = latest.VersionNumber
+	return nil
+}
+
+func (j *jobs) updateJob(ctx context.Context, je *JobExecution, s JobExecutionState, opts *UpdateJobOptions) error {
 	req := &updateJobExecutionRequest{
-		Status:               s,
-		StatusDetails:        opts.Details,
-		ExpectedVersion:      je.VersionNumber,
-		StepTimeoutInMinutes: opts.TimeoutMinutes,
-		ClientToken:          j.token(),
+		Status:                   s,
+		StatusDetails:            opts.Details,
+		ExpectedVersion:          je.VersionNumber,
+		IncludeJobExecutionState: true,
+		StepTimeoutInMinutes:     opts.TimeoutMinutes,
+		ClientToken:              j.token(),
 	}
 	ch := make(chan interface{}, 1)
 	j.mu.Lock()
@@ -258,6 +392,11 @@ func (j *jobs) UpdateJob(ctx context.Context, je *JobExecution, s JobExecutionSt
 	case res := <-ch:
 		switch r := res.(type) {
 		case *updateJobExecutionResponse:
+			if st := r.ExecutionState; st != nil {
+				je.Status = st.Status
+				je.StatusDetails = st.StatusDetails
+				je.VersionNumber = st.VersionNumber
+			}
 			return nil
 		case *ErrorResponse:
 			return r
@@ -269,6 +408,59 @@ func (j *jobs) UpdateJob(ctx context.Context, je *JobExecution, s JobExecutionSt
 	}
 }
 
+func (j *jobs) StartNextPendingJob(ctx context.Context, opt ...UpdateJobOption) (*JobExecution, error) {
+	opts := &UpdateJobOptions{
+		Details: make(map[string]string),
+	}
+	for _, o := range opt {
+		o(opts)
+	}
+	req := &startNextPendingJobExecutionRequest{
+		StatusDetails:        opts.Details,
+		StepTimeoutInMinutes: opts.TimeoutMinutes,
+		ClientToken:          j.token(),
+	}
+	ch := make(chan interface{}, 1)
+	j.mu.Lock()
+	j.chResps[req.ClientToken] = ch
+	j.mu.Unlock()
+	defer func() {
+		j.mu.Lock()
+		delete(j.chResps, req.ClientToken)
+		j.mu.Unlock()
+	}()
+
+	breq, err := json.Marshal(req)
+	if err != nil {
+		return nil, ioterr.New(err, "marshaling request")
+	}
+	if err := j.cli.Publish(ctx,
+		&mqtt.Message{
+			Topic:   j.topic("start-next"),
+			QoS:     mqtt.QoS1,
+			Payload: breq,
+		},
+	); err != nil {
+		return nil, ioterr.New(err, "sending request")
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ioterr.New(ctx.Err(), "starting next pending job")
+	case res := <-ch:
+		switch r := res.(type) {
+		case *startNextPendingJobExecutionResponse:
+			return r.execution, nil
+		case *ErrorResponse:
+			return nil, r
+		case error:
+			return nil, ioterr.New(r, "starting next pending job")
+		default:
+			return nil, ioterr.New(ErrInvalidResponse, "starting next pending job")
+		}
+	}
+}
+
 func (j *jobs) handleResponse(r interface{}) {
 	token, ok := clientToken(r)
 	if !ok {
@@ -318,10 +510,12 @@ func (j *jobs) getAccepted(msg *mqtt.Message) {
 
 func (j *jobs) getJobAccepted(msg *mqtt.Message) {
 	res := &describeJobExecutionResponse{}
-	if j.opts.JobDocumentType != nil {
-		res.Execution.JobDocument = reflect.New(reflect.TypeOf(j.opts.JobDocumentType)).Interface()
+	res.Execution.JobDocument = j.newJobDocument()
+	err := json.Unmarshal(msg.Payload, res)
+	if err == nil {
+		err = j.decodeJobDocument(&res.Execution)
 	}
-	if err := json.Unmarshal(msg.Payload, res); err != nil {
+	if err != nil {
 		err := ioterr.Newf(err, "unmarshaling describe job execution response: %s", string(msg.Payload))
 		if !j.handleErrorResponse(msg.Payload, err) {
 			j.handleError(err)
@@ -343,6 +537,22 @@ func (j *jobs) updateJobAccepted(msg *mqtt.Message) {
 	j.handleResponse(res)
 }
 
+func (j *jobs) startNextAccepted(msg *mqtt.Message) {
+	res := &startNextPendingJobExecutionResponse{}
+	err := json.Unmarshal(msg.Payload, res)
+	if err == nil {
+		res.execution, err = j.jobExecution(res.Execution)
+	}
+	if err != nil {
+		err := ioterr.Newf(err, "unmarshaling start next pending job execution response: %s", string(msg.Payload))
+		if !j.handleErrorResponse(msg.Payload, err) {
+			j.handleError(err)
+		}
+		return
+	}
+	j.handleResponse(res)
+}
+
 func (j *jobs) rejected(msg *mqtt.Message) {
 	e := &ErrorResponse{}
 	if err := json.Unmarshal(msg.Payload, e); err != nil {
@@ -375,3 +585,9 @@ func (j *jobs) OnJobChange(cb func(map[JobExecutionState][]JobExecutionSummary))
 	j.onJobChange = cb
 	j.mu.Unlock()
 }
+
+func (j *jobs) OnNextJobChange(cb func(*JobExecution)) {
+	j.mu.Lock()
+	j.onNextJob = cb
+	j.mu.Unlock()
+}
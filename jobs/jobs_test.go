@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -44,6 +45,10 @@ func (d *mockDevice) ThingName() string {
 	return "test"
 }
 
+type testJobDocument struct {
+	Key string `json:"key"`
+}
+
 type invalidResponse struct {
 	ClientToken    string `json:"clientToken"`
 	ExecutionState bool   `json:"executionState"`
@@ -116,6 +121,186 @@ func TestNotify(t *testing.T) {
 	}
 }
 
+func TestNotifyNext(t *testing.T) {
+	testCases := map[string]struct {
+		message  string
+		expected *JobExecution
+	}{
+		"Next": {
+			message: `{"timestamp":1,"execution":{"jobId":"testID","status":"QUEUED","jobDocument":{"key":"value"},"versionNumber":1}}`,
+			expected: &JobExecution{
+				JobID:         "testID",
+				Status:        Queued,
+				JobDocument:   &testJobDocument{Key: "value"},
+				VersionNumber: 1,
+			},
+		},
+		"NoNext": {
+			message: `{"timestamp":1}`,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			cli := &mockDevice{mockClient: &mockmqtt.Client{}}
+			j, err := NewWithOptions(ctx, cli, WithJobDocumentType(testJobDocument{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cli.Handle(j)
+
+			done := make(chan struct{})
+			j.OnNextJobChange(func(je *JobExecution) {
+				if !reflect.DeepEqual(testCase.expected, je) {
+					t.Errorf("Expected job: %+v, got: %+v", testCase.expected, je)
+				}
+				close(done)
+			})
+
+			cli.Serve(&mqtt.Message{
+				Topic:   j.(*jobs).topic("notify-next"),
+				Payload: []byte(testCase.message),
+			})
+
+			select {
+			case <-done:
+			case <-ctx.Done():
+				t.Fatal("Timeout")
+			}
+		})
+	}
+}
+
+func TestStartNextPendingJob(t *testing.T) {
+	testCases := map[string]struct {
+		publishFailure  bool
+		options         []UpdateJobOption
+		expectedRequest interface{}
+		response        string
+		responseTopic   string
+		expected        *JobExecution
+		err             error
+	}{
+		"Success": {
+			options: []UpdateJobOption{
+				WithTimeout(10),
+				WithDetail("testKey", "testValue"),
+			},
+			expectedRequest: &startNextPendingJobExecutionRequest{
+				StatusDetails:        map[string]string{"testKey": "testValue"},
+				StepTimeoutInMinutes: 10,
+			},
+			response:      `{"execution":{"jobId":"testID","status":"IN_PROGRESS","jobDocument":{"key":"value"},"versionNumber":2}}`,
+			responseTopic: "start-next/accepted",
+			expected: &JobExecution{
+				JobID:         "testID",
+				Status:        InProgress,
+				JobDocument:   &testJobDocument{Key: "value"},
+				VersionNumber: 2,
+			},
+		},
+		"NoPendingJob": {
+			expectedRequest: &startNextPendingJobExecutionRequest{},
+			response:        `{}`,
+			responseTopic:   "start-next/accepted",
+		},
+		"Error": {
+			expectedRequest: &startNextPendingJobExecutionRequest{},
+			response:        `{"code":"Failed","message":"Reason"}`,
+			responseTopic:   "start-next/rejected",
+			err: &ErrorResponse{
+				Code:    "Failed",
+				Message: "Reason",
+			},
+		},
+		"PublishError": {
+			publishFailure: true,
+			err:            errPublish,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			var j Jobs
+			cli := &mockDevice{
+				mockClient: &mockmqtt.Client{
+					PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+						if testCase.publishFailure {
+							return errPublish
+						}
+						if topic := j.(*jobs).topic("start-next"); msg.Topic != topic {
+							t.Errorf("Expected topic: %s, got: %s", topic, msg.Topic)
+						}
+						req := &startNextPendingJobExecutionRequest{}
+						if err := json.Unmarshal(msg.Payload, req); err != nil {
+							t.Error(err)
+							cancel()
+							return err
+						}
+						clientToken := req.ClientToken
+						setClientToken(req, "")
+						if !reflect.DeepEqual(testCase.expectedRequest, req) {
+							t.Errorf("Expected request: %+v, got: %+v", testCase.expectedRequest, req)
+						}
+						res := map[string]interface{}{}
+						if err := json.Unmarshal([]byte(testCase.response), &res); err != nil {
+							t.Error(err)
+							cancel()
+							return err
+						}
+						res["clientToken"] = clientToken
+						bres, err := json.Marshal(res)
+						if err != nil {
+							t.Error(err)
+							cancel()
+							return err
+						}
+						j.Serve(&mqtt.Message{
+							Topic:   j.(*jobs).topic(testCase.responseTopic),
+							Payload: bres,
+						})
+						return nil
+					},
+				},
+			}
+			var err error
+			j, err = NewWithOptions(ctx, cli, WithJobDocumentType(testJobDocument{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cli.Handle(j)
+
+			je, err := j.StartNextPendingJob(ctx, testCase.options...)
+			if err != nil {
+				setClientToken(err, "")
+				var er *ErrorResponse
+				if errors.As(err, &er) {
+					if !reflect.DeepEqual(testCase.err, er) {
+						t.Fatalf("Expected error: %v, got: %v", testCase.err, err)
+					}
+				} else if !errors.Is(err, testCase.err) {
+					t.Fatalf("Expected error: %v, got: %v", testCase.err, err)
+				}
+				return
+			}
+			if testCase.err != nil {
+				t.Fatalf("Expected error: %v", testCase.err)
+			}
+			if !reflect.DeepEqual(testCase.expected, je) {
+				t.Errorf("Expected job: %+v, got: %+v", testCase.expected, je)
+			}
+		})
+	}
+}
+
 func TestGetPendingJobs(t *testing.T) {
 	testCases := map[string]struct {
 		publishFailure bool
@@ -217,6 +402,12 @@ func TestGetPendingJobs(t *testing.T) {
 	}
 }
 
+func testDocumentRegistry() *DocumentRegistry {
+	r := NewDocumentRegistry("operation")
+	r.Register("reboot", rebootDocument{})
+	return r
+}
+
 func TestDescribeJob(t *testing.T) {
 	type jobDoc struct {
 		Hoge string
@@ -234,6 +425,7 @@ func TestDescribeJob(t *testing.T) {
 		errIs           error
 		errType         error
 		errResponse     *ErrorResponse
+		describeOptions []DescribeJobOption
 	}{
 		"Success": {
 			id: "testID",
@@ -320,6 +512,120 @@ func TestDescribeJob(t *testing.T) {
 			},
 			errType: &ioterr.Error{},
 		},
+		"WithOptions": {
+			id: "testID",
+			expectedRequest: &describeJobExecutionRequest{
+				ExecutionNumber:    2,
+				IncludeJobDocument: false,
+			},
+			response: &describeJobExecutionResponse{
+				Execution: JobExecution{
+					JobID:                            "testID",
+					Status:                           InProgress,
+					ExecutionNumber:                  2,
+					ApproximateSecondsBeforeTimedOut: 30,
+				},
+			},
+			responseTopic: "testID/get/accepted",
+			expected: &JobExecution{
+				JobID:                            "testID",
+				Status:                           InProgress,
+				ExecutionNumber:                  2,
+				ApproximateSecondsBeforeTimedOut: 30,
+			},
+			describeOptions: []DescribeJobOption{
+				WithExecutionNumber(2),
+				WithoutJobDocument(),
+			},
+		},
+		"Next": {
+			id: NextJobID,
+			expectedRequest: &describeJobExecutionRequest{
+				IncludeJobDocument: true,
+			},
+			response: &describeJobExecutionResponse{
+				Execution: JobExecution{
+					JobID:       "testID",
+					JobDocument: "doc",
+				},
+			},
+			responseTopic: NextJobID + "/get/accepted",
+			expected: &JobExecution{
+				JobID:       "testID",
+				JobDocument: "doc",
+			},
+		},
+		"NextNoPendingJob": {
+			id: NextJobID,
+			expectedRequest: &describeJobExecutionRequest{
+				IncludeJobDocument: true,
+			},
+			response:      &simpleResponse{},
+			responseTopic: NextJobID + "/get/accepted",
+			expected:      (*JobExecution)(nil),
+		},
+		"SuccessWithDocumentRegistry": {
+			id: "testID",
+			expectedRequest: &describeJobExecutionRequest{
+				IncludeJobDocument: true,
+			},
+			response: &describeJobExecutionResponse{
+				Execution: JobExecution{
+					JobID:         "testID",
+					JobDocument:   json.RawMessage(`{"operation":"reboot","delay":3}`),
+					StatusDetails: map[string]string{},
+				},
+			},
+			responseTopic: "testID/get/accepted",
+			expected: &JobExecution{
+				JobID:         "testID",
+				JobDocument:   &rebootDocument{Operation: "reboot", Delay: 3},
+				StatusDetails: map[string]string{},
+			},
+			options: []Option{
+				WithDocumentRegistry(testDocumentRegistry()),
+			},
+		},
+		"UnknownKindWithDocumentRegistry": {
+			id: "testID",
+			expectedRequest: &describeJobExecutionRequest{
+				IncludeJobDocument: true,
+			},
+			response: &describeJobExecutionResponse{
+				Execution: JobExecution{
+					JobID:         "testID",
+					JobDocument:   json.RawMessage(`{"operation":"unknown"}`),
+					StatusDetails: map[string]string{},
+				},
+			},
+			responseTopic: "testID/get/accepted",
+			expected: &JobExecution{
+				JobID:         "testID",
+				JobDocument:   json.RawMessage(`{"operation":"unknown"}`),
+				StatusDetails: map[string]string{},
+			},
+			options: []Option{
+				WithDocumentRegistry(testDocumentRegistry()),
+			},
+		},
+		"InvalidDocumentWithDocumentRegistry": {
+			id: "testID",
+			expectedRequest: &describeJobExecutionRequest{
+				IncludeJobDocument: true,
+			},
+			response: &describeJobExecutionResponse{
+				Execution: JobExecution{
+					JobID:         "testID",
+					JobDocument:   json.RawMessage(`{"operation":"reboot","delay":"3"}`),
+					StatusDetails: map[string]string{},
+				},
+			},
+			responseTopic: "testID/get/accepted",
+			options: []Option{
+				WithDocumentRegistry(testDocumentRegistry()),
+			},
+			errIs: ErrInvalidDocument,
+		},
 	}
 
 	for name, testCase := range testCases {
@@ -374,7 +680,7 @@ func TestDescribeJob(t *testing.T) {
 			}
 			cli.Handle(j)
 
-			jb, err := j.DescribeJob(ctx, testCase.id)
+			jb, err := j.DescribeJob(ctx, testCase.id, testCase.describeOptions...)
 			switch {
 			case testCase.errResponse != nil:
 				setClientToken(err, "")
@@ -425,9 +731,10 @@ func TestUpdateJob(t *testing.T) {
 			},
 			status: Queued,
 			expectedRequest: &updateJobExecutionRequest{
-				Status:          Queued,
-				ExpectedVersion: 3,
-				StatusDetails:   map[string]string{},
+				IncludeJobExecutionState: true,
+				Status:                   Queued,
+				ExpectedVersion:          3,
+				StatusDetails:            map[string]string{},
 			},
 			response:      &updateJobExecutionResponse{},
 			responseTopic: "testID/update/accepted",
@@ -441,9 +748,10 @@ func TestUpdateJob(t *testing.T) {
 			},
 			status: Canceled,
 			expectedRequest: &updateJobExecutionRequest{
-				Status:          Canceled,
-				ExpectedVersion: 5,
-				StatusDetails:   map[string]string{},
+				IncludeJobExecutionState: true,
+				Status:                   Canceled,
+				ExpectedVersion:          5,
+				StatusDetails:            map[string]string{},
 			},
 			response:      &updateJobExecutionResponse{},
 			responseTopic: "testID/update/accepted",
@@ -460,10 +768,11 @@ func TestUpdateJob(t *testing.T) {
 				WithTimeout(100),
 			},
 			expectedRequest: &updateJobExecutionRequest{
-				Status:               Queued,
-				ExpectedVersion:      3,
-				StepTimeoutInMinutes: 100,
-				StatusDetails:        map[string]string{},
+				IncludeJobExecutionState: true,
+				Status:                   Queued,
+				ExpectedVersion:          3,
+				StepTimeoutInMinutes:     100,
+				StatusDetails:            map[string]string{},
 			},
 			response:      &updateJobExecutionResponse{},
 			responseTopic: "testID/update/accepted",
@@ -481,8 +790,9 @@ func TestUpdateJob(t *testing.T) {
 				WithDetail("testKey2", "testValue2"),
 			},
 			expectedRequest: &updateJobExecutionRequest{
-				Status:          Queued,
-				ExpectedVersion: 3,
+				IncludeJobExecutionState: true,
+				Status:                   Queued,
+				ExpectedVersion:          3,
 				StatusDetails: map[string]string{
 					"testKey1": "testValue1",
 					"testKey2": "testValue2",
@@ -501,9 +811,10 @@ func TestUpdateJob(t *testing.T) {
 			},
 			status: Queued,
 			expectedRequest: &updateJobExecutionRequest{
-				Status:          Queued,
-				ExpectedVersion: 6,
-				StatusDetails:   map[string]string{},
+				IncludeJobExecutionState: true,
+				Status:                   Queued,
+				ExpectedVersion:          6,
+				StatusDetails:            map[string]string{},
 			},
 			response: &ErrorResponse{
 				Code:    "Failed",
@@ -524,9 +835,10 @@ func TestUpdateJob(t *testing.T) {
 			},
 			status: Queued,
 			expectedRequest: &updateJobExecutionRequest{
-				Status:          Queued,
-				ExpectedVersion: 6,
-				StatusDetails:   map[string]string{},
+				IncludeJobExecutionState: true,
+				Status:                   Queued,
+				ExpectedVersion:          6,
+				StatusDetails:            map[string]string{},
 			},
 			response: &invalidResponse{
 				ExecutionState: true,
@@ -543,9 +855,10 @@ func TestUpdateJob(t *testing.T) {
 			},
 			status: Queued,
 			expectedRequest: &updateJobExecutionRequest{
-				Status:          Queued,
-				ExpectedVersion: 6,
-				StatusDetails:   map[string]string{},
+				IncludeJobExecutionState: true,
+				Status:                   Queued,
+				ExpectedVersion:          6,
+				StatusDetails:            map[string]string{},
 			},
 			response: &invalidResponse{
 				ExecutionState: true,
@@ -562,9 +875,10 @@ func TestUpdateJob(t *testing.T) {
 			},
 			status: Queued,
 			expectedRequest: &updateJobExecutionRequest{
-				Status:          Queued,
-				ExpectedVersion: 6,
-				StatusDetails:   map[string]string{},
+				IncludeJobExecutionState: true,
+				Status:                   Queued,
+				ExpectedVersion:          6,
+				StatusDetails:            map[string]string{},
 			},
 			response:      &struct{}{},
 			responseTopic: "testID/update/accepted",
@@ -662,12 +976,15 @@ func TestUpdateJob(t *testing.T) {
 func TestHandlers_InvalidResponse(t *testing.T) {
 	for _, topic := range []string{
 		"notify",
+		"notify-next",
 		"test/get/accepted",
 		"test/get/rejected",
 		"test/update/accepted",
 		"test/update/rejected",
 		"get/accepted",
 		"get/rejected",
+		"start-next/accepted",
+		"start-next/rejected",
 	} {
 		topic := topic
 		t.Run(topic, func(t *testing.T) {
@@ -701,3 +1018,158 @@ func TestHandlers_InvalidResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateJob_Version(t *testing.T) {
+	newJobs := func(ctx context.Context, t *testing.T, version *int) Jobs {
+		var j Jobs
+		cli := &mockDevice{
+			mockClient: &mockmqtt.Client{
+				PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+					req := &updateJobExecutionRequest{}
+					if err := json.Unmarshal(msg.Payload, req); err != nil {
+						t.Error(err)
+						return err
+					}
+					var res interface{}
+					topic := "testID/update/accepted"
+					if req.ExpectedVersion == *version {
+						*version++
+						res = &updateJobExecutionResponse{
+							ExecutionState: &JobExecutionStateDetails{
+								Status:        req.Status,
+								StatusDetails: req.StatusDetails,
+								VersionNumber: *version,
+							},
+							ClientToken: req.ClientToken,
+						}
+					} else {
+						topic = "testID/update/rejected"
+						res = &ErrorResponse{
+							Code: "VersionMismatch",
+							ExecutionState: JobExecutionStateDetails{
+								Status:        InProgress,
+								VersionNumber: *version,
+							},
+							ClientToken: req.ClientToken,
+						}
+					}
+					bres, err := json.Marshal(res)
+					if err != nil {
+						t.Error(err)
+						return err
+					}
+					j.Serve(&mqtt.Message{
+						Topic:   j.(*jobs).topic(topic),
+						Payload: bres,
+					})
+					return nil
+				},
+			},
+		}
+		var err error
+		j, err = New(ctx, cli)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cli.Handle(j)
+		return j
+	}
+
+	t.Run("Successive", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		version := 1
+		j := newJobs(ctx, t, &version)
+		je := &JobExecution{JobID: "testID", Status: Queued, VersionNumber: 1}
+		for i := 0; i < 3; i++ {
+			if err := j.UpdateJob(ctx, je, InProgress, WithDetail("progress", fmt.Sprint(i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if je.VersionNumber != 4 {
+			t.Errorf("Expected version 4, got %d", je.VersionNumber)
+		}
+		if je.Status != InProgress {
+			t.Errorf("Expected status %s, got %s", InProgress, je.Status)
+		}
+	})
+	t.Run("Mismatch", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		version := 5
+		j := newJobs(ctx, t, &version)
+		je := &JobExecution{JobID: "testID", Status: Queued, VersionNumber: 1}
+		err := j.UpdateJob(ctx, je, InProgress)
+		if !errors.Is(err, ErrVersionMismatch) {
+			t.Fatalf("Expected error: %v, got: %v", ErrVersionMismatch, err)
+		}
+		if je.VersionNumber != 5 {
+			t.Errorf("Expected refreshed version 5, got %d", je.VersionNumber)
+		}
+		if err := j.UpdateJob(ctx, je, Succeeded); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("RetryOnMismatch", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		version := 5
+		j := newJobs(ctx, t, &version)
+		je := &JobExecution{JobID: "testID", Status: Queued, VersionNumber: 1}
+		if err := j.UpdateJob(ctx, je, Succeeded, WithRetryOnVersionMismatch(1)); err != nil {
+			t.Fatal(err)
+		}
+		if je.VersionNumber != 6 {
+			t.Errorf("Expected version 6, got %d", je.VersionNumber)
+		}
+		if je.Status != Succeeded {
+			t.Errorf("Expected status %s, got %s", Succeeded, je.Status)
+		}
+	})
+	t.Run("MismatchWithoutExecution", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		var j Jobs
+		cli := &mockDevice{
+			mockClient: &mockmqtt.Client{
+				PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+					req := &simpleRequest{}
+					if err := json.Unmarshal(msg.Payload, req); err != nil {
+						t.Error(err)
+						return err
+					}
+					// Neither the error nor the describe response has the execution.
+					topic := "testID/update/rejected"
+					if msg.Topic == j.(*jobs).topic("testID/get") {
+						topic = "testID/get/accepted"
+					}
+					j.Serve(&mqtt.Message{
+						Topic: j.(*jobs).topic(topic),
+						Payload: []byte(fmt.Sprintf(
+							`{"code":"VersionMismatch","clientToken":"%s"}`, req.ClientToken,
+						)),
+					})
+					return nil
+				},
+			},
+		}
+		var err error
+		j, err = New(ctx, cli)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cli.Handle(j)
+
+		je := &JobExecution{JobID: "testID", Status: Queued, VersionNumber: 1}
+		if err := j.UpdateJob(ctx, je, InProgress); !errors.Is(err, ErrVersionMismatch) {
+			t.Fatalf("Expected error: %v, got: %v", ErrVersionMismatch, err)
+		}
+		if je.VersionNumber != 1 {
+			t.Errorf("Expected version 1, got %d", je.VersionNumber)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/atomicfile"
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+// JournalEntry represents a job execution accepted by Worker.
+type JournalEntry struct {
+	JobID           string `json:"jobId"`
+	Operation       string `json:"operation"`
+	ExecutionNumber int    `json:"executionNumber"`
+	// VersionNumber is the last known version of the job execution.
+	VersionNumber int `json:"versionNumber"`
+	// Checkpoint is the last step checkpoint set by JobProgress.SetCheckpoint.
+	Checkpoint string `json:"checkpoint,omitempty"`
+	// Details is the last status details.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Journal is an interface of the storage of the running job executions.
+// Entries are stored while the job is running and removed when the job
+// execution reaches the terminal state.
+type Journal interface {
+	// Load returns all stored entries sorted by the job ID.
+	Load() ([]JournalEntry, error)
+	// Save stores the entry.
+	// The entry of the same job ID is overwritten.
+	Save(e *JournalEntry) error
+	// Remove removes the entry of the job ID.
+	// Removing non-existent entry is not an error.
+	Remove(jobID string) error
+}
+
+type memoryJournal struct {
+	mu      sync.Mutex
+	entries map[string]JournalEntry
+}
+
+// NewMemoryJournal returns Journal which stores the entries on memory.
+func NewMemoryJournal() Journal {
+	return &memoryJournal{entries: make(map[string]JournalEntry)}
+}
+
+func (j *memoryJournal) Load() ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return sortedEntries(j.entries), nil
+}
+
+func (j *memoryJournal) Save(e *JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries[e.JobID] = copyEntry(e)
+	return nil
+}
+
+func (j *memoryJournal) Remove(jobID string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.entries, jobID)
+	return nil
+}
+
+type fileJournal struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileJournal returns Journal which stores the entries in the given file.
+// Each write is flushed to the disk so that the journal survives
+// the reboot during the job.
+func NewFileJournal(path string) Journal {
+	return &fileJournal{path: path}
+}
+
+func (j *fileJournal) read() (map[string]JournalEntry, error) {
+	entries := make(map[string]JournalEntry)
+	b, err := os.ReadFile(j.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, ioterr.New(err, "reading journal")
+	}
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, ioterr.New(err, "unmarshaling journal")
+	}
+	return entries, nil
+}
+
+func (j *fileJournal) write(entries map[string]JournalEntry) error {
+	if len(entries) == 0 {
+		if err := atomicfile.Remove(j.path); err != nil && !os.IsNotExist(err) {
+			return ioterr.New(err, "removing journal")
+		}
+		return nil
+	}
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return ioterr.New(err, "marshaling journal")
+	}
+	if err := atomicfile.WriteFile(j.path, b, 0600); err != nil {
+		return ioterr.New(err, "writing journal")
+	}
+	return nil
+}
+
+func (j *fileJournal) Load() ([]JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entries, err := j.read()
+	if err != nil {
+		return nil, err
+	}
+	return sortedEntries(entries), nil
+}
+
+func (j *fileJournal) Save(e *JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entries, err := j.read()
+	if err != nil {
+		return err
+	}
+	entries[e.JobID] = copyEntry(e)
+	return j.write(entries)
+}
+
+func (j *fileJournal) Remove(jobID string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entries, err := j.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[jobID]; !ok {
+		return nil
+	}
+	delete(entries, jobID)
+	return j.write(entries)
+}
+
+func copyEntry(e *JournalEntry) JournalEntry {
+	c := *e
+	if e.Details != nil {
+		c.Details = make(map[string]string, len(e.Details))
+		for k, v := range e.Details {
+			c.Details[k] = v
+		}
+	}
+	return c
+}
+
+func sortedEntries(entries map[string]JournalEntry) []JournalEntry {
+	ret := make([]JournalEntry, 0, len(entries))
+	for _, e := range entries {
+		ret = append(ret, copyEntry(&e))
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].JobID < ret[j].JobID
+	})
+	return ret
+}
@@ -0,0 +1,244 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	dir := t.TempDir()
+	journals := map[string]func() Journal{
+		"Memory": NewMemoryJournal,
+		"File": func() Journal {
+			return NewFileJournal(filepath.Join(dir, "journal.json"))
+		},
+	}
+	for name, newJournal := range journals {
+		newJournal := newJournal
+		t.Run(name, func(t *testing.T) {
+			j := newJournal()
+
+			entries, err := j.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Fatalf("Expected no entries, got: %+v", entries)
+			}
+
+			e1 := &JournalEntry{JobID: "job1", Operation: "op", VersionNumber: 2, Checkpoint: "a"}
+			e2 := &JournalEntry{JobID: "job0", Operation: "op", Details: map[string]string{"k": "v"}}
+			for _, e := range []*JournalEntry{e1, e2} {
+				if err := j.Save(e); err != nil {
+					t.Fatal(err)
+				}
+			}
+			e2.Details["k"] = "modified"
+			e1.Checkpoint = "b"
+			if err := j.Save(e1); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := []JournalEntry{
+				{JobID: "job0", Operation: "op", Details: map[string]string{"k": "v"}},
+				{JobID: "job1", Operation: "op", VersionNumber: 2, Checkpoint: "b"},
+			}
+			entries, err = j.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expected, entries) {
+				t.Errorf("Expected entries:\n%+v\ngot:\n%+v", expected, entries)
+			}
+
+			for _, id := range []string{"job0", "job1", "unknown"} {
+				if err := j.Remove(id); err != nil {
+					t.Fatal(err)
+				}
+			}
+			entries, err = j.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("Expected no entries, got: %+v", entries)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(dir, "journal.json")); !os.IsNotExist(err) {
+		t.Errorf("Empty journal file must be removed, got: %v", err)
+	}
+}
+
+func TestWorker_Journal(t *testing.T) {
+	t.Run("Checkpoint", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		j := newStubJobs(&JobExecution{
+			JobID:         "job1",
+			Status:        Queued,
+			JobDocument:   map[string]interface{}{"operation": "install"},
+			VersionNumber: 1,
+		})
+		journal := NewMemoryJournal()
+		w := NewWorker(j, WithJournal(journal))
+		chCheckpoint := make(chan struct{})
+		w.Handle("install", func(ctx context.Context, je *JobExecution, p *JobProgress) error {
+			p.SetDetail("step", "2")
+			if err := p.SetCheckpoint("downloaded"); err != nil {
+				t.Error(err)
+			}
+			close(chCheckpoint)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		runCtx, runCancel := context.WithCancel(ctx)
+		chRun := make(chan error)
+		go func() { chRun <- w.Run(runCtx) }()
+
+		select {
+		case <-chCheckpoint:
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		}
+		// Simulate the restart.
+		runCancel()
+		<-chRun
+
+		expected := []JournalEntry{{
+			JobID:         "job1",
+			Operation:     "install",
+			VersionNumber: 2,
+			Checkpoint:    "downloaded",
+			Details:       map[string]string{"step": "2"},
+		}}
+		entries, err := journal.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, entries) {
+			t.Errorf("Expected entries:\n%+v\ngot:\n%+v", expected, entries)
+		}
+	})
+
+	testCases := map[string]struct {
+		policy     ResumePolicy
+		checkpoint string
+		called     bool
+		expected   []jobUpdate
+	}{
+		"Resume": {
+			policy:     ResumeInterrupted,
+			checkpoint: "downloaded",
+			called:     true,
+			expected: []jobUpdate{
+				{JobID: "job1", Status: InProgress, Details: map[string]string{"step": "2"}},
+				{JobID: "job1", Status: Succeeded, Details: map[string]string{"step": "2"}},
+			},
+		},
+		"Restart": {
+			policy: RestartInterrupted,
+			called: true,
+			expected: []jobUpdate{
+				{JobID: "job1", Status: InProgress, Details: map[string]string{}},
+				{JobID: "job1", Status: Succeeded, Details: map[string]string{}},
+			},
+		},
+		"Fail": {
+			policy: FailInterrupted,
+			expected: []jobUpdate{
+				{JobID: "job1", Status: Failed, Details: map[string]string{"step": "2", DetailReason: "interrupted"}},
+			},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			j := newStubJobs(&JobExecution{
+				JobID:         "job1",
+				Status:        InProgress,
+				JobDocument:   map[string]interface{}{"operation": "install"},
+				VersionNumber: 2,
+			})
+			journal := NewMemoryJournal()
+			for _, e := range []*JournalEntry{
+				{
+					JobID:         "job1",
+					Operation:     "install",
+					VersionNumber: 2,
+					Checkpoint:    "downloaded",
+					Details:       map[string]string{"step": "2"},
+				},
+				{JobID: "removed", Operation: "install", VersionNumber: 1},
+			} {
+				if err := journal.Save(e); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			w := NewWorker(j, WithJournal(journal), WithResumePolicy(tt.policy))
+			chCalled := make(chan string, 1)
+			w.Handle("install", func(ctx context.Context, je *JobExecution, p *JobProgress) error {
+				chCalled <- p.Checkpoint()
+				return nil
+			})
+			go func() { _ = w.Run(ctx) }()
+
+			for _, e := range tt.expected {
+				if u := j.waitUpdate(t); !reflect.DeepEqual(e, u) {
+					t.Errorf("Expected update: %+v, got: %+v", e, u)
+				}
+			}
+			select {
+			case cp := <-chCalled:
+				if !tt.called {
+					t.Error("Handler must not be called")
+				}
+				if cp != tt.checkpoint {
+					t.Errorf("Expected checkpoint: %q, got: %q", tt.checkpoint, cp)
+				}
+			default:
+				if tt.called {
+					t.Error("Handler must be called")
+				}
+			}
+
+			var entries []JournalEntry
+			for i := 0; i < 50; i++ {
+				var err error
+				if entries, err = journal.Load(); err != nil {
+					t.Fatal(err)
+				}
+				if len(entries) == 0 {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if len(entries) != 0 {
+				t.Errorf("Expected no entries, got: %+v", entries)
+			}
+		})
+	}
+}
@@ -14,9 +14,14 @@
 
 package jobs
 
+import (
+	"time"
+)
+
 // Options stores Jobs options.
 type Options struct {
-	JobDocumentType interface{}
+	JobDocumentType  interface{}
+	DocumentRegistry *DocumentRegistry
 }
 
 // Option is a functional option of Jobs.
@@ -36,10 +41,49 @@ func WithJobDocumentType(t interface{}) Option {
 	}
 }
 
+// WithDocumentRegistry sets DocumentRegistry to decode the job documents
+// to the types registered for their kinds.
+// It takes precedence over WithJobDocumentType.
+func WithDocumentRegistry(r *DocumentRegistry) Option {
+	return func(o *Options) {
+		o.DocumentRegistry = r
+	}
+}
+
+// DescribeJobOptions stores DescribeJob options.
+type DescribeJobOptions struct {
+	// ExecutionNumber specifies the execution number to describe.
+	// Zero means the latest execution.
+	ExecutionNumber int
+	// ExcludeJobDocument omits the job document from the response.
+	ExcludeJobDocument bool
+}
+
+// DescribeJobOption is a functional option of DescribeJob.
+type DescribeJobOption func(*DescribeJobOptions)
+
+// WithExecutionNumber sets the execution number to describe.
+func WithExecutionNumber(n int) DescribeJobOption {
+	return func(o *DescribeJobOptions) {
+		o.ExecutionNumber = n
+	}
+}
+
+// WithoutJobDocument omits the job document from the response
+// for lightweight polling of the execution status.
+// JobExecution.JobDocument will be nil.
+func WithoutJobDocument() DescribeJobOption {
+	return func(o *DescribeJobOptions) {
+		o.ExcludeJobDocument = true
+	}
+}
+
 // UpdateJobOptions stores UpdateJob options.
 type UpdateJobOptions struct {
 	TimeoutMinutes int
 	Details        map[string]string
+	// RetryOnVersionMismatch is a maximum number of retries on version mismatch.
+	RetryOnVersionMismatch int
 }
 
 // UpdateJobOption is a functional option of UpdateJob.
@@ -58,3 +102,85 @@ func WithDetail(key, val string) UpdateJobOption {
 		o.Details[key] = val
 	}
 }
+
+// WithRetryOnVersionMismatch makes UpdateJob retry the update with
+// the refreshed version on version mismatch up to n times.
+// StartNextPendingJob ignores this option.
+func WithRetryOnVersionMismatch(n int) UpdateJobOption {
+	return func(o *UpdateJobOptions) {
+		o.RetryOnVersionMismatch = n
+	}
+}
+
+// WorkerOptions stores options of Worker.
+type WorkerOptions struct {
+	// Concurrency is a maximum number of the jobs processed concurrently.
+	Concurrency int
+	// ProgressInterval is an interval of the progress updates.
+	ProgressInterval time.Duration
+	// StepTimeoutMinutes is a step timeout set on each progress update.
+	// Zero means no step timeout.
+	StepTimeoutMinutes int
+	// Journal stores the running job executions to resume after the restart.
+	Journal Journal
+	// ResumePolicy controls how to handle the job executions interrupted by the restart.
+	ResumePolicy ResumePolicy
+}
+
+// ResumePolicy represents a policy to handle the job executions
+// recorded in the Journal and still in progress on startup.
+type ResumePolicy int
+
+// Resume policies.
+const (
+	// ResumeInterrupted calls the handler again with the recorded checkpoint.
+	ResumeInterrupted ResumePolicy = iota
+	// RestartInterrupted drops the recorded checkpoint and calls the handler
+	// from the beginning.
+	RestartInterrupted
+	// FailInterrupted marks the job execution as Failed.
+	FailInterrupted
+)
+
+// WorkerOption is a functional option of Worker.
+type WorkerOption func(*WorkerOptions)
+
+// WithConcurrency sets maximum number of the jobs processed concurrently.
+// Default is 1.
+func WithConcurrency(n int) WorkerOption {
+	return func(o *WorkerOptions) {
+		o.Concurrency = n
+	}
+}
+
+// WithProgressInterval sets interval of the progress updates.
+// Default is 1 minute.
+func WithProgressInterval(d time.Duration) WorkerOption {
+	return func(o *WorkerOptions) {
+		o.ProgressInterval = d
+	}
+}
+
+// WithStepTimeout sets step timeout in minutes.
+// The timeout is extended on each progress update while the handler is running.
+// Progress interval must be shorter than the step timeout.
+func WithStepTimeout(min int) WorkerOption {
+	return func(o *WorkerOptions) {
+		o.StepTimeoutMinutes = min
+	}
+}
+
+// WithJournal sets Journal to resume the job executions after the restart.
+func WithJournal(j Journal) WorkerOption {
+	return func(o *WorkerOptions) {
+		o.Journal = j
+	}
+}
+
+// WithResumePolicy sets policy to handle the job executions interrupted by the restart.
+// Default is ResumeInterrupted.
+func WithResumePolicy(p ResumePolicy) WorkerOption {
+	return func(o *WorkerOptions) {
+		o.ResumePolicy = p
+	}
+}
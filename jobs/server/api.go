@@ -0,0 +1,189 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/jobs"
+)
+
+var errInvalidRequest = errors.New("invalid request")
+
+// apiHandler handles the subset of AWS IoT job management API requests.
+type apiHandler struct {
+	server *Server
+	mux    *http.ServeMux
+}
+
+type createJobInput struct {
+	Targets       []string `json:"targets"`
+	Document      string   `json:"document"`
+	TimeoutConfig *struct {
+		InProgressTimeoutInMinutes int `json:"inProgressTimeoutInMinutes"`
+	} `json:"timeoutConfig"`
+}
+
+type jobOutput struct {
+	JobArn string `json:"jobArn"`
+	JobID  string `json:"jobId"`
+}
+
+type describeJobExecutionOutput struct {
+	Execution *jobs.JobExecution `json:"execution"`
+}
+
+// thingName returns the thing name of the target.
+// Target can be either thing ARN or thing name.
+func thingName(target string) string {
+	if i := strings.LastIndex(target, ":thing/"); i >= 0 {
+		return target[i+len(":thing/"):]
+	}
+	return target
+}
+
+func jobArn(id string) string {
+	return "arn:clone:iot:::job/" + id
+}
+
+func (h *apiHandler) createJob(r *http.Request) (interface{}, error) {
+	in := &createJobInput{}
+	if err := json.NewDecoder(r.Body).Decode(in); err != nil {
+		return nil, ioterr.New(errInvalidRequest, "decoding request")
+	}
+	j := &Job{
+		JobID:    r.PathValue("jobId"),
+		Document: json.RawMessage(in.Document),
+	}
+	for _, t := range in.Targets {
+		j.Targets = append(j.Targets, thingName(t))
+	}
+	if in.TimeoutConfig != nil {
+		if in.TimeoutConfig.InProgressTimeoutInMinutes < 0 {
+			return nil, ioterr.New(errInvalidRequest, "validating timeoutConfig.inProgressTimeoutInMinutes")
+		}
+		j.InProgressTimeout = time.Duration(in.TimeoutConfig.InProgressTimeoutInMinutes) * time.Minute
+	}
+	if err := h.server.CreateJob(j); err != nil {
+		return nil, err
+	}
+	return &jobOutput{JobArn: jobArn(j.JobID), JobID: j.JobID}, nil
+}
+
+func force(r *http.Request) (bool, error) {
+	v := r.URL.Query().Get("force")
+	if v == "" {
+		return false, nil
+	}
+	f, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, ioterr.New(errInvalidRequest, "parsing force")
+	}
+	return f, nil
+}
+
+func (h *apiHandler) cancelJob(r *http.Request) (interface{}, error) {
+	f, err := force(r)
+	if err != nil {
+		return nil, err
+	}
+	id := r.PathValue("jobId")
+	if err := h.server.CancelJob(id, f); err != nil {
+		return nil, err
+	}
+	return &jobOutput{JobArn: jobArn(id), JobID: id}, nil
+}
+
+func (h *apiHandler) deleteJob(r *http.Request) (interface{}, error) {
+	f, err := force(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.server.DeleteJob(r.PathValue("jobId"), f); err != nil {
+		return nil, err
+	}
+	return struct{}{}, nil
+}
+
+func (h *apiHandler) describeJobExecution(r *http.Request) (interface{}, error) {
+	je, ok := h.server.DescribeJobExecution(r.PathValue("thingName"), r.PathValue("jobId"))
+	if !ok {
+		return nil, ioterr.New(ErrJobNotFound, "describing job execution")
+	}
+	return &describeJobExecutionOutput{Execution: je}, nil
+}
+
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrJobExists), errors.Is(err, ErrJobInProgress):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (h *apiHandler) handle(fn func(*http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		out, err := fn(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to handle request: %v", err), statusCode(err))
+			return
+		}
+		b, err := json.Marshal(out)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to handle request: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(b); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to handle request: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func (h *apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// NewAPIHandler creates http handler of the job management API.
+// Following requests of AWS IoT API are supported:
+//
+//	PUT /jobs/{jobId} (CreateJob)
+//	PUT /jobs/{jobId}/cancel?force={force} (CancelJob)
+//	DELETE /jobs/{jobId}?force={force} (DeleteJob)
+//	GET /things/{thingName}/jobs/{jobId} (DescribeJobExecution)
+//
+// Targets of CreateJob can be either thing ARNs or thing names.
+func NewAPIHandler(server *Server) http.Handler {
+	h := &apiHandler{
+		server: server,
+		mux:    http.NewServeMux(),
+	}
+	h.mux.HandleFunc("PUT /jobs/{jobId}", h.handle(h.createJob))
+	h.mux.HandleFunc("PUT /jobs/{jobId}/cancel", h.handle(h.cancelJob))
+	h.mux.HandleFunc("DELETE /jobs/{jobId}", h.handle(h.deleteJob))
+	h.mux.HandleFunc("GET /things/{thingName}/jobs/{jobId}", h.handle(h.describeJobExecution))
+	return h
+}
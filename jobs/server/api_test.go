@@ -0,0 +1,149 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/jobs"
+)
+
+func TestAPI(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv, _ := newRawTestServer(ctx, t)
+	ts := httptest.NewServer(NewAPIHandler(srv))
+	defer ts.Close()
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		code     int
+		expected string
+	}{
+		{
+			name:   "CreateJob",
+			method: http.MethodPut,
+			path:   "/jobs/job1",
+			body: `{"targets":["arn:aws:iot:ap-northeast-1:123456789012:thing/test","test2"],` +
+				`"document":"{\"operation\":\"reboot\"}","timeoutConfig":{"inProgressTimeoutInMinutes":10}}`,
+			code:     http.StatusOK,
+			expected: `{"jobArn":"arn:clone:iot:::job/job1","jobId":"job1"}`,
+		},
+		{
+			name:   "CreateExistingJob",
+			method: http.MethodPut,
+			path:   "/jobs/job1",
+			body:   `{"targets":["test"],"document":"{}"}`,
+			code:   http.StatusConflict,
+		},
+		{
+			name:   "CreateInvalidDocument",
+			method: http.MethodPut,
+			path:   "/jobs/job2",
+			body:   `{"targets":["test"],"document":"["}`,
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "DescribeJobExecution",
+			method: http.MethodGet,
+			path:   "/things/test/jobs/job1",
+			code:   http.StatusOK,
+			expected: `{"execution":{"jobId":"job1","thingName":"test","jobDocument":{"operation":"reboot"},` +
+				`"status":"QUEUED","statusDetails":null,"queuedAt":1000,"startedAt":0,"lastUpdatedAt":1000,` +
+				`"versionNumber":1,"executionNumber":1,"approximateSecondsBeforeTimedOut":0}}`,
+		},
+		{
+			name:   "DescribeNotFound",
+			method: http.MethodGet,
+			path:   "/things/test3/jobs/job1",
+			code:   http.StatusNotFound,
+		},
+		{
+			name:     "CancelJob",
+			method:   http.MethodPut,
+			path:     "/jobs/job1/cancel?force=true",
+			code:     http.StatusOK,
+			expected: `{"jobArn":"arn:clone:iot:::job/job1","jobId":"job1"}`,
+		},
+		{
+			name:   "CancelInvalidForce",
+			method: http.MethodPut,
+			path:   "/jobs/job1/cancel?force=a",
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:     "DeleteJob",
+			method:   http.MethodDelete,
+			path:     "/jobs/job1",
+			code:     http.StatusOK,
+			expected: `{}`,
+		},
+		{
+			name:   "DeleteNotFound",
+			method: http.MethodDelete,
+			path:   "/jobs/job1",
+			code:   http.StatusNotFound,
+		},
+	}
+	for _, tt := range testCases {
+		tt := tt
+		if ok := t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(ctx, tt.method, ts.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.code {
+				t.Fatalf("Expected status code: %d, got: %d", tt.code, res.StatusCode)
+			}
+			if tt.expected == "" {
+				return
+			}
+			var b json.RawMessage
+			if err := json.NewDecoder(res.Body).Decode(&b); err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, b)
+			}
+		}); !ok {
+			break
+		}
+	}
+
+	for thing, st := range map[string]jobs.JobExecutionState{
+		"test":  jobs.Canceled,
+		"test2": jobs.Canceled,
+	} {
+		je, ok := srv.DescribeJobExecution(thing, "job1")
+		if !ok || je.Status != st {
+			t.Errorf("Expected status of %s: %s, got: %+v", thing, st, je)
+		}
+	}
+}
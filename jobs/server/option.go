@@ -0,0 +1,40 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+)
+
+// Option is a functional option of Server.
+type Option func(*Server)
+
+// WithClock sets the function to get current time.
+// It is used to fill the timestamps and to check the timeouts
+// of the job executions.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// WithTimeoutCheckInterval sets the interval to check the step
+// and in-progress timeouts of the job executions.
+// Timeouts are also checked on every request.
+func WithTimeoutCheckInterval(d time.Duration) Option {
+	return func(s *Server) {
+		s.checkInterval = d
+	}
+}
@@ -0,0 +1,731 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server implements AWS IoT Jobs service emulator
+// for testing and offline development.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/at-wat/mqtt-go"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/jobs"
+)
+
+const defaultCheckInterval = time.Second
+
+// Errors returned by the job management methods.
+var (
+	ErrJobExists     = errors.New("job already exists")
+	ErrJobNotFound   = errors.New("job not found")
+	ErrJobInProgress = errors.New("job is in progress")
+	ErrInvalidJob    = errors.New("invalid job")
+)
+
+// Job represents a job to be created.
+type Job struct {
+	JobID string
+	// Targets is a list of the thing names.
+	Targets []string
+	// Document is a JSON object of the job document.
+	Document json.RawMessage
+	// InProgressTimeout is a timeout of the IN_PROGRESS execution.
+	// Zero means no timeout.
+	InProgressTimeout time.Duration
+}
+
+type job struct {
+	id                string
+	document          json.RawMessage
+	inProgressTimeout time.Duration
+	deleted           bool
+	executions        map[string]*execution
+}
+
+type execution struct {
+	jobID              string
+	thingName          string
+	status             jobs.JobExecutionState
+	statusDetails      map[string]string
+	queuedAt           time.Time
+	startedAt          time.Time
+	lastUpdatedAt      time.Time
+	versionNumber      int
+	executionNumber    int
+	stepDeadline       time.Time
+	inProgressDeadline time.Time
+}
+
+func (e *execution) pending() bool {
+	return e.status == jobs.Queued || e.status == jobs.InProgress
+}
+
+// Server emulates AWS IoT Jobs service over MQTT.
+// Server must be registered to the MQTT client as a message handler.
+type Server struct {
+	cli           mqtt.Client
+	now           func() time.Time
+	checkInterval time.Duration
+
+	mu    sync.Mutex
+	jobs  map[string]*job
+	order []string
+
+	ctx   context.Context
+	chPub chan []*mqtt.Message
+}
+
+// New creates Jobs service emulator and subscribes the request topics
+// of all things.
+// Responses and notifications are published in background
+// and the timeouts of the job executions are checked periodically
+// until the context is canceled.
+func New(ctx context.Context, cli mqtt.Client, opts ...Option) (*Server, error) {
+	s := &Server{
+		ctx:           ctx,
+		chPub:         make(chan []*mqtt.Message, 64),
+		cli:           cli,
+		now:           time.Now,
+		checkInterval: defaultCheckInterval,
+		jobs:          make(map[string]*job),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	if _, err := cli.Subscribe(ctx,
+		mqtt.Subscription{Topic: "$aws/things/+/jobs/get", QoS: mqtt.QoS1},
+		mqtt.Subscription{Topic: "$aws/things/+/jobs/start-next", QoS: mqtt.QoS1},
+		mqtt.Subscription{Topic: "$aws/things/+/jobs/+/get", QoS: mqtt.QoS1},
+		mqtt.Subscription{Topic: "$aws/things/+/jobs/+/update", QoS: mqtt.QoS1},
+	); err != nil {
+		return nil, ioterr.New(err, "subscribing jobs topics")
+	}
+	go s.publish()
+	go s.checkTimeout()
+	return s, nil
+}
+
+// publish publishes the responses in order.
+// Messages are published outside of the message handler to avoid blocking
+// the MQTT client.
+func (s *Server) publish() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case msgs := <-s.chPub:
+			for _, m := range msgs {
+				if err := s.cli.Publish(s.ctx, m); err != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+func (s *Server) enqueue(msgs []*mqtt.Message) {
+	if len(msgs) == 0 {
+		return
+	}
+	select {
+	case s.chPub <- msgs:
+	case <-s.ctx.Done():
+	}
+}
+
+// checkTimeout periodically moves the timed out executions to TIMED_OUT.
+func (s *Server) checkTimeout() {
+	tick := time.NewTicker(s.checkInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-tick.C:
+			s.mu.Lock()
+			msgs := s.expire()
+			s.mu.Unlock()
+			s.enqueue(msgs)
+		}
+	}
+}
+
+// CreateJob creates the job and queues the executions of the target things.
+func (s *Server) CreateJob(j *Job) error {
+	if j.JobID == "" || strings.ContainsAny(j.JobID, "/+#$") {
+		return ioterr.Newf(ErrInvalidJob, "validating job ID %q", j.JobID)
+	}
+	if len(j.Targets) == 0 {
+		return ioterr.New(ErrInvalidJob, "validating targets")
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(j.Document, &doc); err != nil || doc == nil {
+		return ioterr.New(ErrInvalidJob, "validating document")
+	}
+
+	s.mu.Lock()
+	msgs := s.expire()
+	if _, ok := s.jobs[j.JobID]; ok {
+		s.mu.Unlock()
+		s.enqueue(msgs)
+		return ioterr.Newf(ErrJobExists, "creating job %q", j.JobID)
+	}
+	now := s.now()
+	jb := &job{
+		id:                j.JobID,
+		document:          append(json.RawMessage{}, j.Document...),
+		inProgressTimeout: j.InProgressTimeout,
+		executions:        make(map[string]*execution),
+	}
+	before := make(map[string]pendingState)
+	for _, thing := range j.Targets {
+		if _, ok := jb.executions[thing]; ok {
+			continue
+		}
+		before[thing] = s.pending(thing)
+		jb.executions[thing] = &execution{
+			jobID:           j.JobID,
+			thingName:       thing,
+			status:          jobs.Queued,
+			queuedAt:        now,
+			lastUpdatedAt:   now,
+			versionNumber:   1,
+			executionNumber: 1,
+		}
+	}
+	s.jobs[j.JobID] = jb
+	s.order = append(s.order, j.JobID)
+	msgs = append(msgs, s.notifications(before)...)
+	s.mu.Unlock()
+
+	s.enqueue(msgs)
+	return nil
+}
+
+// CancelJob cancels the job.
+// QUEUED executions are moved to CANCELED.
+// IN_PROGRESS executions are also canceled if force is true.
+func (s *Server) CancelJob(jobID string, force bool) error {
+	return s.finishJob(jobID, force, jobs.Canceled, false)
+}
+
+// DeleteJob deletes the job.
+// Pending executions are moved to REMOVED and no longer visible
+// from the devices.
+// The job having IN_PROGRESS executions can be deleted only if force is true.
+func (s *Server) DeleteJob(jobID string, force bool) error {
+	return s.finishJob(jobID, force, jobs.Removed, true)
+}
+
+func (s *Server) finishJob(jobID string, force bool, st jobs.JobExecutionState, del bool) error {
+	s.mu.Lock()
+	msgs := s.expire()
+	jb, ok := s.jobs[jobID]
+	if !ok || jb.deleted {
+		s.mu.Unlock()
+		s.enqueue(msgs)
+		return ioterr.Newf(ErrJobNotFound, "finding job %q", jobID)
+	}
+	if del && !force {
+		for _, e := range jb.executions {
+			if e.status == jobs.InProgress {
+				s.mu.Unlock()
+				s.enqueue(msgs)
+				return ioterr.Newf(ErrJobInProgress, "deleting job %q", jobID)
+			}
+		}
+	}
+	now := s.now()
+	before := make(map[string]pendingState)
+	for thing, e := range jb.executions {
+		before[thing] = s.pending(thing)
+		if e.status == jobs.Queued || (e.status == jobs.InProgress && force) {
+			e.status = st
+			e.lastUpdatedAt = now
+			e.versionNumber++
+		}
+	}
+	jb.deleted = del
+	msgs = append(msgs, s.notifications(before)...)
+	s.mu.Unlock()
+
+	s.enqueue(msgs)
+	return nil
+}
+
+// DescribeJobExecution returns the execution of the job on the thing.
+func (s *Server) DescribeJobExecution(thingName, jobID string) (*jobs.JobExecution, bool) {
+	s.mu.Lock()
+	msgs := s.expire()
+	var je *jobs.JobExecution
+	jb, ok := s.jobs[jobID]
+	if ok {
+		var e *execution
+		if e, ok = jb.executions[thingName]; ok {
+			ex := s.jobExecution(jb, e, true)
+			je = &jobs.JobExecution{
+				JobID:                            ex.JobID,
+				ThingName:                        ex.ThingName,
+				JobDocument:                      append(json.RawMessage{}, ex.JobDocument...),
+				Status:                           ex.Status,
+				StatusDetails:                    ex.StatusDetails,
+				QueuedAt:                         ex.QueuedAt,
+				StartedAt:                        ex.StartedAt,
+				LastUpdatedAt:                    ex.LastUpdatedAt,
+				VersionNumber:                    ex.VersionNumber,
+				ExecutionNumber:                  ex.ExecutionNumber,
+				ApproximateSecondsBeforeTimedOut: ex.ApproximateSecondsBeforeTimedOut,
+			}
+		}
+	}
+	s.mu.Unlock()
+	s.enqueue(msgs)
+	return je, ok
+}
+
+// expire moves the timed out executions to TIMED_OUT.
+// Caller must hold the lock.
+func (s *Server) expire() []*mqtt.Message {
+	now := s.now()
+	before := make(map[string]pendingState)
+	for _, id := range s.order {
+		for thing, e := range s.jobs[id].executions {
+			if e.status != jobs.InProgress || !expired(now, e.stepDeadline, e.inProgressDeadline) {
+				continue
+			}
+			if _, ok := before[thing]; !ok {
+				before[thing] = s.pending(thing)
+			}
+			e.status = jobs.TimedOut
+			e.lastUpdatedAt = now
+			e.versionNumber++
+		}
+	}
+	if len(before) == 0 {
+		return nil
+	}
+	return s.notifications(before)
+}
+
+func expired(now time.Time, deadlines ...time.Time) bool {
+	for _, d := range deadlines {
+		if !d.IsZero() && !now.Before(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// execution returns the execution visible from the device.
+// Caller must hold the lock.
+func (s *Server) execution(thing, jobID string) (*job, *execution) {
+	jb, ok := s.jobs[jobID]
+	if !ok || jb.deleted {
+		return nil, nil
+	}
+	e, ok := jb.executions[thing]
+	if !ok {
+		return nil, nil
+	}
+	return jb, e
+}
+
+// next returns the next pending execution of the thing.
+// IN_PROGRESS executions precede QUEUED ones.
+// Caller must hold the lock.
+func (s *Server) next(thing string) (*job, *execution) {
+	var queued *job
+	for _, id := range s.order {
+		jb, e := s.execution(thing, id)
+		if e == nil {
+			continue
+		}
+		switch e.status {
+		case jobs.InProgress:
+			return jb, e
+		case jobs.Queued:
+			if queued == nil {
+				queued = jb
+			}
+		}
+	}
+	if queued == nil {
+		return nil, nil
+	}
+	return queued, queued.executions[thing]
+}
+
+// pendingExecutions returns the pending executions of the thing
+// in the order of creation.
+// Caller must hold the lock.
+func (s *Server) pendingExecutions(thing string) []*execution {
+	var ret []*execution
+	for _, id := range s.order {
+		if _, e := s.execution(thing, id); e != nil && e.pending() {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+type pendingState struct {
+	jobIDs string
+	next   string
+}
+
+// pending returns the state of the pending list used to detect
+// the changes to be notified.
+// Caller must hold the lock.
+func (s *Server) pending(thing string) pendingState {
+	var ids []string
+	for _, e := range s.pendingExecutions(thing) {
+		ids = append(ids, e.jobID)
+	}
+	var st pendingState
+	st.jobIDs = strings.Join(ids, "/")
+	if _, e := s.next(thing); e != nil {
+		st.next = e.jobID
+	}
+	return st
+}
+
+// notifications returns notify and notify-next messages of the things
+// whose pending list or next job is changed from the given state.
+// Caller must hold the lock.
+func (s *Server) notifications(before map[string]pendingState) []*mqtt.Message {
+	var msgs []*mqtt.Message
+	ts := s.now().Unix()
+	for thing, b := range before {
+		after := s.pending(thing)
+		prefix := "$aws/things/" + thing + "/jobs/"
+		if after.jobIDs != b.jobIDs {
+			summaries := make(map[jobs.JobExecutionState][]jobs.JobExecutionSummary)
+			for _, e := range s.pendingExecutions(thing) {
+				summaries[e.status] = append(summaries[e.status], summary(e))
+			}
+			msgs = append(msgs, s.message(prefix+"notify", &jobExecutionsChangedMessage{
+				Jobs:      summaries,
+				Timestamp: ts,
+			}))
+		}
+		if after.next != b.next {
+			m := &nextJobExecutionChangedMessage{Timestamp: ts}
+			if jb, e := s.next(thing); e != nil {
+				m.Execution = s.jobExecution(jb, e, true)
+			}
+			msgs = append(msgs, s.message(prefix+"notify-next", m))
+		}
+	}
+	return msgs
+}
+
+// parseTopic parses request topic.
+// Returns prefix of the response topics, thing name, job ID and operation.
+func parseTopic(topic string) (string, string, string, string, bool) {
+	t := strings.Split(topic, "/")
+	if len(t) < 5 || t[0] != "$aws" || t[1] != "things" || t[3] != "jobs" {
+		return "", "", "", "", false
+	}
+	prefix := strings.Join(t[:4], "/")
+	switch {
+	case len(t) == 5 && (t[4] == "get" || t[4] == "start-next"):
+		return prefix, t[2], "", t[4], true
+	case len(t) == 6 && (t[5] == "get" || t[5] == "update"):
+		return prefix, t[2], t[4], t[5], true
+	}
+	return "", "", "", "", false
+}
+
+// Serve implements mqtt.Handler.
+func (s *Server) Serve(msg *mqtt.Message) {
+	prefix, thing, jobID, op, ok := parseTopic(msg.Topic)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	msgs := s.expire()
+	switch {
+	case jobID == "" && op == "get":
+		msgs = append(s.getPending(prefix, thing, msg.Payload), msgs...)
+	case jobID == "" && op == "start-next":
+		msgs = append(s.startNext(prefix, thing, msg.Payload), msgs...)
+	case op == "get":
+		msgs = append(s.describe(prefix, thing, jobID, msg.Payload), msgs...)
+	case op == "update":
+		msgs = append(s.update(prefix, thing, jobID, msg.Payload), msgs...)
+	}
+	s.mu.Unlock()
+
+	s.enqueue(msgs)
+}
+
+func (s *Server) message(topic string, v interface{}) *mqtt.Message {
+	b, err := json.Marshal(v)
+	if err != nil {
+		// Unreachable since the responses consist of plain Go values and
+		// the job documents are validated as JSON objects by CreateJob.
+		panic(err)
+	}
+	return &mqtt.Message{Topic: topic, QoS: mqtt.QoS1, Payload: b}
+}
+
+func (s *Server) rejected(topic, code, msg, token string, e *execution) []*mqtt.Message {
+	res := &errorResponse{
+		Code:        code,
+		Message:     msg,
+		Timestamp:   s.now().Unix(),
+		ClientToken: token,
+	}
+	if e != nil {
+		res.ExecutionState = stateDetails(e)
+	}
+	return []*mqtt.Message{s.message(topic+"/rejected", res)}
+}
+
+func (s *Server) jobExecution(jb *job, e *execution, includeDocument bool) *jobExecution {
+	je := &jobExecution{
+		JobID:           e.jobID,
+		ThingName:       e.thingName,
+		Status:          e.status,
+		StatusDetails:   copyDetails(e.statusDetails),
+		QueuedAt:        e.queuedAt.Unix(),
+		StartedAt:       unix(e.startedAt),
+		LastUpdatedAt:   e.lastUpdatedAt.Unix(),
+		VersionNumber:   e.versionNumber,
+		ExecutionNumber: e.executionNumber,
+	}
+	if includeDocument {
+		je.JobDocument = jb.document
+	}
+	if e.status == jobs.InProgress {
+		now := s.now()
+		for _, d := range []time.Time{e.stepDeadline, e.inProgressDeadline} {
+			if d.IsZero() {
+				continue
+			}
+			sec := int64(d.Sub(now) / time.Second)
+			if je.ApproximateSecondsBeforeTimedOut == 0 || sec < je.ApproximateSecondsBeforeTimedOut {
+				je.ApproximateSecondsBeforeTimedOut = sec
+			}
+		}
+	}
+	return je
+}
+
+func (s *Server) getPending(prefix, thing string, payload []byte) []*mqtt.Message {
+	topic := prefix + "/get"
+	req := &simpleRequest{}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, req); err != nil {
+			return s.rejected(topic, "InvalidJson", "Invalid JSON", "", nil)
+		}
+	}
+	res := &getPendingJobExecutionsResponse{
+		InProgressJobs: []jobs.JobExecutionSummary{},
+		QueuedJobs:     []jobs.JobExecutionSummary{},
+		Timestamp:      s.now().Unix(),
+		ClientToken:    req.ClientToken,
+	}
+	for _, e := range s.pendingExecutions(thing) {
+		if e.status == jobs.InProgress {
+			res.InProgressJobs = append(res.InProgressJobs, summary(e))
+		} else {
+			res.QueuedJobs = append(res.QueuedJobs, summary(e))
+		}
+	}
+	return []*mqtt.Message{s.message(topic+"/accepted", res)}
+}
+
+func (s *Server) describe(prefix, thing, jobID string, payload []byte) []*mqtt.Message {
+	topic := prefix + "/" + jobID + "/get"
+	req := &describeJobExecutionRequest{}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, req); err != nil {
+			return s.rejected(topic, "InvalidJson", "Invalid JSON", "", nil)
+		}
+	}
+	includeDocument := req.IncludeJobDocument == nil || *req.IncludeJobDocument
+
+	var jb *job
+	var e *execution
+	if jobID == jobs.NextJobID {
+		jb, e = s.next(thing)
+	} else {
+		jb, e = s.execution(thing, jobID)
+		if e == nil || (req.ExecutionNumber != 0 && req.ExecutionNumber != e.executionNumber) {
+			return s.rejected(topic, "ResourceNotFound", "Job execution not found", req.ClientToken, nil)
+		}
+	}
+	res := &describeJobExecutionResponse{
+		Timestamp:   s.now().Unix(),
+		ClientToken: req.ClientToken,
+	}
+	if e != nil {
+		res.Execution = s.jobExecution(jb, e, includeDocument)
+	}
+	return []*mqtt.Message{s.message(topic+"/accepted", res)}
+}
+
+func (s *Server) update(prefix, thing, jobID string, payload []byte) []*mqtt.Message {
+	topic := prefix + "/" + jobID + "/update"
+	req := &updateJobExecutionRequest{}
+	if err := json.Unmarshal(payload, req); err != nil {
+		return s.rejected(topic, "InvalidJson", "Invalid JSON", "", nil)
+	}
+	switch req.Status {
+	case jobs.InProgress, jobs.Failed, jobs.Succeeded, jobs.Rejected:
+	case "":
+		return s.rejected(topic, "InvalidRequest", "Missing required field: status", req.ClientToken, nil)
+	default:
+		return s.rejected(topic, "InvalidStateTransition",
+			"Job execution can not be updated to "+string(req.Status), req.ClientToken, nil,
+		)
+	}
+	if req.StepTimeoutInMinutes < -1 {
+		return s.rejected(topic, "InvalidRequest", "Invalid stepTimeoutInMinutes", req.ClientToken, nil)
+	}
+
+	jb, e := s.execution(thing, jobID)
+	if e == nil || (req.ExecutionNumber != 0 && req.ExecutionNumber != e.executionNumber) {
+		return s.rejected(topic, "ResourceNotFound", "Job execution not found", req.ClientToken, nil)
+	}
+	if !e.pending() {
+		return s.rejected(topic, "TerminalStateReached",
+			"Job execution is already in terminal state "+string(e.status), req.ClientToken, e,
+		)
+	}
+	if req.ExpectedVersion != 0 && req.ExpectedVersion != e.versionNumber {
+		return s.rejected(topic, "VersionMismatch", "Version mismatch", req.ClientToken, e)
+	}
+
+	before := map[string]pendingState{thing: s.pending(thing)}
+	now := s.now()
+	if e.status == jobs.Queued {
+		e.startedAt = now
+		if jb.inProgressTimeout > 0 {
+			e.inProgressDeadline = now.Add(jb.inProgressTimeout)
+		}
+	}
+	e.status = req.Status
+	if req.StatusDetails != nil {
+		e.statusDetails = copyDetails(req.StatusDetails)
+	}
+	switch {
+	case req.StepTimeoutInMinutes > 0:
+		e.stepDeadline = now.Add(time.Duration(req.StepTimeoutInMinutes) * time.Minute)
+	case req.StepTimeoutInMinutes == -1:
+		e.stepDeadline = time.Time{}
+	}
+	e.lastUpdatedAt = now
+	e.versionNumber++
+
+	res := &updateJobExecutionResponse{
+		Timestamp:   now.Unix(),
+		ClientToken: req.ClientToken,
+	}
+	if req.IncludeJobExecutionState {
+		res.ExecutionState = stateDetails(e)
+	}
+	if req.IncludeJobDocument {
+		res.JobDocument = jb.document
+	}
+	msgs := []*mqtt.Message{s.message(topic+"/accepted", res)}
+	return append(msgs, s.notifications(before)...)
+}
+
+func (s *Server) startNext(prefix, thing string, payload []byte) []*mqtt.Message {
+	topic := prefix + "/start-next"
+	req := &startNextPendingJobExecutionRequest{}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, req); err != nil {
+			return s.rejected(topic, "InvalidJson", "Invalid JSON", "", nil)
+		}
+	}
+	if req.StepTimeoutInMinutes < -1 {
+		return s.rejected(topic, "InvalidRequest", "Invalid stepTimeoutInMinutes", req.ClientToken, nil)
+	}
+
+	now := s.now()
+	res := &startNextPendingJobExecutionResponse{
+		Timestamp:   now.Unix(),
+		ClientToken: req.ClientToken,
+	}
+	jb, e := s.next(thing)
+	if e == nil {
+		return []*mqtt.Message{s.message(topic+"/accepted", res)}
+	}
+	var notifications []*mqtt.Message
+	if e.status == jobs.Queued {
+		before := map[string]pendingState{thing: s.pending(thing)}
+		e.status = jobs.InProgress
+		e.statusDetails = copyDetails(req.StatusDetails)
+		e.startedAt = now
+		e.lastUpdatedAt = now
+		e.versionNumber++
+		if jb.inProgressTimeout > 0 {
+			e.inProgressDeadline = now.Add(jb.inProgressTimeout)
+		}
+		if req.StepTimeoutInMinutes > 0 {
+			e.stepDeadline = now.Add(time.Duration(req.StepTimeoutInMinutes) * time.Minute)
+		}
+		notifications = s.notifications(before)
+	}
+	res.Execution = s.jobExecution(jb, e, true)
+	return append([]*mqtt.Message{s.message(topic+"/accepted", res)}, notifications...)
+}
+
+func summary(e *execution) jobs.JobExecutionSummary {
+	return jobs.JobExecutionSummary{
+		JobID:           e.jobID,
+		QueuedAt:        e.queuedAt.Unix(),
+		StartedAt:       unix(e.startedAt),
+		LastUpdatedAt:   e.lastUpdatedAt.Unix(),
+		VersionNumber:   e.versionNumber,
+		ExecutionNumber: e.executionNumber,
+	}
+}
+
+func stateDetails(e *execution) *jobs.JobExecutionStateDetails {
+	return &jobs.JobExecutionStateDetails{
+		Status:        e.status,
+		StatusDetails: copyDetails(e.statusDetails),
+		VersionNumber: e.versionNumber,
+	}
+}
+
+func copyDetails(d map[string]string) map[string]string {
+	if d == nil {
+		return nil
+	}
+	ret := make(map[string]string, len(d))
+	for k, v := range d {
+		ret[k] = v
+	}
+	return ret
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,654 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/at-wat/mqtt-go"
+	mockmqtt "github.com/at-wat/mqtt-go/mock"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/jobs"
+)
+
+type mockClient interface {
+	mqtt.Client
+	mqtt.Handler
+}
+
+type mockDevice struct {
+	mockClient
+	mqtt.Retryer
+}
+
+func (d *mockDevice) ThingName() string {
+	return "test"
+}
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// newTestServer connects the server and the device by mock MQTT clients.
+func newTestServer(ctx context.Context, t *testing.T, opts ...Option) (*Server, *mockDevice) {
+	var srv *Server
+	cliSrv := &mockmqtt.Client{}
+	cliDev := &mockDevice{
+		mockClient: &mockmqtt.Client{
+			PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+				srv.Serve(msg)
+				return nil
+			},
+		},
+	}
+	cliSrv.PublishFn = func(ctx context.Context, msg *mqtt.Message) error {
+		cliDev.Serve(msg)
+		return nil
+	}
+	var err error
+	srv, err = New(ctx, cliSrv, append([]Option{
+		WithClock(func() time.Time { return time.Unix(1000, 0) }),
+	}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, cliDev
+}
+
+// newRawTestServer returns the server and the channel of the published messages.
+func newRawTestServer(ctx context.Context, t *testing.T, opts ...Option) (*Server, chan *mqtt.Message) {
+	ch := make(chan *mqtt.Message, 100)
+	cli := &mockmqtt.Client{
+		PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+			ch <- msg
+			return nil
+		},
+	}
+	srv, err := New(ctx, cli, append([]Option{
+		WithClock(func() time.Time { return time.Unix(1000, 0) }),
+	}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, ch
+}
+
+func receive(ctx context.Context, t *testing.T, ch chan *mqtt.Message, topic string) *mqtt.Message {
+	t.Helper()
+	for {
+		select {
+		case msg := <-ch:
+			if msg.Topic == topic {
+				return msg
+			}
+		case <-ctx.Done():
+			t.Fatalf("Timeout waiting %s", topic)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv, cli := newTestServer(ctx, t)
+	j, err := jobs.New(ctx, cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.Handle(j)
+
+	chJobs := make(chan map[jobs.JobExecutionState][]jobs.JobExecutionSummary, 10)
+	j.OnJobChange(func(jbs map[jobs.JobExecutionState][]jobs.JobExecutionSummary) { chJobs <- jbs })
+	chNext := make(chan *jobs.JobExecution, 10)
+	j.OnNextJobChange(func(je *jobs.JobExecution) { chNext <- je })
+
+	waitJobs := func(t *testing.T) map[jobs.JobExecutionState][]jobs.JobExecutionSummary {
+		t.Helper()
+		select {
+		case jbs := <-chJobs:
+			return jbs
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		}
+		return nil
+	}
+	waitNext := func(t *testing.T) *jobs.JobExecution {
+		t.Helper()
+		select {
+		case je := <-chNext:
+			return je
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		}
+		return nil
+	}
+
+	createJob := func(t *testing.T, id string) {
+		t.Helper()
+		if err := srv.CreateJob(&Job{
+			JobID:    id,
+			Targets:  []string{"test", "other"},
+			Document: json.RawMessage(`{"operation":"` + id + `"}`),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createJob(t, "job1")
+	if jbs := waitJobs(t); len(jbs[jobs.Queued]) != 1 {
+		t.Errorf("Expected 1 queued job, got: %v", jbs)
+	}
+	if je := waitNext(t); je == nil || je.JobID != "job1" {
+		t.Errorf("Expected next job: job1, got: %+v", je)
+	}
+	createJob(t, "job2")
+	if jbs := waitJobs(t); len(jbs[jobs.Queued]) != 2 {
+		t.Errorf("Expected 2 queued jobs, got: %v", jbs)
+	}
+
+	t.Run("CreateExisting", func(t *testing.T) {
+		err := srv.CreateJob(&Job{
+			JobID:    "job1",
+			Targets:  []string{"test"},
+			Document: json.RawMessage(`{}`),
+		})
+		if !errors.Is(err, ErrJobExists) {
+			t.Errorf("Expected error: %v, got: %v", ErrJobExists, err)
+		}
+	})
+	t.Run("GetPendingJobs", func(t *testing.T) {
+		jbs, err := j.GetPendingJobs(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[jobs.JobExecutionState][]jobs.JobExecutionSummary{
+			jobs.InProgress: {},
+			jobs.Queued: {
+				{JobID: "job1", QueuedAt: 1000, LastUpdatedAt: 1000, VersionNumber: 1, ExecutionNumber: 1},
+				{JobID: "job2", QueuedAt: 1000, LastUpdatedAt: 1000, VersionNumber: 1, ExecutionNumber: 1},
+			},
+		}
+		if !reflect.DeepEqual(expected, jbs) {
+			t.Errorf("Expected:\n%+v\ngot:\n%+v", expected, jbs)
+		}
+	})
+	t.Run("DescribeJob", func(t *testing.T) {
+		je, err := j.DescribeJob(ctx, "job2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]interface{}{"operation": "job2"}
+		if !reflect.DeepEqual(expected, je.JobDocument) {
+			t.Errorf("Expected document: %v, got: %v", expected, je.JobDocument)
+		}
+		if je.Status != jobs.Queued || je.ThingName != "test" {
+			t.Errorf("Unexpected execution: %+v", je)
+		}
+	})
+	t.Run("DescribeJobNotFound", func(t *testing.T) {
+		_, err := j.DescribeJob(ctx, "job3")
+		var e *jobs.ErrorResponse
+		if !errors.As(err, &e) || e.Code != "ResourceNotFound" {
+			t.Errorf("Expected ResourceNotFound, got: %v", err)
+		}
+	})
+	t.Run("DescribeNextJob", func(t *testing.T) {
+		je, err := j.DescribeJob(ctx, jobs.NextJobID, jobs.WithoutJobDocument())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if je.JobID != "job1" || je.JobDocument != nil {
+			t.Errorf("Unexpected execution: %+v", je)
+		}
+	})
+	var je *jobs.JobExecution
+	t.Run("StartNextPendingJob", func(t *testing.T) {
+		var err error
+		je, err = j.StartNextPendingJob(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if je.JobID != "job1" || je.Status != jobs.InProgress || je.VersionNumber != 2 {
+			t.Errorf("Unexpected execution: %+v", je)
+		}
+		je2, err := j.StartNextPendingJob(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if je2.JobID != "job1" || je2.VersionNumber != 2 {
+			t.Errorf("In progress execution must be returned, got: %+v", je2)
+		}
+	})
+	t.Run("UpdateJob", func(t *testing.T) {
+		if err := j.UpdateJob(ctx, je, jobs.Succeeded, jobs.WithDetail("a", "b")); err != nil {
+			t.Fatal(err)
+		}
+		if je.VersionNumber != 3 {
+			t.Errorf("Expected version: 3, got: %d", je.VersionNumber)
+		}
+		if jbs := waitJobs(t); len(jbs[jobs.Queued]) != 1 || jbs[jobs.Queued][0].JobID != "job2" {
+			t.Errorf("Expected job2 queued, got: %v", jbs)
+		}
+		if next := waitNext(t); next == nil || next.JobID != "job2" {
+			t.Errorf("Expected next job: job2, got: %+v", next)
+		}
+		st, ok := srv.DescribeJobExecution("test", "job1")
+		if !ok {
+			t.Fatal("Job execution not found")
+		}
+		if st.Status != jobs.Succeeded || !reflect.DeepEqual(map[string]string{"a": "b"}, st.StatusDetails) {
+			t.Errorf("Unexpected execution: %+v", st)
+		}
+	})
+	t.Run("UpdateTerminated", func(t *testing.T) {
+		err := j.UpdateJob(ctx, je, jobs.Failed)
+		var e *jobs.ErrorResponse
+		if !errors.As(err, &e) || e.Code != "TerminalStateReached" {
+			t.Errorf("Expected TerminalStateReached, got: %v", err)
+		}
+	})
+}
+
+func TestServer_Update(t *testing.T) {
+	testCases := map[string]struct {
+		status   jobs.JobExecutionState
+		request  string
+		topic    string
+		expected errorResponse
+	}{
+		"InvalidJSON": {
+			status:   jobs.Queued,
+			request:  `{`,
+			topic:    "$aws/things/test/jobs/job1/update/rejected",
+			expected: errorResponse{Code: "InvalidJson", Message: "Invalid JSON", Timestamp: 1000},
+		},
+		"InvalidStatus": {
+			status:  jobs.Queued,
+			request: `{"status":"CANCELED","clientToken":"t"}`,
+			topic:   "$aws/things/test/jobs/job1/update/rejected",
+			expected: errorResponse{
+				Code: "InvalidStateTransition", Message: "Job execution can not be updated to CANCELED",
+				ClientToken: "t", Timestamp: 1000,
+			},
+		},
+		"VersionMismatch": {
+			status:  jobs.Queued,
+			request: `{"status":"IN_PROGRESS","expectedVersion":2,"clientToken":"t"}`,
+			topic:   "$aws/things/test/jobs/job1/update/rejected",
+			expected: errorResponse{
+				Code: "VersionMismatch", Message: "Version mismatch", ClientToken: "t", Timestamp: 1000,
+				ExecutionState: &jobs.JobExecutionStateDetails{Status: jobs.Queued, VersionNumber: 1},
+			},
+		},
+		"TerminalStateReached": {
+			status:  jobs.Canceled,
+			request: `{"status":"IN_PROGRESS","clientToken":"t"}`,
+			topic:   "$aws/things/test/jobs/job1/update/rejected",
+			expected: errorResponse{
+				Code: "TerminalStateReached", Message: "Job execution is already in terminal state CANCELED",
+				ClientToken: "t", Timestamp: 1000,
+				ExecutionState: &jobs.JobExecutionStateDetails{Status: jobs.Canceled, VersionNumber: 2},
+			},
+		},
+		"Removed": {
+			status:  jobs.Removed,
+			request: `{"status":"IN_PROGRESS","clientToken":"t"}`,
+			topic:   "$aws/things/test/jobs/job1/update/rejected",
+			expected: errorResponse{
+				Code: "ResourceNotFound", Message: "Job execution not found", ClientToken: "t", Timestamp: 1000,
+			},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			srv, ch := newRawTestServer(ctx, t)
+			if err := srv.CreateJob(&Job{
+				JobID:    "job1",
+				Targets:  []string{"test"},
+				Document: json.RawMessage(`{}`),
+			}); err != nil {
+				t.Fatal(err)
+			}
+			switch tt.status {
+			case jobs.Canceled:
+				if err := srv.CancelJob("job1", false); err != nil {
+					t.Fatal(err)
+				}
+			case jobs.Removed:
+				if err := srv.DeleteJob("job1", false); err != nil {
+					t.Fatal(err)
+				}
+			}
+			srv.Serve(&mqtt.Message{
+				Topic:   "$aws/things/test/jobs/job1/update",
+				Payload: []byte(tt.request),
+			})
+			msg := receive(ctx, t, ch, tt.topic)
+			var res errorResponse
+			if err := json.Unmarshal(msg.Payload, &res); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.expected, res) {
+				t.Errorf("Expected:\n%+v\ngot:\n%+v", tt.expected, res)
+			}
+		})
+	}
+
+	t.Run("Accepted", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		srv, ch := newRawTestServer(ctx, t)
+		if err := srv.CreateJob(&Job{
+			JobID:    "job1",
+			Targets:  []string{"test"},
+			Document: json.RawMessage(`{"a":1}`),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		srv.Serve(&mqtt.Message{
+			Topic: "$aws/things/test/jobs/job1/update",
+			Payload: []byte(`{"status":"IN_PROGRESS","statusDetails":{"a":"b"},"expectedVersion":1,` +
+				`"includeJobExecutionState":true,"includeJobDocument":true,"clientToken":"t"}`),
+		})
+		msg := receive(ctx, t, ch, "$aws/things/test/jobs/job1/update/accepted")
+		expected := `{"executionState":{"status":"IN_PROGRESS","statusDetails":{"a":"b"},"versionNumber":2},` +
+			`"jobDocument":{"a":1},"timestamp":1000,"clientToken":"t"}`
+		if string(msg.Payload) != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, msg.Payload)
+		}
+	})
+}
+
+func TestServer_Timeout(t *testing.T) {
+	testCases := map[string]struct {
+		job      *Job
+		request  string
+		elapsed  time.Duration
+		expected jobs.JobExecutionState
+	}{
+		"StepTimeout": {
+			job:      &Job{JobID: "job1", Targets: []string{"test"}, Document: json.RawMessage(`{}`)},
+			request:  `{"stepTimeoutInMinutes":1}`,
+			elapsed:  time.Minute,
+			expected: jobs.TimedOut,
+		},
+		"BeforeStepTimeout": {
+			job:      &Job{JobID: "job1", Targets: []string{"test"}, Document: json.RawMessage(`{}`)},
+			request:  `{"stepTimeoutInMinutes":1}`,
+			elapsed:  59 * time.Second,
+			expected: jobs.InProgress,
+		},
+		"InProgressTimeout": {
+			job: &Job{
+				JobID: "job1", Targets: []string{"test"}, Document: json.RawMessage(`{}`),
+				InProgressTimeout: 10 * time.Minute,
+			},
+			request:  `{}`,
+			elapsed:  10 * time.Minute,
+			expected: jobs.TimedOut,
+		},
+		"NoTimeout": {
+			job:      &Job{JobID: "job1", Targets: []string{"test"}, Document: json.RawMessage(`{}`)},
+			request:  `{}`,
+			elapsed:  24 * time.Hour,
+			expected: jobs.InProgress,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			clock := &testClock{now: time.Unix(1000, 0)}
+			srv, ch := newRawTestServer(ctx, t,
+				WithClock(clock.Now),
+				WithTimeoutCheckInterval(10*time.Millisecond),
+			)
+			if err := srv.CreateJob(tt.job); err != nil {
+				t.Fatal(err)
+			}
+			srv.Serve(&mqtt.Message{
+				Topic:   "$aws/things/test/jobs/start-next",
+				Payload: []byte(tt.request),
+			})
+			receive(ctx, t, ch, "$aws/things/test/jobs/start-next/accepted")
+
+			clock.Add(tt.elapsed)
+			if tt.expected == jobs.TimedOut {
+				msg := receive(ctx, t, ch, "$aws/things/test/jobs/notify")
+				expected := `{"jobs":{},"timestamp":` + jsonInt(clock.Now().Unix()) + `}`
+				if string(msg.Payload) != expected {
+					t.Errorf("Expected notification:\n%s\ngot:\n%s", expected, msg.Payload)
+				}
+			}
+			je, ok := srv.DescribeJobExecution("test", "job1")
+			if !ok {
+				t.Fatal("Job execution not found")
+			}
+			if je.Status != tt.expected {
+				t.Errorf("Expected status: %s, got: %s", tt.expected, je.Status)
+			}
+		})
+	}
+}
+
+func jsonInt(i int64) string {
+	b, _ := json.Marshal(i)
+	return string(b)
+}
+
+func TestServer_Cancel(t *testing.T) {
+	testCases := map[string]struct {
+		fn       func(*Server) error
+		err      error
+		expected map[string]jobs.JobExecutionState
+	}{
+		"Cancel": {
+			fn:  func(s *Server) error { return s.CancelJob("job1", false) },
+			err: nil,
+			expected: map[string]jobs.JobExecutionState{
+				"queued": jobs.Canceled, "inprogress": jobs.InProgress, "done": jobs.Succeeded,
+			},
+		},
+		"ForceCancel": {
+			fn: func(s *Server) error { return s.CancelJob("job1", true) },
+			expected: map[string]jobs.JobExecutionState{
+				"queued": jobs.Canceled, "inprogress": jobs.Canceled, "done": jobs.Succeeded,
+			},
+		},
+		"Delete": {
+			fn:  func(s *Server) error { return s.DeleteJob("job1", false) },
+			err: ErrJobInProgress,
+			expected: map[string]jobs.JobExecutionState{
+				"queued": jobs.Queued, "inprogress": jobs.InProgress, "done": jobs.Succeeded,
+			},
+		},
+		"ForceDelete": {
+			fn: func(s *Server) error { return s.DeleteJob("job1", true) },
+			expected: map[string]jobs.JobExecutionState{
+				"queued": jobs.Removed, "inprogress": jobs.Removed, "done": jobs.Succeeded,
+			},
+		},
+		"NotFound": {
+			fn:  func(s *Server) error { return s.CancelJob("job2", false) },
+			err: ErrJobNotFound,
+			expected: map[string]jobs.JobExecutionState{
+				"queued": jobs.Queued, "inprogress": jobs.InProgress, "done": jobs.Succeeded,
+			},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			srv, ch := newRawTestServer(ctx, t)
+			if err := srv.CreateJob(&Job{
+				JobID:    "job1",
+				Targets:  []string{"queued", "inprogress", "done"},
+				Document: json.RawMessage(`{}`),
+			}); err != nil {
+				t.Fatal(err)
+			}
+			for thing, st := range map[string]jobs.JobExecutionState{
+				"inprogress": jobs.InProgress,
+				"done":       jobs.Succeeded,
+			} {
+				srv.Serve(&mqtt.Message{
+					Topic:   "$aws/things/" + thing + "/jobs/job1/update",
+					Payload: []byte(`{"status":"` + string(st) + `"}`),
+				})
+				receive(ctx, t, ch, "$aws/things/"+thing+"/jobs/job1/update/accepted")
+			}
+
+			if err := tt.fn(srv); !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error: %v, got: %v", tt.err, err)
+			}
+			for thing, st := range tt.expected {
+				je, ok := srv.DescribeJobExecution(thing, "job1")
+				if !ok {
+					t.Fatalf("Job execution of %s not found", thing)
+				}
+				if je.Status != st {
+					t.Errorf("Expected status of %s: %s, got: %s", thing, st, je.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_DescribeNext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	srv, ch := newRawTestServer(ctx, t)
+	srv.Serve(&mqtt.Message{
+		Topic:   "$aws/things/test/jobs/$next/get",
+		Payload: []byte(`{"clientToken":"t"}`),
+	})
+	msg := receive(ctx, t, ch, "$aws/things/test/jobs/$next/get/accepted")
+	if expected := `{"timestamp":1000,"clientToken":"t"}`; string(msg.Payload) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, msg.Payload)
+	}
+
+	if err := srv.CreateJob(&Job{
+		JobID:    "job1",
+		Targets:  []string{"test"},
+		Document: json.RawMessage(`{"a":1}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	srv.Serve(&mqtt.Message{
+		Topic:   "$aws/things/test/jobs/$next/get",
+		Payload: []byte(`{"includeJobDocument":false,"clientToken":"t"}`),
+	})
+	msg = receive(ctx, t, ch, "$aws/things/test/jobs/$next/get/accepted")
+	expected := `{"execution":{"jobId":"job1","thingName":"test","status":"QUEUED",` +
+		`"queuedAt":1000,"lastUpdatedAt":1000,"versionNumber":1,"executionNumber":1},` +
+		`"timestamp":1000,"clientToken":"t"}`
+	if string(msg.Payload) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, msg.Payload)
+	}
+}
+
+func TestServer_ConcurrentWorker(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv, cli := newTestServer(ctx, t)
+	j, err := jobs.New(ctx, cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.Handle(j)
+
+	const n = 8
+	var ids []string
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("job%d", i)
+		ids = append(ids, id)
+		if err := srv.CreateJob(&Job{
+			JobID:    id,
+			Targets:  []string{"test"},
+			Document: json.RawMessage(`{"operation":"op"}`),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := jobs.NewWorker(j, jobs.WithConcurrency(4))
+	w.OnError(func(err error) {
+		t.Errorf("Unexpected error: %v", err)
+	})
+	w.Handle("op", func(ctx context.Context, je *jobs.JobExecution, p *jobs.JobProgress) error {
+		p.SetDetail("id", je.JobID)
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	wctx, wcancel := context.WithCancel(ctx)
+	chRun := make(chan error)
+	go func() { chRun <- w.Run(wctx) }()
+
+	for _, id := range ids {
+		for {
+			je, ok := srv.DescribeJobExecution("test", id)
+			if !ok {
+				t.Fatalf("Job execution of %s not found", id)
+			}
+			if je.Status == jobs.Succeeded {
+				if je.StatusDetails["id"] != id {
+					t.Errorf("Expected details of %s, got: %v", id, je.StatusDetails)
+				}
+				break
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatalf("Timeout waiting %s, status: %s", id, je.Status)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	wcancel()
+	if err := <-chRun; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error: %v, got: %v", context.Canceled, err)
+	}
+}
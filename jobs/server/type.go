@@ -0,0 +1,106 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/jobs"
+)
+
+type jobExecution struct {
+	JobID                            string                 `json:"jobId"`
+	ThingName                        string                 `json:"thingName"`
+	JobDocument                      json.RawMessage        `json:"jobDocument,omitempty"`
+	Status                           jobs.JobExecutionState `json:"status"`
+	StatusDetails                    map[string]string      `json:"statusDetails,omitempty"`
+	QueuedAt                         int64                  `json:"queuedAt"`
+	StartedAt                        int64                  `json:"startedAt,omitempty"`
+	LastUpdatedAt                    int64                  `json:"lastUpdatedAt"`
+	VersionNumber                    int                    `json:"versionNumber"`
+	ExecutionNumber                  int                    `json:"executionNumber"`
+	ApproximateSecondsBeforeTimedOut int64                  `json:"approximateSecondsBeforeTimedOut,omitempty"`
+}
+
+type errorResponse struct {
+	Code           string                         `json:"code"`
+	Message        string                         `json:"message"`
+	ClientToken    string                         `json:"clientToken,omitempty"`
+	Timestamp      int64                          `json:"timestamp"`
+	ExecutionState *jobs.JobExecutionStateDetails `json:"executionState,omitempty"`
+}
+
+type jobExecutionsChangedMessage struct {
+	Jobs      map[jobs.JobExecutionState][]jobs.JobExecutionSummary `json:"jobs"`
+	Timestamp int64                                                 `json:"timestamp"`
+}
+
+type nextJobExecutionChangedMessage struct {
+	Execution *jobExecution `json:"execution,omitempty"`
+	Timestamp int64         `json:"timestamp"`
+}
+
+type simpleRequest struct {
+	ClientToken string `json:"clientToken"`
+}
+
+type getPendingJobExecutionsResponse struct {
+	InProgressJobs []jobs.JobExecutionSummary `json:"inProgressJobs"`
+	QueuedJobs     []jobs.JobExecutionSummary `json:"queuedJobs"`
+	Timestamp      int64                      `json:"timestamp"`
+	ClientToken    string                     `json:"clientToken,omitempty"`
+}
+
+type describeJobExecutionRequest struct {
+	ExecutionNumber    int    `json:"executionNumber"`
+	IncludeJobDocument *bool  `json:"includeJobDocument"`
+	ClientToken        string `json:"clientToken"`
+}
+
+type describeJobExecutionResponse struct {
+	Execution   *jobExecution `json:"execution,omitempty"`
+	Timestamp   int64         `json:"timestamp"`
+	ClientToken string        `json:"clientToken,omitempty"`
+}
+
+type updateJobExecutionRequest struct {
+	Status                   jobs.JobExecutionState `json:"status"`
+	StatusDetails            map[string]string      `json:"statusDetails"`
+	ExpectedVersion          int                    `json:"expectedVersion"`
+	ExecutionNumber          int                    `json:"executionNumber"`
+	IncludeJobExecutionState bool                   `json:"includeJobExecutionState"`
+	IncludeJobDocument       bool                   `json:"includeJobDocument"`
+	StepTimeoutInMinutes     int                    `json:"stepTimeoutInMinutes"`
+	ClientToken              string                 `json:"clientToken"`
+}
+
+type updateJobExecutionResponse struct {
+	ExecutionState *jobs.JobExecutionStateDetails `json:"executionState,omitempty"`
+	JobDocument    json.RawMessage                `json:"jobDocument,omitempty"`
+	Timestamp      int64                          `json:"timestamp"`
+	ClientToken    string                         `json:"clientToken,omitempty"`
+}
+
+type startNextPendingJobExecutionRequest struct {
+	StatusDetails        map[string]string `json:"statusDetails"`
+	StepTimeoutInMinutes int               `json:"stepTimeoutInMinutes"`
+	ClientToken          string            `json:"clientToken"`
+}
+
+type startNextPendingJobExecutionResponse struct {
+	Execution   *jobExecution `json:"execution,omitempty"`
+	Timestamp   int64         `json:"timestamp"`
+	ClientToken string        `json:"clientToken,omitempty"`
+}
@@ -15,6 +15,7 @@
 package jobs
 
 import (
+	"encoding/json"
 	"fmt"
 )
 
@@ -33,6 +34,9 @@ const (
 	Removed    JobExecutionState = "REMOVED"
 )
 
+// NextJobID is a special job ID to specify the next pending job.
+const NextJobID = "$next"
+
 // JobExecutionSummary represents summary of a job.
 type JobExecutionSummary struct {
 	JobID           string `json:"jobId"`
@@ -55,6 +59,10 @@ type JobExecution struct {
 	LastUpdatedAt   int64             `json:"lastUpdatedAt"`
 	VersionNumber   int               `json:"versionNumber"`
 	ExecutionNumber int               `json:"executionNumber"`
+	// ApproximateSecondsBeforeTimedOut is a remaining time before the
+	// IN_PROGRESS execution is moved to TIMED_OUT.
+	// Zero if no timeout is set.
+	ApproximateSecondsBeforeTimedOut int64 `json:"approximateSecondsBeforeTimedOut"`
 }
 
 // JobExecutionStateDetails represents details of JobExecutionState.
@@ -78,11 +86,22 @@ func (e *ErrorResponse) Error() string {
 	return fmt.Sprintf("%s (%s): %s", e.Code, e.ClientToken, e.Message)
 }
 
+// Is implements errors.Is interface.
+// VersionMismatch error response matches ErrVersionMismatch.
+func (e *ErrorResponse) Is(target error) bool {
+	return target == ErrVersionMismatch && e.Code == "VersionMismatch"
+}
+
 type jobExecutionsChangedMessage struct {
 	Jobs      map[JobExecutionState][]JobExecutionSummary `json:"jobs"`
 	Timestamp int64                                       `json:"timestamp"`
 }
 
+type nextJobExecutionChangedMessage struct {
+	Execution json.RawMessage `json:"execution"`
+	Timestamp int64           `json:"timestamp"`
+}
+
 type simpleRequest struct {
 	ClientToken string `json:"clientToken"`
 }
@@ -122,8 +141,22 @@ type updateJobExecutionRequest struct {
 }
 
 type updateJobExecutionResponse struct {
-	ExecutionState JobExecutionState `json:"executionState"`
-	JobDocument    interface{}       `json:"jobDocument"`
-	Timestamp      int64             `json:"timestamp"`
-	ClientToken    string            `json:"clientToken"`
+	ExecutionState *JobExecutionStateDetails `json:"executionState"`
+	JobDocument    interface{}               `json:"jobDocument"`
+	Timestamp      int64                     `json:"timestamp"`
+	ClientToken    string                    `json:"clientToken"`
+}
+
+type startNextPendingJobExecutionRequest struct {
+	StatusDetails        map[string]string `json:"statusDetails,omitempty"`
+	StepTimeoutInMinutes int               `json:"stepTimeoutInMinutes,omitempty"`
+	ClientToken          string            `json:"clientToken"`
+}
+
+type startNextPendingJobExecutionResponse struct {
+	Execution   json.RawMessage `json:"execution"`
+	Timestamp   int64           `json:"timestamp"`
+	ClientToken string          `json:"clientToken"`
+
+	execution *JobExecution
 }
@@ -0,0 +1,510 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+// Status detail keys set by Worker.
+const (
+	// DetailReason is a status detail key of the failure or rejection reason.
+	DetailReason = "reason"
+)
+
+// JobHandler processes the job execution.
+// ctx is canceled if the job is canceled or removed, or the worker is stopped.
+// Returning error marks the job as Failed. Wrap ErrJobRejected to mark
+// the job as Rejected.
+type JobHandler func(ctx context.Context, je *JobExecution, progress *JobProgress) error
+
+// JobProgress stores status details of the running job.
+// The details are periodically sent to AWS IoT while the handler is running
+// and also sent with the final status.
+type JobProgress struct {
+	mu         sync.Mutex
+	details    map[string]string
+	changed    bool
+	checkpoint string
+	save       func(checkpoint string, details map[string]string) error
+}
+
+// Checkpoint returns the last step checkpoint.
+// If the job is resumed after the restart, the checkpoint recorded
+// in the Journal before the restart is returned.
+func (p *JobProgress) Checkpoint() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.checkpoint
+}
+
+// SetCheckpoint records the step checkpoint.
+// The checkpoint is immediately stored in the Journal if available.
+func (p *JobProgress) SetCheckpoint(checkpoint string) error {
+	p.mu.Lock()
+	p.checkpoint = checkpoint
+	details := make(map[string]string, len(p.details))
+	for k, v := range p.details {
+		details[k] = v
+	}
+	save := p.save
+	p.mu.Unlock()
+	if save == nil {
+		return nil
+	}
+	return save(checkpoint, details)
+}
+
+// SetDetail sets status detail of the running job.
+func (p *JobProgress) SetDetail(key, val string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.details == nil {
+		p.details = make(map[string]string)
+	}
+	if old, ok := p.details[key]; ok && old == val {
+		return
+	}
+	p.details[key] = val
+	p.changed = true
+}
+
+// snapshot returns copy of the details and whether the details are
+// changed since the last snapshot.
+func (p *JobProgress) snapshot() (map[string]string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	d := make(map[string]string, len(p.details))
+	for k, v := range p.details {
+		d[k] = v
+	}
+	changed := p.changed
+	p.changed = false
+	return d, changed
+}
+
+// Worker is an interface of the job execution worker.
+// Worker receives job change notifications, describes the pending jobs and
+// calls the handler registered for the "operation" of the job document.
+type Worker interface {
+	// Handle registers the handler of the job document operation.
+	Handle(operation string, h JobHandler)
+	// Run processes the jobs until the context is canceled.
+	// Run overwrites the handler set by Jobs.OnJobChange.
+	// It returns after all running handlers are returned.
+	Run(ctx context.Context) error
+	// OnError sets handler of asynchronous errors.
+	OnError(func(error))
+}
+
+type worker struct {
+	jobs     Jobs
+	opts     WorkerOptions
+	mu       sync.Mutex
+	handlers map[string]JobHandler
+	running  map[string]context.CancelFunc
+	pending  []string
+	entries  map[string]*JournalEntry
+	onError  func(error)
+	chKick   chan struct{}
+}
+
+// NewWorker creates job execution worker.
+func NewWorker(j Jobs, opt ...WorkerOption) Worker {
+	opts := WorkerOptions{
+		Concurrency:      1,
+		ProgressInterval: time.Minute,
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	return &worker{
+		jobs:     j,
+		opts:     opts,
+		handlers: make(map[string]JobHandler),
+		running:  make(map[string]context.CancelFunc),
+		entries:  make(map[string]*JournalEntry),
+		chKick:   make(chan struct{}, 1),
+	}
+}
+
+func (w *worker) Handle(operation string, h JobHandler) {
+	w.mu.Lock()
+	w.handlers[operation] = h
+	w.mu.Unlock()
+}
+
+func (w *worker) OnError(cb func(error)) {
+	w.mu.Lock()
+	w.onError = cb
+	w.mu.Unlock()
+}
+
+func (w *worker) handleError(err error) {
+	w.mu.Lock()
+	cb := w.onError
+	w.mu.Unlock()
+	if cb != nil {
+		cb(err)
+	}
+}
+
+func (w *worker) kick() {
+	select {
+	case w.chKick <- struct{}{}:
+	default:
+	}
+}
+
+func (w *worker) Run(ctx context.Context) error {
+	w.jobs.OnJobChange(w.onJobChange)
+	defer w.jobs.OnJobChange(nil)
+
+	jbs, err := w.jobs.GetPendingJobs(ctx)
+	if err != nil {
+		return ioterr.New(err, "getting pending jobs")
+	}
+	if w.opts.Journal != nil {
+		if err := w.reconcileJournal(ctx, jbs); err != nil {
+			return err
+		}
+	}
+	w.onJobChange(jbs)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		w.mu.Lock()
+		for len(w.running) < w.opts.Concurrency && len(w.pending) > 0 {
+			id := w.pending[0]
+			w.pending = w.pending[1:]
+			jctx, cancel := context.WithCancel(ctx)
+			w.running[id] = cancel
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w.process(ctx, jctx, cancel, id)
+				cancel()
+				w.mu.Lock()
+				delete(w.running, id)
+				w.mu.Unlock()
+				w.kick()
+			}()
+		}
+		w.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.chKick:
+		}
+	}
+}
+
+func (w *worker) onJobChange(jbs map[JobExecutionState][]JobExecutionSummary) {
+	var summaries []JobExecutionSummary
+	active := make(map[string]bool)
+	// Jobs left in progress (e.g. by the restart of the device) are resumed first.
+	for _, s := range []JobExecutionState{InProgress, Queued} {
+		ss := append([]JobExecutionSummary{}, jbs[s]...)
+		sort.SliceStable(ss, func(i, j int) bool {
+			return ss[i].QueuedAt < ss[j].QueuedAt
+		})
+		for _, js := range ss {
+			active[js.JobID] = true
+		}
+		summaries = append(summaries, ss...)
+	}
+
+	w.mu.Lock()
+	for id, cancel := range w.running {
+		if !active[id] {
+			// Job is canceled or removed.
+			cancel()
+		}
+	}
+	w.pending = w.pending[:0]
+	for _, js := range summaries {
+		if _, ok := w.running[js.JobID]; !ok {
+			w.pending = append(w.pending, js.JobID)
+		}
+	}
+	w.mu.Unlock()
+
+	w.kick()
+}
+
+// reconcileJournal applies ResumePolicy to the journal entries recorded
+// before the restart.
+// Entries of the jobs no longer pending are dropped.
+func (w *worker) reconcileJournal(ctx context.Context, jbs map[JobExecutionState][]JobExecutionSummary) error {
+	entries, err := w.opts.Journal.Load()
+	if err != nil {
+		return ioterr.New(err, "loading journal")
+	}
+	inProgress := make(map[string]bool)
+	for _, js := range jbs[InProgress] {
+		inProgress[js.JobID] = true
+	}
+	for _, e := range entries {
+		e := e
+		if inProgress[e.JobID] {
+			switch w.opts.ResumePolicy {
+			case ResumeInterrupted:
+				w.mu.Lock()
+				w.entries[e.JobID] = &e
+				w.mu.Unlock()
+				continue
+			case FailInterrupted:
+				if err := w.failInterrupted(ctx, &e); err != nil {
+					return err
+				}
+			}
+		}
+		if err := w.opts.Journal.Remove(e.JobID); err != nil {
+			return ioterr.New(err, "removing journal entry")
+		}
+	}
+	return nil
+}
+
+func (w *worker) failInterrupted(ctx context.Context, e *JournalEntry) error {
+	je, err := w.jobs.DescribeJob(ctx, e.JobID)
+	if err != nil {
+		return ioterr.New(err, "describing interrupted job")
+	}
+	details := make(map[string]string, len(e.Details)+1)
+	for k, v := range e.Details {
+		details[k] = v
+	}
+	details[DetailReason] = "interrupted"
+	if err := w.update(ctx, je, Failed, details); err != nil && !isTerminal(err) {
+		return err
+	}
+	return nil
+}
+
+func (w *worker) saveEntry(e *JournalEntry) {
+	if w.opts.Journal == nil {
+		return
+	}
+	if err := w.opts.Journal.Save(e); err != nil {
+		w.handleError(ioterr.New(err, "saving journal entry"))
+	}
+}
+
+func (w *worker) removeEntry(id string) {
+	w.mu.Lock()
+	delete(w.entries, id)
+	w.mu.Unlock()
+	if w.opts.Journal == nil {
+		return
+	}
+	if err := w.opts.Journal.Remove(id); err != nil {
+		w.handleError(ioterr.New(err, "removing journal entry"))
+	}
+}
+
+func (w *worker) process(runCtx, ctx context.Context, cancel func(), id string) {
+	je, err := w.jobs.DescribeJob(ctx, id)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.handleError(ioterr.New(err, "describing job"))
+		}
+		return
+	}
+	if je.Status != Queued && je.Status != InProgress {
+		w.removeEntry(id)
+		return
+	}
+	defer func() {
+		if runCtx.Err() == nil {
+			// Job is finished, canceled or removed.
+			w.removeEntry(id)
+		}
+	}()
+
+	op, err := jobOperation(je.JobDocument)
+	w.mu.Lock()
+	h, ok := w.handlers[op]
+	w.mu.Unlock()
+	if err != nil || !ok {
+		reason := fmt.Sprintf("unknown operation %q", op)
+		if err != nil {
+			reason = "invalid job document: " + err.Error()
+		}
+		w.update(ctx, je, Rejected, map[string]string{DetailReason: reason})
+		return
+	}
+
+	w.mu.Lock()
+	entry, ok := w.entries[id]
+	if !ok {
+		entry = &JournalEntry{
+			JobID:           id,
+			Operation:       op,
+			ExecutionNumber: je.ExecutionNumber,
+		}
+		w.entries[id] = entry
+	}
+	progress := &JobProgress{
+		details:    copyEntry(entry).Details,
+		checkpoint: entry.Checkpoint,
+		save: func(checkpoint string, details map[string]string) error {
+			w.mu.Lock()
+			entry.Checkpoint = checkpoint
+			entry.Details = details
+			e := copyEntry(entry)
+			w.mu.Unlock()
+			if w.opts.Journal == nil {
+				return nil
+			}
+			if err := w.opts.Journal.Save(&e); err != nil {
+				return ioterr.New(err, "saving checkpoint")
+			}
+			return nil
+		},
+	}
+	w.mu.Unlock()
+
+	details, _ := progress.snapshot()
+	if err := w.update(ctx, je, InProgress, details); err != nil {
+		if isTerminal(err) {
+			w.removeEntry(id)
+		}
+		return
+	}
+	w.mu.Lock()
+	entry.VersionNumber = je.VersionNumber
+	e := copyEntry(entry)
+	w.mu.Unlock()
+	w.saveEntry(&e)
+
+	done := make(chan error, 1)
+	jeCopy := *je
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- h(ctx, &jeCopy, progress)
+	}()
+
+	ticker := time.NewTicker(w.opts.ProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			if ctx.Err() != nil {
+				// Job is canceled or removed, or the worker is stopped.
+				return
+			}
+			details, _ := progress.snapshot()
+			switch {
+			case err == nil:
+				w.update(ctx, je, Succeeded, details)
+			case errors.Is(err, ErrJobRejected):
+				details[DetailReason] = err.Error()
+				w.update(ctx, je, Rejected, details)
+			default:
+				details[DetailReason] = err.Error()
+				w.update(ctx, je, Failed, details)
+			}
+			return
+		case <-ticker.C:
+			details, changed := progress.snapshot()
+			if !changed && w.opts.StepTimeoutMinutes == 0 {
+				continue
+			}
+			if err := w.update(ctx, je, InProgress, details); err != nil {
+				if isTerminal(err) {
+					cancel()
+				}
+				continue
+			}
+			w.mu.Lock()
+			entry.VersionNumber = je.VersionNumber
+			entry.Details = details
+			e := copyEntry(entry)
+			w.mu.Unlock()
+			w.saveEntry(&e)
+		}
+	}
+}
+
+func (w *worker) update(ctx context.Context, je *JobExecution, s JobExecutionState, details map[string]string) error {
+	var opts []UpdateJobOption
+	for k, v := range details {
+		opts = append(opts, WithDetail(k, v))
+	}
+	if s == InProgress && w.opts.StepTimeoutMinutes > 0 {
+		opts = append(opts, WithTimeout(w.opts.StepTimeoutMinutes))
+	}
+	if err := w.jobs.UpdateJob(ctx, je, s, opts...); err != nil {
+		if ctx.Err() == nil {
+			w.handleError(ioterr.Newf(err, "updating job %s to %s", je.JobID, s))
+		}
+		return err
+	}
+	return nil
+}
+
+// jobOperation returns "operation" field of the job document.
+func jobOperation(doc interface{}) (string, error) {
+	if m, ok := doc.(map[string]interface{}); ok {
+		op, _ := m["operation"].(string)
+		return op, nil
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	d := &struct {
+		Operation string `json:"operation"`
+	}{}
+	if err := json.Unmarshal(b, d); err != nil {
+		return "", err
+	}
+	return d.Operation, nil
+}
+
+// isTerminal returns true if the update is rejected since the job
+// execution is already in a terminal state.
+func isTerminal(err error) bool {
+	var er *ErrorResponse
+	if !errors.As(err, &er) {
+		return false
+	}
+	if er.Code == "TerminalStateReached" {
+		return true
+	}
+	switch er.ExecutionState.Status {
+	case Failed, Succeeded, Canceled, TimedOut, Rejected, Removed:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,406 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type jobUpdate struct {
+	JobID   string
+	Status  JobExecutionState
+	Details map[string]string
+	Timeout int
+}
+
+type stubJobs struct {
+	Jobs
+	mu          sync.Mutex
+	executions  map[string]*JobExecution
+	onJobChange func(map[JobExecutionState][]JobExecutionSummary)
+	chUpdate    chan jobUpdate
+}
+
+func newStubJobs(jes ...*JobExecution) *stubJobs {
+	s := &stubJobs{
+		executions: make(map[string]*JobExecution),
+		chUpdate:   make(chan jobUpdate, 100),
+	}
+	for _, je := range jes {
+		s.executions[je.JobID] = je
+	}
+	return s
+}
+
+func (s *stubJobs) summaries() map[JobExecutionState][]JobExecutionSummary {
+	ret := make(map[JobExecutionState][]JobExecutionSummary)
+	for _, je := range s.executions {
+		if je.Status == Queued || je.Status == InProgress {
+			ret[je.Status] = append(ret[je.Status], JobExecutionSummary{
+				JobID:         je.JobID,
+				QueuedAt:      je.QueuedAt,
+				VersionNumber: je.VersionNumber,
+			})
+		}
+	}
+	return ret
+}
+
+func (s *stubJobs) GetPendingJobs(ctx context.Context) (map[JobExecutionState][]JobExecutionSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.summaries(), nil
+}
+
+func (s *stubJobs) DescribeJob(ctx context.Context, id string, opt ...DescribeJobOption) (*JobExecution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id == NextJobID {
+		var next *JobExecution
+		for _, je := range s.executions {
+			if je.Status != Queued && je.Status != InProgress {
+				continue
+			}
+			if next == nil || je.QueuedAt < next.QueuedAt {
+				next = je
+			}
+		}
+		if next == nil {
+			return nil, nil
+		}
+		c := *next
+		return &c, nil
+	}
+	je, ok := s.executions[id]
+	if !ok {
+		return nil, &ErrorResponse{Code: "ResourceNotFound"}
+	}
+	c := *je
+	return &c, nil
+}
+
+func (s *stubJobs) UpdateJob(ctx context.Context, je *JobExecution, st JobExecutionState, opt ...UpdateJobOption) error {
+	opts := &UpdateJobOptions{Details: make(map[string]string)}
+	for _, o := range opt {
+		o(opts)
+	}
+	s.mu.Lock()
+	cur, ok := s.executions[je.JobID]
+	if !ok {
+		s.mu.Unlock()
+		return &ErrorResponse{Code: "ResourceNotFound"}
+	}
+	switch cur.Status {
+	case Queued, InProgress:
+	default:
+		s.mu.Unlock()
+		return &ErrorResponse{
+			Code:           "InvalidStateTransition",
+			ExecutionState: JobExecutionStateDetails{Status: cur.Status},
+		}
+	}
+	if cur.VersionNumber != je.VersionNumber {
+		s.mu.Unlock()
+		return &ErrorResponse{Code: "VersionMismatch"}
+	}
+	cur.Status = st
+	cur.VersionNumber++
+	je.Status = cur.Status
+	je.VersionNumber = cur.VersionNumber
+	jbs := s.summaries()
+	cb := s.onJobChange
+	s.mu.Unlock()
+
+	s.chUpdate <- jobUpdate{
+		JobID:   je.JobID,
+		Status:  st,
+		Details: opts.Details,
+		Timeout: opts.TimeoutMinutes,
+	}
+	if cb != nil {
+		go cb(jbs)
+	}
+	return nil
+}
+
+func (s *stubJobs) OnJobChange(cb func(map[JobExecutionState][]JobExecutionSummary)) {
+	s.mu.Lock()
+	s.onJobChange = cb
+	s.mu.Unlock()
+}
+
+func (s *stubJobs) setStatus(id string, st JobExecutionState) {
+	s.mu.Lock()
+	s.executions[id].Status = st
+	jbs := s.summaries()
+	cb := s.onJobChange
+	s.mu.Unlock()
+	if cb != nil {
+		cb(jbs)
+	}
+}
+
+func (s *stubJobs) waitUpdate(t *testing.T) jobUpdate {
+	t.Helper()
+	select {
+	case u := <-s.chUpdate:
+		return u
+	case <-time.After(time.Second):
+		t.Fatal("Timeout")
+	}
+	return jobUpdate{}
+}
+
+func TestWorker(t *testing.T) {
+	testCases := map[string]struct {
+		document interface{}
+		handler  JobHandler
+		expected []jobUpdate
+	}{
+		"Succeeded": {
+			document: map[string]interface{}{"operation": "reboot"},
+			handler: func(ctx context.Context, je *JobExecution, p *JobProgress) error {
+				p.SetDetail("step", "done")
+				return nil
+			},
+			expected: []jobUpdate{
+				{JobID: "job1", Status: InProgress, Details: map[string]string{}},
+				{JobID: "job1", Status: Succeeded, Details: map[string]string{"step": "done"}},
+			},
+		},
+		"TypedDocument": {
+			document: &struct {
+				Operation string `json:"operation"`
+			}{Operation: "reboot"},
+			handler: func(ctx context.Context, je *JobExecution, p *JobProgress) error {
+				return nil
+			},
+			expected: []jobUpdate{
+				{JobID: "job1", Status: InProgress, Details: map[string]string{}},
+				{JobID: "job1", Status: Succeeded, Details: map[string]string{}},
+			},
+		},
+		"Failed": {
+			document: map[string]interface{}{"operation": "reboot"},
+			handler: func(ctx context.Context, je *JobExecution, p *JobProgress) error {
+				return errors.New("dummy")
+			},
+			expected: []jobUpdate{
+				{JobID: "job1", Status: InProgress, Details: map[string]string{}},
+				{JobID: "job1", Status: Failed, Details: map[string]string{DetailReason: "dummy"}},
+			},
+		},
+		"Panic": {
+			document: map[string]interface{}{"operation": "reboot"},
+			handler: func(ctx context.Context, je *JobExecution, p *JobProgress) error {
+				panic("dummy")
+			},
+			expected: []jobUpdate{
+				{JobID: "job1", Status: InProgress, Details: map[string]string{}},
+				{JobID: "job1", Status: Failed, Details: map[string]string{DetailReason: "panic: dummy"}},
+			},
+		},
+		"Rejected": {
+			document: map[string]interface{}{"operation": "reboot"},
+			handler: func(ctx context.Context, je *JobExecution, p *JobProgress) error {
+				return fmt.Errorf("%w: bad argument", ErrJobRejected)
+			},
+			expected: []jobUpdate{
+				{JobID: "job1", Status: InProgress, Details: map[string]string{}},
+				{JobID: "job1", Status: Rejected, Details: map[string]string{DetailReason: "job rejected: bad argument"}},
+			},
+		},
+		"UnknownOperation": {
+			document: map[string]interface{}{"operation": "unknown"},
+			expected: []jobUpdate{
+				{JobID: "job1", Status: Rejected, Details: map[string]string{DetailReason: `unknown operation "unknown"`}},
+			},
+		},
+	}
+
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			j := newStubJobs(&JobExecution{
+				JobID:         "job1",
+				Status:        Queued,
+				JobDocument:   tt.document,
+				VersionNumber: 1,
+			})
+			w := NewWorker(j)
+			if tt.handler != nil {
+				w.Handle("reboot", tt.handler)
+			}
+			w.OnError(func(err error) {
+				t.Errorf("Unexpected error: %v", err)
+			})
+			chRun := make(chan error)
+			go func() { chRun <- w.Run(ctx) }()
+
+			for _, expected := range tt.expected {
+				if u := j.waitUpdate(t); !reflect.DeepEqual(expected, u) {
+					t.Errorf("Expected update: %+v, got: %+v", expected, u)
+				}
+			}
+			cancel()
+			if err := <-chRun; !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected error: %v, got: %v", context.Canceled, err)
+			}
+			select {
+			case u := <-j.chUpdate:
+				t.Errorf("Unexpected update: %+v", u)
+			default:
+			}
+		})
+	}
+
+	t.Run("Progress", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		j := newStubJobs(&JobExecution{
+			JobID:         "job1",
+			Status:        Queued,
+			JobDocument:   map[string]interface{}{"operation": "download"},
+			VersionNumber: 1,
+		})
+		w := NewWorker(j,
+			WithProgressInterval(10*time.Millisecond),
+			WithStepTimeout(5),
+		)
+		chDone := make(chan struct{})
+		w.Handle("download", func(ctx context.Context, je *JobExecution, p *JobProgress) error {
+			p.SetDetail("progress", "50%")
+			<-chDone
+			return nil
+		})
+		go func() { _ = w.Run(ctx) }()
+
+		expected := []jobUpdate{
+			{JobID: "job1", Status: InProgress, Details: map[string]string{}, Timeout: 5},
+			{JobID: "job1", Status: InProgress, Details: map[string]string{"progress": "50%"}, Timeout: 5},
+		}
+		for _, e := range expected {
+			if u := j.waitUpdate(t); !reflect.DeepEqual(e, u) {
+				t.Errorf("Expected update: %+v, got: %+v", e, u)
+			}
+		}
+		close(chDone)
+		for {
+			u := j.waitUpdate(t)
+			if u.Status == InProgress {
+				continue
+			}
+			e := jobUpdate{JobID: "job1", Status: Succeeded, Details: map[string]string{"progress": "50%"}}
+			if !reflect.DeepEqual(e, u) {
+				t.Errorf("Expected update: %+v, got: %+v", e, u)
+			}
+			break
+		}
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		j := newStubJobs(&JobExecution{
+			JobID:         "job1",
+			Status:        Queued,
+			JobDocument:   map[string]interface{}{"operation": "reboot"},
+			VersionNumber: 1,
+		})
+		w := NewWorker(j)
+		chCanceled := make(chan struct{})
+		w.Handle("reboot", func(ctx context.Context, je *JobExecution, p *JobProgress) error {
+			<-ctx.Done()
+			close(chCanceled)
+			return ctx.Err()
+		})
+		go func() { _ = w.Run(ctx) }()
+
+		if u := j.waitUpdate(t); u.Status != InProgress {
+			t.Fatalf("Expected %s, got %s", InProgress, u.Status)
+		}
+		j.setStatus("job1", Canceled)
+
+		select {
+		case <-chCanceled:
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		}
+		time.Sleep(20 * time.Millisecond)
+		select {
+		case u := <-j.chUpdate:
+			t.Errorf("Unexpected update: %+v", u)
+		default:
+		}
+	})
+
+	t.Run("Concurrency", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		var jes []*JobExecution
+		for i := 0; i < 4; i++ {
+			jes = append(jes, &JobExecution{
+				JobID:         fmt.Sprintf("job%d", i),
+				Status:        Queued,
+				JobDocument:   map[string]interface{}{"operation": "op"},
+				QueuedAt:      int64(i),
+				VersionNumber: 1,
+			})
+		}
+		j := newStubJobs(jes...)
+		w := NewWorker(j, WithConcurrency(2))
+
+		var mu sync.Mutex
+		var cnt, maxCnt int
+		w.Handle("op", func(ctx context.Context, je *JobExecution, p *JobProgress) error {
+			mu.Lock()
+			cnt++
+			if cnt > maxCnt {
+				maxCnt = cnt
+			}
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			cnt--
+			mu.Unlock()
+			return nil
+		})
+		go func() { _ = w.Run(ctx) }()
+
+		succeeded := make(map[string]bool)
+		for len(succeeded) < len(jes) {
+			if u := j.waitUpdate(t); u.Status == Succeeded {
+				succeeded[u.JobID] = true
+			}
+		}
+		mu.Lock()
+		if maxCnt != 2 {
+			t.Errorf("Expected max concurrency 2, got %d", maxCnt)
+		}
+		mu.Unlock()
+	})
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+func (s *shadow) Acknowledge(ctx context.Context, delta NestedState, opt ...AcknowledgeOption) (*ThingDocument, error) {
+	opts := &AcknowledgeOptions{}
+	for _, o := range opt {
+		o(opts)
+	}
+	const failure = "acknowledging delta"
+
+	var reported interface{} = delta
+	hasReported := len(delta) > 0
+	if s.opts.IncrementalUpdate {
+		var err error
+		s.mu.Lock()
+		reported, hasReported, err = stateDiff(s.localDoc().State.Reported, delta)
+		s.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	hasDesired := !opts.KeepDesired && len(delta) > 0
+	if !hasReported && !hasDesired {
+		s.mu.Lock()
+		doc := s.doc.clone()
+		s.mu.Unlock()
+		return doc, nil
+	}
+
+	req := &thingDocumentRaw{}
+	if hasReported {
+		rawReported, err := json.Marshal(reported)
+		if err != nil {
+			return nil, ioterr.New(err, "marshaling state")
+		}
+		req.State.Reported = json.RawMessage(rawReported)
+	}
+	if hasDesired {
+		// Desired state is not diffed since the attributes to be cleared
+		// may be already removed from the local document by the other updates.
+		rawDesired, err := json.Marshal(nullLeaves(delta))
+		if err != nil {
+			return nil, ioterr.New(err, "marshaling state")
+		}
+		req.State.Desired = json.RawMessage(rawDesired)
+	}
+	if s.opts.DocumentLimits != nil {
+		if err := s.opts.DocumentLimits.check(req); err != nil {
+			return nil, ioterr.New(err, failure)
+		}
+	}
+	return s.update(ctx, req, failure)
+}
+
+// nullLeaves returns the state which has the same structure as the given
+// state and all leaf values are null.
+func nullLeaves(s map[string]interface{}) NestedState {
+	out := NestedState{}
+	for k, v := range s {
+		if m, ok := asStateMap(v); ok && len(m) > 0 {
+			out[k] = nullLeaves(m)
+			continue
+		}
+		out[k] = nil
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/at-wat/mqtt-go"
+	mockmqtt "github.com/at-wat/mqtt-go/mock"
+)
+
+func TestAcknowledge(t *testing.T) {
+	testCases := map[string]struct {
+		reported NestedState
+		delta    NestedState
+		opts     []AcknowledgeOption
+		expected map[string]interface{}
+	}{
+		"ClearDesired": {
+			delta: NestedState{
+				"led": true,
+				"net": NestedState{"ssid": "home"},
+			},
+			expected: map[string]interface{}{
+				"reported": map[string]interface{}{
+					"led": true,
+					"net": map[string]interface{}{"ssid": "home"},
+				},
+				"desired": map[string]interface{}{
+					"led": nil,
+					"net": map[string]interface{}{"ssid": nil},
+				},
+			},
+		},
+		"KeepDesired": {
+			delta: NestedState{"led": true},
+			opts:  []AcknowledgeOption{WithKeepDesired()},
+			expected: map[string]interface{}{
+				"reported": map[string]interface{}{"led": true},
+			},
+		},
+		"AlreadyReported": {
+			reported: NestedState{"led": true},
+			delta:    NestedState{"led": true},
+			expected: map[string]interface{}{
+				"desired": map[string]interface{}{"led": nil},
+			},
+		},
+		"AlreadyReportedKeepDesired": {
+			reported: NestedState{"led": true},
+			delta:    NestedState{"led": true},
+			opts:     []AcknowledgeOption{WithKeepDesired()},
+		},
+	}
+
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			var s Shadow
+			var cli *mockDevice
+			var payload []byte
+			cli = &mockDevice{
+				mockClient: &mockmqtt.Client{
+					PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+						payload = msg.Payload
+						req := &simpleRequest{}
+						if err := json.Unmarshal(msg.Payload, req); err != nil {
+							t.Error(err)
+							return err
+						}
+						res := &ThingDocument{Version: 2}
+						setClientToken(res, req.ClientToken)
+						bres, err := json.Marshal(res)
+						if err != nil {
+							t.Error(err)
+							return err
+						}
+						cli.Serve(&mqtt.Message{
+							Topic:   s.(*shadow).topic("update/accepted"),
+							Payload: bres,
+						})
+						return nil
+					},
+				},
+			}
+			var err error
+			s, err = New(ctx, cli)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cli.Handle(s)
+			if tt.reported != nil {
+				s.(*shadow).doc = &ThingDocument{
+					State: ThingState{
+						Reported: tt.reported,
+						Desired:  NestedState{},
+						Delta:    NestedState{},
+					},
+					Version: 1,
+				}
+			}
+
+			if _, err := s.Acknowledge(ctx, tt.delta, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+			if tt.expected == nil {
+				if payload != nil {
+					t.Errorf("Expected no update, got: %s", string(payload))
+				}
+				return
+			}
+			var req struct {
+				State map[string]interface{} `json:"state"`
+			}
+			if err := json.Unmarshal(payload, &req); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.expected, req.State) {
+				t.Errorf("Expected state:\n%+v\ngot:\n%+v", tt.expected, req.State)
+			}
+		})
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Input formats.
+const (
+	formatAuto   = "auto"
+	formatSchema = "schema"
+	formatSample = "sample"
+)
+
+var errUnsupportedSchema = errors.New("unsupported schema")
+
+type field struct {
+	name     string
+	jsonName string
+	typ      string
+	tag      string
+}
+
+type structDef struct {
+	name   string
+	path   string
+	fields []field
+}
+
+type generator struct {
+	nullable map[string]bool
+	structs  []*structDef
+	names    map[string]bool
+}
+
+// kind of the generated type.
+type kind int
+
+const (
+	kindScalar kind = iota
+	kindStruct
+	kindReference // slice, map and interface
+)
+
+func newGenerator(nullable []string) *generator {
+	g := &generator{
+		nullable: make(map[string]bool),
+		names:    make(map[string]bool),
+	}
+	for _, p := range nullable {
+		if p != "" {
+			g.nullable[p] = true
+		}
+	}
+	return g
+}
+
+// generate generates Go source code from the JSON Schema or the sample document.
+func generate(input []byte, inputFormat, pkg, typeName string, nullable []string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("parsing input: %w", err)
+	}
+	root, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("input must be a JSON object")
+	}
+	if inputFormat == formatAuto {
+		inputFormat = detectFormat(root)
+	}
+
+	g := newGenerator(nullable)
+	var err error
+	switch inputFormat {
+	case formatSchema:
+		_, _, _, err = g.schemaType(typeName, "", root)
+	case formatSample:
+		_, _, err = g.sampleType(typeName, "", sampleState(root))
+	default:
+		return nil, fmt.Errorf("unknown input format %q", inputFormat)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return g.source(pkg)
+}
+
+func detectFormat(root map[string]interface{}) string {
+	if _, ok := root["$schema"]; ok {
+		return formatSchema
+	}
+	if _, ok := root["properties"].(map[string]interface{}); ok && root["type"] == "object" {
+		return formatSchema
+	}
+	return formatSample
+}
+
+// sampleState returns union of the desired and reported state if the
+// sample is a thing document. Otherwise, the sample itself is returned.
+func sampleState(root map[string]interface{}) map[string]interface{} {
+	st, ok := root["state"].(map[string]interface{})
+	if !ok {
+		return root
+	}
+	out := make(map[string]interface{})
+	for _, section := range []string{"desired", "reported"} {
+		if s, ok := st[section].(map[string]interface{}); ok {
+			mergeSample(out, s)
+		}
+	}
+	return out
+}
+
+func mergeSample(dst, src map[string]interface{}) {
+	for k, v := range src {
+		d, dok := dst[k].(map[string]interface{})
+		s, sok := v.(map[string]interface{})
+		switch {
+		case dok && sok:
+			mergeSample(d, s)
+		case dst[k] == nil:
+			dst[k] = v
+		}
+	}
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// schemaType returns Go type of the schema.
+func (g *generator) schemaType(name, path string, s map[string]interface{}) (string, kind, bool, error) {
+	if _, ok := s["$ref"]; ok {
+		return "", 0, false, fmt.Errorf("%w: $ref at %q", errUnsupportedSchema, path)
+	}
+	nullable := g.nullable[path]
+	if n, ok := s["nullable"].(bool); ok && n {
+		nullable = true
+	}
+	var typ string
+	switch t := s["type"].(type) {
+	case string:
+		typ = t
+	case []interface{}:
+		for _, e := range t {
+			switch e {
+			case "null":
+				nullable = true
+			default:
+				if str, ok := e.(string); ok && typ == "" {
+					typ = str
+				}
+			}
+		}
+	}
+
+	switch typ {
+	case "object":
+		if props, ok := s["properties"].(map[string]interface{}); ok {
+			def := g.newStruct(name, path)
+			for _, k := range sortedKeys(props) {
+				ps, ok := props[k].(map[string]interface{})
+				if !ok {
+					return "", 0, false, fmt.Errorf("%w: property %q", errUnsupportedSchema, joinPath(path, k))
+				}
+				fieldName := exportedName(k)
+				t, kd, n, err := g.schemaType(name+fieldName, joinPath(path, k), ps)
+				if err != nil {
+					return "", 0, false, err
+				}
+				def.add(fieldName, k, t, kd, n)
+			}
+			return def.name, kindStruct, nullable, nil
+		}
+		if ap, ok := s["additionalProperties"].(map[string]interface{}); ok {
+			t, kd, n, err := g.schemaType(name+"Value", joinPath(path, "*"), ap)
+			if err != nil {
+				return "", 0, false, err
+			}
+			return "map[string]" + elemType(t, kd, n), kindReference, nullable, nil
+		}
+		return "map[string]interface{}", kindReference, nullable, nil
+	case "array":
+		items, ok := s["items"].(map[string]interface{})
+		if !ok {
+			return "[]interface{}", kindReference, nullable, nil
+		}
+		t, kd, n, err := g.schemaType(name+"Item", joinPath(path, "*"), items)
+		if err != nil {
+			return "", 0, false, err
+		}
+		return "[]" + elemType(t, kd, n), kindReference, nullable, nil
+	case "string":
+		return "string", kindScalar, nullable, nil
+	case "integer":
+		return "int", kindScalar, nullable, nil
+	case "number":
+		return "float64", kindScalar, nullable, nil
+	case "boolean":
+		return "bool", kindScalar, nullable, nil
+	}
+	return "interface{}", kindReference, nullable, nil
+}
+
+// sampleType returns Go type of the sample value.
+func (g *generator) sampleType(name, path string, v interface{}) (string, kind, error) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		def := g.newStruct(name, path)
+		for _, k := range sortedKeys(vv) {
+			fieldName := exportedName(k)
+			p := joinPath(path, k)
+			t, kd, err := g.sampleType(name+fieldName, p, vv[k])
+			if err != nil {
+				return "", 0, err
+			}
+			def.add(fieldName, k, t, kd, g.nullable[p] || vv[k] == nil)
+		}
+		return def.name, kindStruct, nil
+	case []interface{}:
+		if len(vv) == 0 {
+			return "[]interface{}", kindReference, nil
+		}
+		// Elements of the array are assumed to have the same type.
+		elem := vv[0]
+		if m, ok := elem.(map[string]interface{}); ok {
+			merged := make(map[string]interface{})
+			mergeSample(merged, m)
+			for _, e := range vv[1:] {
+				if m, ok := e.(map[string]interface{}); ok {
+					mergeSample(merged, m)
+				}
+			}
+			elem = merged
+		}
+		t, kd, err := g.sampleType(name+"Item", joinPath(path, "*"), elem)
+		if err != nil {
+			return "", 0, err
+		}
+		return "[]" + elemType(t, kd, g.nullable[joinPath(path, "*")]), kindReference, nil
+	case string:
+		return "string", kindScalar, nil
+	case json.Number:
+		if strings.ContainsAny(vv.String(), ".eE") {
+			return "float64", kindScalar, nil
+		}
+		return "int", kindScalar, nil
+	case bool:
+		return "bool", kindScalar, nil
+	}
+	return "interface{}", kindReference, nil
+}
+
+func elemType(t string, k kind, nullable bool) string {
+	if nullable && k != kindReference {
+		return "*" + t
+	}
+	return t
+}
+
+func (g *generator) newStruct(name, path string) *structDef {
+	n := name
+	for i := 2; g.names[n]; i++ {
+		n = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[n] = true
+	def := &structDef{name: n, path: path}
+	g.structs = append(g.structs, def)
+	return def
+}
+
+// add adds a field.
+// Nullable fields are pointers without omitempty to be marshaled to null.
+// Other fields have omitempty to update only the specified attributes.
+// Scalars and nested structs are pointers to be omitted only when unset,
+// so that zero values like false, 0 and "" can be updated.
+func (d *structDef) add(name, jsonName, typ string, k kind, nullable bool) {
+	for i := 2; d.hasField(name); i++ {
+		name = fmt.Sprintf("%s%d", exportedName(jsonName), i)
+	}
+	tag := jsonName + ",omitempty"
+	switch {
+	case nullable:
+		tag = jsonName
+		if k != kindReference {
+			typ = "*" + typ
+		}
+	case k == kindStruct, k == kindScalar:
+		typ = "*" + typ
+	}
+	d.fields = append(d.fields, field{
+		name:     name,
+		jsonName: jsonName,
+		typ:      typ,
+		tag:      tag,
+	})
+}
+
+func (d *structDef) hasField(name string) bool {
+	for _, f := range d.fields {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) source(pkg string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by shadowgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n", pkg)
+	for _, def := range g.structs {
+		if def.path == "" {
+			fmt.Fprintf(&b, "\n// %s is the thing shadow state.\n", def.name)
+		} else {
+			fmt.Fprintf(&b, "\n// %s is the %q attribute of the thing shadow state.\n", def.name, def.path)
+		}
+		fmt.Fprintf(&b, "type %s struct {\n", def.name)
+		for _, f := range def.fields {
+			fmt.Fprintf(&b, "\t%s %s `json:%q`\n", f.name, f.typ, f.tag)
+		}
+		fmt.Fprintf(&b, "}\n")
+	}
+	out, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return out, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var initialisms = map[string]string{
+	"api":  "API",
+	"http": "HTTP",
+	"id":   "ID",
+	"ip":   "IP",
+	"json": "JSON",
+	"url":  "URL",
+	"uuid": "UUID",
+}
+
+// exportedName converts JSON attribute name to exported Go identifier.
+// e.g. "battery_level" and "battery-level" are converted to "BatteryLevel".
+func exportedName(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, w := range words {
+		if i, ok := initialisms[strings.ToLower(w)]; ok {
+			b.WriteString(i)
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
@@ -0,0 +1,163 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		format   string
+		nullable []string
+		expected string
+	}{
+		"Schema": {
+			input: `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "properties": {
+    "robot_id": {"type": "string"},
+    "speed": {"type": "number"},
+    "count": {"type": "integer"},
+    "error": {"type": ["string", "null"]},
+    "lights": {
+      "type": "object",
+      "properties": {
+        "front": {"type": "boolean"}
+      }
+    },
+    "tags": {"type": "array", "items": {"type": "string"}},
+    "params": {"type": "object", "additionalProperties": {"type": "number"}}
+  }
+}`,
+			format: formatAuto,
+			expected: "// Code generated by shadowgen. DO NOT EDIT.\n" +
+				"\n" +
+				"package test\n" +
+				"\n" +
+				"// State is the thing shadow state.\n" +
+				"type State struct {\n" +
+				"\tCount   *int               `json:\"count,omitempty\"`\n" +
+				"\tError   *string            `json:\"error\"`\n" +
+				"\tLights  *StateLights       `json:\"lights,omitempty\"`\n" +
+				"\tParams  map[string]float64 `json:\"params,omitempty\"`\n" +
+				"\tRobotID *string            `json:\"robot_id,omitempty\"`\n" +
+				"\tSpeed   *float64           `json:\"speed,omitempty\"`\n" +
+				"\tTags    []string           `json:\"tags,omitempty\"`\n" +
+				"}\n" +
+				"\n" +
+				"// StateLights is the \"lights\" attribute of the thing shadow state.\n" +
+				"type StateLights struct {\n" +
+				"\tFront *bool `json:\"front,omitempty\"`\n" +
+				"}\n",
+		},
+		"SampleDocument": {
+			input: `{
+  "state": {
+    "desired": {"mode": "auto", "lights": {"front": true}},
+    "reported": {"mode": "auto", "lights": {"rear": false}, "battery-level": 80, "ratio": 0.5}
+  },
+  "version": 3
+}`,
+			format:   formatAuto,
+			nullable: []string{"lights.rear", "mode"},
+			expected: "// Code generated by shadowgen. DO NOT EDIT.\n" +
+				"\n" +
+				"package test\n" +
+				"\n" +
+				"// State is the thing shadow state.\n" +
+				"type State struct {\n" +
+				"\tBatteryLevel *int         `json:\"battery-level,omitempty\"`\n" +
+				"\tLights       *StateLights `json:\"lights,omitempty\"`\n" +
+				"\tMode         *string      `json:\"mode\"`\n" +
+				"\tRatio        *float64     `json:\"ratio,omitempty\"`\n" +
+				"}\n" +
+				"\n" +
+				"// StateLights is the \"lights\" attribute of the thing shadow state.\n" +
+				"type StateLights struct {\n" +
+				"\tFront *bool `json:\"front,omitempty\"`\n" +
+				"\tRear  *bool `json:\"rear\"`\n" +
+				"}\n",
+		},
+		"SampleState": {
+			input:  `{"points": [{"x": 1}, {"y": 2.5}], "note": null}`,
+			format: formatSample,
+			expected: "// Code generated by shadowgen. DO NOT EDIT.\n" +
+				"\n" +
+				"package test\n" +
+				"\n" +
+				"// State is the thing shadow state.\n" +
+				"type State struct {\n" +
+				"\tNote   interface{}       `json:\"note\"`\n" +
+				"\tPoints []StatePointsItem `json:\"points,omitempty\"`\n" +
+				"}\n" +
+				"\n" +
+				"// StatePointsItem is the \"points.*\" attribute of the thing shadow state.\n" +
+				"type StatePointsItem struct {\n" +
+				"\tX *int     `json:\"x,omitempty\"`\n" +
+				"\tY *float64 `json:\"y,omitempty\"`\n" +
+				"}\n",
+		},
+	}
+
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			out, err := generate([]byte(tt.input), tt.format, "test", "State", tt.nullable)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, string(out))
+			}
+		})
+	}
+
+	t.Run("UnsupportedRef", func(t *testing.T) {
+		_, err := generate(
+			[]byte(`{"type": "object", "properties": {"a": {"$ref": "#/definitions/a"}}}`),
+			formatSchema, "test", "State", nil,
+		)
+		if !errors.Is(err, errUnsupportedSchema) {
+			t.Errorf("Expected error: %v, got: %v", errUnsupportedSchema, err)
+		}
+	})
+	t.Run("NonObject", func(t *testing.T) {
+		if _, err := generate([]byte(`[1]`), formatAuto, "test", "State", nil); err == nil {
+			t.Error("Expected error")
+		}
+	})
+}
+
+func TestExportedName(t *testing.T) {
+	testCases := map[string]string{
+		"speed":         "Speed",
+		"battery_level": "BatteryLevel",
+		"battery-level": "BatteryLevel",
+		"robotId":       "RobotId",
+		"robot_id":      "RobotID",
+		"url":           "URL",
+		"1st":           "X1st",
+		"":              "X",
+	}
+	for in, expected := range testCases {
+		if out := exportedName(in); out != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, in, out)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command shadowgen generates Go structs of the thing shadow state
+// from a JSON Schema or a sample shadow document.
+//
+// Generated structs can be passed to shadow.Shadow.Report and
+// shadow.Shadow.Desire. Fields have omitempty tags to update only the
+// specified attributes. Scalar fields are pointers to update zero values.
+// Nullable fields are pointers without omitempty to delete the attributes
+// by nil.
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+var (
+	input       = flag.String("input", "", "Input JSON Schema or sample document file (default: stdin)")
+	output      = flag.String("out", "", "Output Go source file (default: stdout)")
+	pkg         = flag.String("package", "main", "Package name of the generated code")
+	typeName    = flag.String("type", "State", "Type name of the root struct")
+	inputFormat = flag.String("format", formatAuto, "Input format: auto, schema or sample")
+	nullable    = flag.String("nullable", "", "Comma separated dot delimited attribute paths to be nullable (e.g. a.b,c)")
+)
+
+func main() {
+	flag.Parse()
+
+	var in []byte
+	var err error
+	if *input == "" {
+		in, err = io.ReadAll(os.Stdin)
+	} else {
+		in, err = os.ReadFile(*input)
+	}
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	src, err := generate(in, *inputFormat, *pkg, *typeName, strings.Split(*nullable, ","))
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	if *output == "" {
+		if _, err := os.Stdout.Write(src); err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		return
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatalf("error: %v", err)
+	}
+}
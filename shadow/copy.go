@@ -21,6 +21,8 @@ func cloneState(s map[string]interface{}) map[string]interface{} {
 		switch vv := v.(type) {
 		case map[string]interface{}:
 			c[k] = cloneState(vv)
+		case NestedState:
+			c[k] = NestedState(cloneState(vv))
 		case []interface{}:
 			c[k] = cloneStateSlice(vv)
 		default:
@@ -37,6 +39,8 @@ func cloneStateSlice(s []interface{}) []interface{} {
 		switch vv := v.(type) {
 		case map[string]interface{}:
 			c = append(c, cloneState(vv))
+		case NestedState:
+			c = append(c, NestedState(cloneState(vv)))
 		case []interface{}:
 			c = append(c, cloneStateSlice(vv))
 		default:
@@ -57,6 +57,20 @@ func TestClone(t *testing.T) {
 				doc.State.Desired["key"].(map[string]interface{})["childKey"] = "value2"
 			},
 		},
+		"NestedStateInMap": {
+			doc: &ThingDocument{
+				State: ThingState{
+					Desired: NestedState{
+						"key": NestedState{
+							"childKey": "value",
+						},
+					},
+				},
+			},
+			mutate: func(doc *ThingDocument) {
+				doc.State.Desired["key"].(NestedState)["childKey"] = "value2"
+			},
+		},
 		"SliceInMap": {
 			doc: &ThingDocument{
 				State: ThingState{
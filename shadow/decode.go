@@ -0,0 +1,194 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// decodeState converts thing state (NestedState or its element) into the value
+// pointed by out.
+// Struct fields are matched by the json tags in the same way as stateDiff.
+func decodeState(in interface{}, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return ioterr.New(ErrIncompatibleMapping, "decoding state into non-pointer")
+	}
+	return decodeStateImpl(in, v.Elem(), "")
+}
+
+func decodeStateImpl(in interface{}, out reflect.Value, path string) error {
+	if reflect.PointerTo(out.Type()).Implements(jsonUnmarshalerType) && out.CanAddr() {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return ioterr.Newf(ErrIncompatibleMapping, "marshaling %s: %v", pathName(path), err)
+		}
+		if err := out.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(b); err != nil {
+			return ioterr.Newf(ErrIncompatibleMapping, "unmarshaling %s: %v", pathName(path), err)
+		}
+		return nil
+	}
+	if in == nil {
+		out.Set(reflect.Zero(out.Type()))
+		return nil
+	}
+
+	switch out.Kind() {
+	case reflect.Ptr:
+		v := reflect.New(out.Type().Elem())
+		if err := decodeStateImpl(in, v.Elem(), path); err != nil {
+			return err
+		}
+		out.Set(v)
+		return nil
+	case reflect.Interface:
+		v := reflect.ValueOf(in)
+		if !v.Type().AssignableTo(out.Type()) {
+			return incompatible(path, in, out)
+		}
+		out.Set(v)
+		return nil
+	case reflect.Struct:
+		m, ok := asStateMap(in)
+		if !ok {
+			return incompatible(path, in, out)
+		}
+		matcher, err := newAttributeMatcher(out)
+		if err != nil {
+			return ioterr.Newf(ErrIncompatibleMapping, "decoding %s", pathName(path))
+		}
+		for k, val := range m {
+			info, ok := matcher.byName[k]
+			if !ok || !info.val.CanSet() {
+				// Unknown attributes are ignored as encoding/json does.
+				continue
+			}
+			if err := decodeStateImpl(val, info.val, joinPath(path, k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		m, ok := asStateMap(in)
+		if !ok {
+			return incompatible(path, in, out)
+		}
+		if out.Type().Key().Kind() != reflect.String {
+			return ioterr.Newf(ErrUnsupportedMapKeyType, "decoding %s", pathName(path))
+		}
+		mv := reflect.MakeMapWithSize(out.Type(), len(m))
+		for k, val := range m {
+			ev := reflect.New(out.Type().Elem()).Elem()
+			if err := decodeStateImpl(val, ev, joinPath(path, k)); err != nil {
+				return err
+			}
+			mv.SetMapIndex(reflect.ValueOf(k).Convert(out.Type().Key()), ev)
+		}
+		out.Set(mv)
+		return nil
+	case reflect.Slice, reflect.Array:
+		s, ok := in.([]interface{})
+		if !ok {
+			return incompatible(path, in, out)
+		}
+		if out.Kind() == reflect.Slice {
+			out.Set(reflect.MakeSlice(out.Type(), len(s), len(s)))
+		} else if out.Len() < len(s) {
+			return incompatible(path, in, out)
+		}
+		for i, val := range s {
+			if err := decodeStateImpl(val, out.Index(i), joinPath(path, indexName(i))); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.String:
+		s, ok := in.(string)
+		if !ok {
+			return incompatible(path, in, out)
+		}
+		out.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, ok := in.(bool)
+		if !ok {
+			return incompatible(path, in, out)
+		}
+		out.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f, ok := asFloat(in)
+		if !ok || f != math.Trunc(f) || out.OverflowInt(int64(f)) {
+			return incompatible(path, in, out)
+		}
+		out.SetInt(int64(f))
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f, ok := asFloat(in)
+		if !ok || f < 0 || f != math.Trunc(f) || out.OverflowUint(uint64(f)) {
+			return incompatible(path, in, out)
+		}
+		out.SetUint(uint64(f))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, ok := asFloat(in)
+		if !ok || out.OverflowFloat(f) {
+			return incompatible(path, in, out)
+		}
+		out.SetFloat(f)
+		return nil
+	}
+	return incompatible(path, in, out)
+}
+
+func asStateMap(in interface{}) (map[string]interface{}, bool) {
+	switch m := in.(type) {
+	case NestedState:
+		return m, true
+	case map[string]interface{}:
+		return m, true
+	}
+	return nil, false
+}
+
+func asFloat(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	rv := reflect.ValueOf(in)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func incompatible(path string, in interface{}, out reflect.Value) error {
+	return ioterr.Newf(ErrIncompatibleMapping, "decoding %T into %s of %s", in, pathName(path), out.Type())
+}
@@ -0,0 +1,133 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testDecodeSub struct {
+	Values []int `json:"values"`
+}
+
+type testDecodeStruct struct {
+	Value    int               `json:"value"`
+	Ratio    float32           `json:"ratio,omitempty"`
+	Name     string            `json:"name"`
+	Enabled  *bool             `json:"enabled"`
+	Sub      testDecodeSub     `json:"sub"`
+	SubPtr   *testDecodeSub    `json:"subPtr"`
+	Labels   map[string]string `json:"labels"`
+	Any      interface{}       `json:"any"`
+	Time     time.Time         `json:"time"`
+	NoTag    uint8
+	Ignored  int `json:"-"`
+	internal int
+}
+
+func TestDecodeState(t *testing.T) {
+	enabled := true
+	testCases := map[string]struct {
+		input    interface{}
+		expected testDecodeStruct
+		err      error
+	}{
+		"Full": {
+			input: NestedState{
+				"value":    float64(10),
+				"ratio":    0.5,
+				"name":     "test",
+				"enabled":  true,
+				"sub":      NestedState{"values": []interface{}{float64(1), float64(2)}},
+				"subPtr":   NestedState{"values": []interface{}{float64(3)}},
+				"labels":   NestedState{"a": "b"},
+				"any":      NestedState{"x": "y"},
+				"time":     "2026-01-02T03:04:05Z",
+				"NoTag":    float64(255),
+				"Ignored":  float64(1),
+				"internal": float64(1),
+				"unknown":  "ignored",
+			},
+			expected: testDecodeStruct{
+				Value:   10,
+				Ratio:   0.5,
+				Name:    "test",
+				Enabled: &enabled,
+				Sub:     testDecodeSub{Values: []int{1, 2}},
+				SubPtr:  &testDecodeSub{Values: []int{3}},
+				Labels:  map[string]string{"a": "b"},
+				Any:     NestedState{"x": "y"},
+				Time:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+				NoTag:   255,
+			},
+		},
+		"Partial": {
+			input: map[string]interface{}{
+				"name": "partial",
+			},
+			expected: testDecodeStruct{Name: "partial"},
+		},
+		"Nil": {
+			input:    nil,
+			expected: testDecodeStruct{},
+		},
+		"TypeMismatch": {
+			input: NestedState{"name": float64(1)},
+			err:   ErrIncompatibleMapping,
+		},
+		"NonIntegral": {
+			input: NestedState{"value": 1.5},
+			err:   ErrIncompatibleMapping,
+		},
+		"Overflow": {
+			input: NestedState{"NoTag": float64(256)},
+			err:   ErrIncompatibleMapping,
+		},
+		"NestedMismatch": {
+			input: NestedState{"sub": NestedState{"values": []interface{}{"a"}}},
+			err:   ErrIncompatibleMapping,
+		},
+		"NonObject": {
+			input: "string",
+			err:   ErrIncompatibleMapping,
+		},
+	}
+
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			var out testDecodeStruct
+			err := decodeState(tt.input, &out)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error: %v, got: %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(tt.expected, out) {
+				t.Errorf("Expected:\n%+v\ngot:\n%+v", tt.expected, out)
+			}
+		})
+	}
+
+	t.Run("NonPointer", func(t *testing.T) {
+		if err := decodeState(NestedState{}, testDecodeStruct{}); !errors.Is(err, ErrIncompatibleMapping) {
+			t.Errorf("Expected error: %v, got: %v", ErrIncompatibleMapping, err)
+		}
+	})
+}
@@ -28,8 +28,8 @@ func stateDiff(base, in interface{}) (interface{}, bool, error) {
 		return nil, false, err
 	}
 	if !hasChild {
-		baseVal := reflect.ValueOf(base)
-		inVal := reflect.ValueOf(in)
+		baseVal := derefValue(reflect.ValueOf(base))
+		inVal := derefValue(reflect.ValueOf(in))
 		if !baseVal.IsValid() {
 			if !inVal.IsValid() {
 				// Both nil
@@ -69,7 +69,7 @@ func stateDiff(base, in interface{}) (interface{}, bool, error) {
 			return nil, false, nil
 		default:
 			// Compare primitive value
-			if reflect.DeepEqual(base, in) {
+			if leafEqual(baseVal, inVal) {
 				return nil, false, nil
 			}
 		}
@@ -125,6 +125,10 @@ func stateDiff(base, in interface{}) (interface{}, bool, error) {
 		if bInfo.omitempty && bInfo.val.IsZero() {
 			continue
 		}
+		if bInfo.field && !derefValue(reflect.ValueOf(b)).IsValid() {
+			// Unset nullable struct field doesn't delete non-existent attribute.
+			continue
+		}
 		out[k] = b
 	}
 	if len(out) == 0 {
@@ -166,11 +170,38 @@ func attributeKeys(a interface{}) ([]string, bool, error) {
 		}
 		return out, true, nil
 	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, false, nil
+		}
 		return attributeKeys(v.Elem().Interface())
 	}
 	return nil, false, nil
 }
 
+// derefValue dereferences pointers and interfaces.
+// Invalid value is returned if nil.
+func derefValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// leafEqual compares primitive values.
+// Numbers are compared by the value regardless of the types since
+// the state received from AWS IoT has float64 numbers.
+func leafEqual(a, b reflect.Value) bool {
+	fa, okA := asFloat(a.Interface())
+	fb, okB := asFloat(b.Interface())
+	if okA && okB {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
 type attributeMatcher struct {
 	byName map[string]attributeInfo
 }
@@ -178,6 +209,7 @@ type attributeMatcher struct {
 type attributeInfo struct {
 	val       reflect.Value
 	omitempty bool
+	field     bool
 }
 
 func newAttributeMatcher(val reflect.Value) (*attributeMatcher, error) {
@@ -203,6 +235,7 @@ func newAttributeMatcher(val reflect.Value) (*attributeMatcher, error) {
 			a.byName[name] = attributeInfo{
 				val:       val.Field(i),
 				omitempty: omitempty,
+				field:     true,
 			}
 		}
 		return a, nil
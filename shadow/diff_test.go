@@ -41,6 +41,13 @@ type testStructWithOmitempty struct {
 type testSubStruct struct {
 	S1, S2 int
 }
+type testStructNullable struct {
+	A *int            `json:"a"`
+	B *int            `json:"b,omitempty"`
+	S *testSubStruct  `json:"s"`
+	T *testSubStruct  `json:"t,omitempty"`
+	M map[string]bool `json:"m,omitempty"`
+}
 type testStructNested struct {
 	A, B, C int
 	S       testSubStruct
@@ -206,6 +213,39 @@ func TestStateDiff(t *testing.T) {
 			diff:    map[string]interface{}{"A": []interface{}{1, 2, 3, 4, 5}},
 			hasDiff: true,
 		},
+		"Float2Int_Equal": {
+			base:    NestedState{"A": 1.0, "B": 2.0, "C": 0.0, "S": "test"},
+			input:   testStruct{A: 1, B: 2, S: "test"},
+			hasDiff: false,
+		},
+		"Float2Int": {
+			base:    NestedState{"A": 1.0, "B": 2.0, "C": 0.0, "S": "test"},
+			input:   testStruct{A: 1, B: 3, S: "test"},
+			diff:    map[string]interface{}{"B": 3},
+			hasDiff: true,
+		},
+		"Map2Map_RemoveNonExistent": {
+			base:    NestedState{"A": 1.0},
+			input:   map[string]interface{}{"A": 1, "S": nil},
+			diff:    map[string]interface{}{"S": nil},
+			hasDiff: true,
+		},
+		"Nullable_Equal": {
+			base:    NestedState{"a": 1.0, "s": NestedState{"S1": 1.0, "S2": 2.0}},
+			input:   testStructNullable{A: intPtr(1), S: &testSubStruct{S1: 1, S2: 2}},
+			hasDiff: false,
+		},
+		"Nullable_Delete": {
+			base:    NestedState{"a": 1.0, "b": 2.0, "s": NestedState{"S1": 1.0, "S2": 2.0}},
+			input:   testStructNullable{},
+			diff:    map[string]interface{}{"a": (*int)(nil), "s": (*testSubStruct)(nil)},
+			hasDiff: true,
+		},
+		"Nullable_DeleteNonExistent": {
+			base:    NestedState{},
+			input:   testStructNullable{},
+			hasDiff: false,
+		},
 		"InterfaceArrayEqual": {
 			base: map[string]interface{}{"A": []interface{}{1, 2, 3, 4, 5}},
 			input: struct{ A [5]int }{
@@ -234,6 +274,71 @@ func TestStateDiff(t *testing.T) {
 	}
 }
 
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestDerefValue(t *testing.T) {
+	var iface interface{} = intPtr(1)
+	testCases := map[string]struct {
+		input    interface{}
+		expected interface{}
+	}{
+		"Nil":          {input: nil},
+		"NilPtr":       {input: (*int)(nil)},
+		"Value":        {input: 1, expected: 1},
+		"Ptr":          {input: intPtr(1), expected: 1},
+		"PtrToPtr":     {input: &iface, expected: 1},
+		"PtrToNilPtr":  {input: func() **int { var p *int; return &p }()},
+		"StructPtr":    {input: &testSubStruct{S1: 1}, expected: testSubStruct{S1: 1}},
+		"NonPtrStruct": {input: testSubStruct{S2: 2}, expected: testSubStruct{S2: 2}},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			v := derefValue(reflect.ValueOf(tt.input))
+			if tt.expected == nil {
+				if v.IsValid() {
+					t.Errorf("Expected invalid value, got: %v", v)
+				}
+				return
+			}
+			if !v.IsValid() {
+				t.Fatal("Expected valid value")
+			}
+			if !reflect.DeepEqual(tt.expected, v.Interface()) {
+				t.Errorf("Expected: %v, got: %v", tt.expected, v.Interface())
+			}
+		})
+	}
+}
+
+func TestLeafEqual(t *testing.T) {
+	testCases := map[string]struct {
+		a, b  interface{}
+		equal bool
+	}{
+		"IntFloat":       {a: 1, b: 1.0, equal: true},
+		"IntFloatDiffer": {a: 1, b: 1.5},
+		"Uint8Int64":     {a: uint8(3), b: int64(3), equal: true},
+		"Float32Float64": {a: float32(0.5), b: 0.5, equal: true},
+		"String":         {a: "a", b: "a", equal: true},
+		"StringDiffer":   {a: "a", b: "b"},
+		"StringNumber":   {a: "1", b: 1},
+		"Bool":           {a: true, b: true, equal: true},
+		"BoolNumber":     {a: true, b: 1},
+		"NamedString":    {a: ChangeType("a"), b: ChangeType("a"), equal: true},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			if eq := leafEqual(reflect.ValueOf(tt.a), reflect.ValueOf(tt.b)); eq != tt.equal {
+				t.Errorf("Expected %v, got %v", tt.equal, eq)
+			}
+		})
+	}
+}
+
 func TestAttributeKeys(t *testing.T) {
 	testCases := map[string]struct {
 		input    interface{}
@@ -244,6 +349,9 @@ func TestAttributeKeys(t *testing.T) {
 		"Slice": {
 			input: []int{1, 2, 3},
 		},
+		"NilPtr": {
+			input: (*testStruct)(nil),
+		},
 		"Map": {
 			input:    map[string]int{"a": 1, "b": 2},
 			keys:     []string{"a", "b"},
@@ -0,0 +1,150 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"reflect"
+	"sort"
+)
+
+// ChangeType represents a type of the attribute change.
+type ChangeType string
+
+// Attribute change types.
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// Document sections.
+const (
+	SectionDesired  = "desired"
+	SectionReported = "reported"
+)
+
+// AttributeChange represents a change of the state attribute.
+type AttributeChange struct {
+	// Section is SectionDesired or SectionReported.
+	Section string
+	// Path is a dot separated path to the attribute.
+	// e.g. "a.b" points {"a": {"b": value}}.
+	// Arrays are treated as a single attribute.
+	Path string
+	Type ChangeType
+	// Previous is the value before the change. nil if added.
+	Previous interface{}
+	// Current is the value after the change. nil if removed.
+	Current interface{}
+	// Timestamp is the update time of the attribute.
+	// Document timestamp is used if the metadata of the attribute is not available.
+	Timestamp int
+}
+
+// DiffDocuments returns list of the attribute changes from prev to cur.
+// Changes of nested objects are expanded into the changes of the leaf attributes.
+// Returned changes are sorted by the section and the path.
+// nil document is treated as an empty document.
+func DiffDocuments(prev, cur *ThingDocument) []AttributeChange {
+	if prev == nil {
+		prev = &ThingDocument{}
+	}
+	if cur == nil {
+		cur = &ThingDocument{}
+	}
+	d := &documentDiffer{timestamp: cur.Timestamp}
+
+	d.section = SectionDesired
+	d.metadata = cur.Metadata.Desired
+	d.diff("", prev.State.Desired, cur.State.Desired)
+
+	d.section = SectionReported
+	d.metadata = cur.Metadata.Reported
+	d.diff("", prev.State.Reported, cur.State.Reported)
+
+	sort.Slice(d.changes, func(i, j int) bool {
+		if d.changes[i].Section != d.changes[j].Section {
+			return d.changes[i].Section < d.changes[j].Section
+		}
+		return d.changes[i].Path < d.changes[j].Path
+	})
+	return d.changes
+}
+
+type documentDiffer struct {
+	section   string
+	metadata  NestedMetadata
+	timestamp int
+	changes   []AttributeChange
+}
+
+func (d *documentDiffer) diff(path string, prev, cur map[string]interface{}) {
+	for k, p := range prev {
+		c, ok := cur[k]
+		if !ok {
+			d.removed(joinPath(path, k), p)
+			continue
+		}
+		pm, pok := asStateMap(p)
+		cm, cok := asStateMap(c)
+		switch {
+		case pok && cok:
+			d.diff(joinPath(path, k), pm, cm)
+		case reflect.DeepEqual(p, c):
+		default:
+			d.add(joinPath(path, k), ChangeModified, p, c)
+		}
+	}
+	for k, c := range cur {
+		if _, ok := prev[k]; !ok {
+			d.added(joinPath(path, k), c)
+		}
+	}
+}
+
+func (d *documentDiffer) added(path string, c interface{}) {
+	if m, ok := asStateMap(c); ok && len(m) > 0 {
+		for k, v := range m {
+			d.added(joinPath(path, k), v)
+		}
+		return
+	}
+	d.add(path, ChangeAdded, nil, c)
+}
+
+func (d *documentDiffer) removed(path string, p interface{}) {
+	if m, ok := asStateMap(p); ok && len(m) > 0 {
+		for k, v := range m {
+			d.removed(joinPath(path, k), v)
+		}
+		return
+	}
+	d.add(path, ChangeRemoved, p, nil)
+}
+
+func (d *documentDiffer) add(path string, t ChangeType, p, c interface{}) {
+	ts, ok := metadataTimestamp(d.metadata, splitPath(path))
+	if !ok {
+		ts = d.timestamp
+	}
+	d.changes = append(d.changes, AttributeChange{
+		Section:   d.section,
+		Path:      path,
+		Type:      t,
+		Previous:  p,
+		Current:   c,
+		Timestamp: ts,
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffDocuments(t *testing.T) {
+	testCases := map[string]struct {
+		prev, cur *ThingDocument
+		expected  []AttributeChange
+	}{
+		"NoChange": {
+			prev: &ThingDocument{
+				State: ThingState{Reported: NestedState{"a": 1.0, "b": []interface{}{1.0}}},
+			},
+			cur: &ThingDocument{
+				State: ThingState{Reported: NestedState{"a": 1.0, "b": []interface{}{1.0}}},
+			},
+			expected: nil,
+		},
+		"Changes": {
+			prev: &ThingDocument{
+				State: ThingState{
+					Desired: NestedState{"a": 1.0},
+					Reported: NestedState{
+						"a":      1.0,
+						"nested": NestedState{"b": "x", "c": "y"},
+						"list":   []interface{}{1.0, 2.0},
+						"obj":    NestedState{"d": true},
+					},
+				},
+				Timestamp: 10,
+			},
+			cur: &ThingDocument{
+				State: ThingState{
+					Reported: NestedState{
+						"a":      2.0,
+						"nested": NestedState{"b": "x", "e": "z"},
+						"list":   []interface{}{1.0},
+						"obj":    "flat",
+						"new":    NestedState{"f": 1.0},
+					},
+				},
+				Metadata: ThingStateMetadata{
+					Reported: NestedMetadata{
+						"a":      Metadata{Timestamp: 15},
+						"nested": NestedMetadata{"e": Metadata{Timestamp: 16}},
+					},
+				},
+				Timestamp: 20,
+			},
+			expected: []AttributeChange{
+				{Section: SectionDesired, Path: "a", Type: ChangeRemoved, Previous: 1.0, Timestamp: 20},
+				{Section: SectionReported, Path: "a", Type: ChangeModified, Previous: 1.0, Current: 2.0, Timestamp: 15},
+				{Section: SectionReported, Path: "list", Type: ChangeModified, Previous: []interface{}{1.0, 2.0}, Current: []interface{}{1.0}, Timestamp: 20},
+				{Section: SectionReported, Path: "nested.c", Type: ChangeRemoved, Previous: "y", Timestamp: 20},
+				{Section: SectionReported, Path: "nested.e", Type: ChangeAdded, Current: "z", Timestamp: 16},
+				{Section: SectionReported, Path: "new.f", Type: ChangeAdded, Current: 1.0, Timestamp: 20},
+				{Section: SectionReported, Path: "obj", Type: ChangeModified, Previous: NestedState{"d": true}, Current: "flat", Timestamp: 20},
+			},
+		},
+		"Created": {
+			cur: &ThingDocument{
+				State:     ThingState{Desired: NestedState{"a": 1.0}},
+				Timestamp: 1,
+			},
+			expected: []AttributeChange{
+				{Section: SectionDesired, Path: "a", Type: ChangeAdded, Current: 1.0, Timestamp: 1},
+			},
+		},
+	}
+
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			changes := DiffDocuments(tt.prev, tt.cur)
+			if !reflect.DeepEqual(tt.expected, changes) {
+				t.Errorf("Expected changes:\n%+v\ngot:\n%+v", tt.expected, changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+// ErrDocumentTooLarge is returned if the update request exceeds DocumentLimits.MaxSize.
+var ErrDocumentTooLarge = errors.New("document too large")
+
+// ErrTooDeep is returned if the state exceeds DocumentLimits.MaxDepth.
+var ErrTooDeep = errors.New("document too deep")
+
+// ErrTooManyAttributes is returned if the state exceeds DocumentLimits.MaxAttributes.
+var ErrTooManyAttributes = errors.New("too many attributes")
+
+// DocumentLimits represents limits of the thing document checked before sending updates.
+// Zero means unlimited.
+type DocumentLimits struct {
+	// MaxSize is maximum size of the update request in bytes.
+	MaxSize int
+	// MaxDepth is maximum nesting level of the desired or reported state.
+	// e.g. {"a": {"b": 1}} has two levels.
+	MaxDepth int
+	// MaxAttributes is maximum number of the leaf attributes
+	// in the desired or reported state.
+	MaxAttributes int
+}
+
+// DefaultDocumentLimits is the default limits of AWS IoT Device Shadow service.
+var DefaultDocumentLimits = DocumentLimits{
+	MaxSize:  8 * 1024,
+	MaxDepth: 6,
+}
+
+// placeholderClientToken has the maximum length of the client token
+// generated by shadow.token().
+var placeholderClientToken = strings.Repeat("f", 8)
+
+// check returns error if the request exceeds the limits.
+func (l *DocumentLimits) check(req *thingDocumentRaw) error {
+	r := *req
+	if r.ClientToken == "" {
+		r.ClientToken = placeholderClientToken
+	}
+	data, err := json.Marshal(&r)
+	if err != nil {
+		return ioterr.New(err, "marshaling request")
+	}
+	if l.MaxSize > 0 && len(data) > l.MaxSize {
+		return ioterr.Newf(ErrDocumentTooLarge, "%d bytes exceeds %d bytes", len(data), l.MaxSize)
+	}
+	for _, raw := range []json.RawMessage{r.State.Desired, r.State.Reported} {
+		if len(raw) == 0 {
+			continue
+		}
+		var st interface{}
+		if err := json.Unmarshal(raw, &st); err != nil {
+			return ioterr.New(err, "unmarshaling state")
+		}
+		depth, n := stateShape(st)
+		if l.MaxDepth > 0 && depth > l.MaxDepth {
+			return ioterr.Newf(ErrTooDeep, "%d levels exceeds %d levels", depth, l.MaxDepth)
+		}
+		if l.MaxAttributes > 0 && n > l.MaxAttributes {
+			return ioterr.Newf(ErrTooManyAttributes, "%d attributes exceeds %d attributes", n, l.MaxAttributes)
+		}
+	}
+	return nil
+}
+
+// stateShape returns the nesting level of the objects and the number of the leaf attributes.
+func stateShape(v interface{}) (int, int) {
+	m, ok := asStateMap(v)
+	if !ok {
+		return 0, 1
+	}
+	var depth, n int
+	for _, e := range m {
+		d, c := stateShape(e)
+		if d > depth {
+			depth = d
+		}
+		n += c
+	}
+	return depth + 1, n
+}
+
+// splitState splits the state into the parts satisfying fits.
+// Top level attributes are greedily packed in the key order and
+// the objects which don't fit are recursively split.
+func splitState(st map[string]interface{}, fits func(NestedState) bool) ([]NestedState, error) {
+	keys := make([]string, 0, len(st))
+	for k := range st {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []NestedState
+	cur := NestedState{}
+	for _, k := range keys {
+		cur[k] = st[k]
+		if fits(cur) {
+			continue
+		}
+		delete(cur, k)
+		if len(cur) > 0 {
+			parts = append(parts, cur)
+		}
+		cur = NestedState{k: st[k]}
+		if fits(cur) {
+			continue
+		}
+		child, ok := asStateMap(st[k])
+		if !ok || len(child) == 0 {
+			return nil, ioterr.Newf(ErrDocumentTooLarge, "attribute %q can't be split", k)
+		}
+		subs, err := splitState(child, func(c NestedState) bool {
+			return fits(NestedState{k: c})
+		})
+		if err != nil {
+			return nil, ioterr.Newf(err, "splitting attribute %q", k)
+		}
+		for _, sub := range subs {
+			parts = append(parts, NestedState{k: sub})
+		}
+		cur = NestedState{}
+	}
+	if len(cur) > 0 {
+		parts = append(parts, cur)
+	}
+	return parts, nil
+}
+
+// checkReport returns error if the reported state can't be sent
+// under the document limits even if it is split.
+func (s *shadow) checkReport(state interface{}) error {
+	limits := s.opts.DocumentLimits
+	if limits == nil && s.opts.AutoSplit {
+		limits = &DefaultDocumentLimits
+	}
+	if limits == nil {
+		return nil
+	}
+	rawState, err := json.Marshal(state)
+	if err != nil {
+		return ioterr.New(err, "marshaling state")
+	}
+	req := &thingDocumentRaw{
+		State: thingStateRaw{Reported: json.RawMessage(rawState)},
+	}
+	if err := limits.check(req); err != nil {
+		if !s.opts.AutoSplit || errors.Is(err, ErrTooDeep) {
+			return ioterr.New(err, "updating reported state")
+		}
+	}
+	return nil
+}
+
+// updateSplit splits the reported state and sends them sequentially.
+func (s *shadow) updateSplit(ctx context.Context, rawState []byte, version int, limits *DocumentLimits, failure string) (*ThingDocument, error) {
+	var st NestedState
+	if err := json.Unmarshal(rawState, &st); err != nil {
+		return nil, ioterr.New(err, "unmarshaling state")
+	}
+	request := func(part NestedState) (*thingDocumentRaw, error) {
+		b, err := json.Marshal(part)
+		if err != nil {
+			return nil, ioterr.New(err, "marshaling state")
+		}
+		return &thingDocumentRaw{
+			Version: version,
+			State:   thingStateRaw{Reported: json.RawMessage(b)},
+		}, nil
+	}
+	parts, err := splitState(st, func(part NestedState) bool {
+		req, err := request(part)
+		return err == nil && limits.check(req) == nil
+	})
+	if err != nil {
+		return nil, ioterr.New(err, failure)
+	}
+
+	var doc *ThingDocument
+	for _, part := range parts {
+		req, err := request(part)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = s.update(ctx, req, failure); err != nil {
+			return nil, err
+		}
+		if version != 0 && doc != nil {
+			// Keep the version condition for the subsequent parts.
+			// Local document is nil if the shadow is deleted during the update.
+			version = doc.Version
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,236 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/at-wat/mqtt-go"
+	mockmqtt "github.com/at-wat/mqtt-go/mock"
+)
+
+func TestDocumentLimits(t *testing.T) {
+	testCases := map[string]struct {
+		limits DocumentLimits
+		state  string
+		err    error
+	}{
+		"OK": {
+			limits: DocumentLimits{MaxSize: 100, MaxDepth: 2, MaxAttributes: 2},
+			state:  `{"a":{"b":1},"c":2}`,
+		},
+		"TooLarge": {
+			limits: DocumentLimits{MaxSize: 50},
+			state:  `{"a":"` + strings.Repeat("x", 50) + `"}`,
+			err:    ErrDocumentTooLarge,
+		},
+		"TooDeep": {
+			limits: DocumentLimits{MaxDepth: 2},
+			state:  `{"a":{"b":{"c":1}}}`,
+			err:    ErrTooDeep,
+		},
+		"TooManyAttributes": {
+			limits: DocumentLimits{MaxAttributes: 2},
+			state:  `{"a":{"b":1,"c":[1,2]},"d":2}`,
+			err:    ErrTooManyAttributes,
+		},
+		"Unlimited": {
+			state: `{"a":{"b":{"c":"` + strings.Repeat("x", 10000) + `"}}}`,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			err := tt.limits.check(&thingDocumentRaw{
+				State: thingStateRaw{Reported: json.RawMessage(tt.state)},
+			})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected error: %v, got: %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestSplitState(t *testing.T) {
+	fits := func(st NestedState) bool {
+		_, n := stateShape(map[string]interface{}(st))
+		return n <= 2
+	}
+	testCases := map[string]struct {
+		input    NestedState
+		expected []NestedState
+		err      error
+	}{
+		"TopLevel": {
+			input: NestedState{"a": 1.0, "b": 2.0, "c": 3.0},
+			expected: []NestedState{
+				{"a": 1.0, "b": 2.0},
+				{"c": 3.0},
+			},
+		},
+		"Nested": {
+			input: NestedState{
+				"a": 1.0,
+				"b": NestedState{"c": 1.0, "d": 2.0, "e": 3.0},
+				"f": 2.0,
+			},
+			expected: []NestedState{
+				{"a": 1.0},
+				{"b": NestedState{"c": 1.0, "d": 2.0}},
+				{"b": NestedState{"e": 3.0}},
+				{"f": 2.0},
+			},
+		},
+		"Unsplittable": {
+			input: NestedState{"a": []interface{}{1.0, 2.0, 3.0}},
+			err:   ErrDocumentTooLarge,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			parts, err := splitState(tt.input, func(st NestedState) bool {
+				if _, ok := st["a"].([]interface{}); ok {
+					return false
+				}
+				return fits(st)
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error: %v, got: %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(tt.expected, parts) {
+				t.Errorf("Expected:\n%+v\ngot:\n%+v", tt.expected, parts)
+			}
+		})
+	}
+}
+
+func TestReport_DocumentLimits(t *testing.T) {
+	testCases := map[string]struct {
+		opts     []Option
+		update   []UpdateOption
+		deleted  bool
+		state    interface{}
+		err      error
+		expected []NestedState
+		reported NestedState
+	}{
+		"TooLarge": {
+			opts:  []Option{WithDocumentLimits(DocumentLimits{MaxAttributes: 2})},
+			state: map[string]interface{}{"a": 1, "b": 2, "c": 3},
+			err:   ErrTooManyAttributes,
+		},
+		"TooDeep": {
+			opts:  []Option{WithDocumentLimits(DocumentLimits{MaxDepth: 1}), WithAutoSplit()},
+			state: map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+			err:   ErrTooDeep,
+		},
+		"AutoSplit": {
+			opts:  []Option{WithDocumentLimits(DocumentLimits{MaxAttributes: 2}), WithAutoSplit()},
+			state: map[string]interface{}{"a": 1, "b": 2, "c": 3},
+			expected: []NestedState{
+				{"a": 1.0, "b": 2.0},
+				{"c": 3.0},
+			},
+			reported: NestedState{"a": 1.0, "b": 2.0, "c": 3.0},
+		},
+		"AutoSplitDeletedDuringUpdate": {
+			opts:    []Option{WithDocumentLimits(DocumentLimits{MaxAttributes: 2}), WithAutoSplit()},
+			update:  []UpdateOption{WithExpectedVersion(1)},
+			deleted: true,
+			state:   map[string]interface{}{"a": 1, "b": 2, "c": 3},
+			expected: []NestedState{
+				{"a": 1.0, "b": 2.0},
+				{"c": 3.0},
+			},
+			reported: NestedState{"c": 3.0},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			var reqs []NestedState
+			var version int
+			var s Shadow
+			var cli *mockDevice
+			cli = &mockDevice{
+				mockClient: &mockmqtt.Client{
+					PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+						req := &struct {
+							State       ThingState `json:"state"`
+							ClientToken string     `json:"clientToken"`
+						}{}
+						if err := json.Unmarshal(msg.Payload, req); err != nil {
+							t.Error(err)
+							return err
+						}
+						reqs = append(reqs, req.State.Reported)
+						version++
+						b, err := json.Marshal(&ThingDocument{
+							State:       ThingState{Reported: req.State.Reported},
+							Version:     version,
+							ClientToken: req.ClientToken,
+						})
+						if err != nil {
+							t.Error(err)
+							return err
+						}
+						cli.Serve(&mqtt.Message{
+							Topic:   s.(*shadow).topic("update/accepted"),
+							Payload: b,
+						})
+						if tt.deleted && version == 1 {
+							// Deleted by another client right after the update.
+							cli.Serve(&mqtt.Message{
+								Topic:   s.(*shadow).topic("delete/accepted"),
+								Payload: []byte("{}"),
+							})
+						}
+						return nil
+					},
+				},
+			}
+			var err error
+			s, err = New(ctx, cli, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cli.Handle(s)
+
+			doc, err := s.Report(ctx, tt.state, tt.update...)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error: %v, got: %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(tt.expected, reqs) {
+				t.Errorf("Expected requests:\n%+v\ngot:\n%+v", tt.expected, reqs)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(tt.reported, doc.State.Reported) {
+				t.Errorf("Expected reported state:\n%+v\ngot:\n%+v", tt.reported, doc.State.Reported)
+			}
+		})
+	}
+}
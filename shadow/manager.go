@@ -0,0 +1,151 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/at-wat/mqtt-go"
+
+	awsiotdev "github.com/seqsense/aws-iot-device-sdk-go/v6"
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+// Manager is an interface of the classic shadow and the named shadows of one thing.
+// Manager subscribes the shadow topics of all shadows by wildcards and
+// routes the messages to the Shadow of the corresponding name.
+type Manager interface {
+	mqtt.Handler
+	// Shadow returns Shadow of the given name.
+	// Empty name means the classic shadow.
+	// Shadow is created on the first call and the options are applied
+	// in addition to the options passed to NewManager.
+	// Options are ignored if the Shadow already exists.
+	// Messages of the shadow received before the first call are dropped.
+	Shadow(name string, opt ...Option) (Shadow, error)
+	// Close closes all shadows created by the Manager.
+	// Shadow returns ErrManagerClosed after Close.
+	Close() error
+}
+
+// ErrManagerClosed is returned if the Manager is already closed.
+var ErrManagerClosed = errors.New("manager closed")
+
+type manager struct {
+	cli     awsiotdev.Device
+	opts    []Option
+	prefix  string
+	mu      sync.Mutex
+	shadows map[string]*shadow
+	closed  bool
+}
+
+// NewManager creates Thing Shadow manager.
+// Given options are applied to all shadows.
+// The context is used to subscribe the shadow topics.
+// Background tasks of the shadows run until Close is called.
+func NewManager(ctx context.Context, cli awsiotdev.Device, opt ...Option) (Manager, error) {
+	m := &manager{
+		cli:     cli,
+		opts:    opt,
+		prefix:  "$aws/things/" + cli.ThingName() + "/shadow/",
+		shadows: make(map[string]*shadow),
+	}
+	opts := DefaultOptions
+	for _, o := range opt {
+		if err := o(&opts); err != nil {
+			return nil, ioterr.New(err, "applying option")
+		}
+	}
+	ops := []string{"update/delta", "update/accepted", "update/rejected", "get/+", "delete/+"}
+	if opts.Documents {
+		ops = append(ops, "update/documents")
+	}
+	var subs []mqtt.Subscription
+	for _, p := range []string{"", "name/+/"} {
+		for _, op := range ops {
+			subs = append(subs, mqtt.Subscription{Topic: m.prefix + p + op, QoS: mqtt.QoS1})
+		}
+	}
+	if _, err := cli.Subscribe(ctx, subs...); err != nil {
+		return nil, ioterr.New(err, "subscribing shadow topics")
+	}
+	return m, nil
+}
+
+func (m *manager) Shadow(name string, opt ...Option) (Shadow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ioterr.New(ErrManagerClosed, "creating shadow")
+	}
+	if s, ok := m.shadows[name]; ok {
+		return s, nil
+	}
+
+	opts := DefaultOptions
+	for _, o := range append(append(m.opts[:len(m.opts):len(m.opts)], opt...), WithName(name)) {
+		if err := o(&opts); err != nil {
+			return nil, ioterr.New(err, "applying option")
+		}
+	}
+	s, err := newShadow(m.cli, &opts)
+	if err != nil {
+		return nil, err
+	}
+	s.start(m.cli)
+	m.shadows[name] = s
+	return s, nil
+}
+
+func (m *manager) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	shadows := m.shadows
+	m.mu.Unlock()
+	for _, s := range shadows {
+		if err := s.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Serve implements mqtt.Handler.
+func (m *manager) Serve(msg *mqtt.Message) {
+	if !strings.HasPrefix(msg.Topic, m.prefix) {
+		return
+	}
+	var name string
+	if op := strings.TrimPrefix(msg.Topic, m.prefix); strings.HasPrefix(op, "name/") {
+		n := strings.SplitN(op, "/", 3)
+		if len(n) != 3 {
+			return
+		}
+		name = n[1]
+	}
+
+	m.mu.Lock()
+	s, ok := m.shadows[name]
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+	s.Serve(msg)
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/at-wat/mqtt-go"
+	mockmqtt "github.com/at-wat/mqtt-go/mock"
+)
+
+func TestManager(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var subscribed []string
+	cli := &mockDevice{mockClient: &mockmqtt.Client{
+		SubscribeFn: func(ctx context.Context, subs ...mqtt.Subscription) ([]mqtt.Subscription, error) {
+			for _, s := range subs {
+				subscribed = append(subscribed, s.Topic)
+			}
+			return subs, nil
+		},
+	}}
+	m, err := NewManager(ctx, cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.Handle(m)
+
+	expectedSubs := []string{
+		"$aws/things/test/shadow/update/delta",
+		"$aws/things/test/shadow/update/accepted",
+		"$aws/things/test/shadow/update/rejected",
+		"$aws/things/test/shadow/get/+",
+		"$aws/things/test/shadow/delete/+",
+		"$aws/things/test/shadow/name/+/update/delta",
+		"$aws/things/test/shadow/name/+/update/accepted",
+		"$aws/things/test/shadow/name/+/update/rejected",
+		"$aws/things/test/shadow/name/+/get/+",
+		"$aws/things/test/shadow/name/+/delete/+",
+	}
+	if !reflect.DeepEqual(expectedSubs, subscribed) {
+		t.Fatalf("Expected subscriptions:\n%v\ngot:\n%v", expectedSubs, subscribed)
+	}
+
+	chDelta := make(map[string]chan NestedState)
+	for _, name := range []string{"", "a", "b"} {
+		s, err := m.Shadow(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ch := make(chan NestedState, 1)
+		s.OnDelta(func(delta NestedState) { ch <- delta })
+		chDelta[name] = ch
+
+		s2, err := m.Shadow(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != s2 {
+			t.Error("Shadow must be reused")
+		}
+	}
+
+	for _, topic := range []string{
+		"$aws/things/test/shadow/name/unknown/update/delta",
+		"$aws/things/test/shadow/name/a/update/delta",
+		"$aws/things/test/shadow/update/delta",
+		"$aws/things/test/shadow/name/b/update/delta",
+	} {
+		cli.Serve(&mqtt.Message{
+			Topic:   topic,
+			Payload: []byte(`{"version":1,"state":{"topic":"` + topic + `"}}`),
+		})
+	}
+
+	for name, topic := range map[string]string{
+		"":  "$aws/things/test/shadow/update/delta",
+		"a": "$aws/things/test/shadow/name/a/update/delta",
+		"b": "$aws/things/test/shadow/name/b/update/delta",
+	} {
+		select {
+		case delta := <-chDelta[name]:
+			if delta["topic"] != topic {
+				t.Errorf("Shadow %q received message of %v", name, delta["topic"])
+			}
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		}
+		select {
+		case delta := <-chDelta[name]:
+			t.Errorf("Shadow %q received unexpected message of %v", name, delta["topic"])
+		default:
+		}
+	}
+
+	t.Run("OptionError", func(t *testing.T) {
+		errDummy := errors.New("dummy")
+		_, err := m.Shadow("c", func(*Options) error { return errDummy })
+		if !errors.Is(err, errDummy) {
+			t.Errorf("Expected error: %v, got: %v", errDummy, err)
+		}
+	})
+	t.Run("Close", func(t *testing.T) {
+		if err := m.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.Shadow("d"); !errors.Is(err, ErrManagerClosed) {
+			t.Errorf("Expected error: %v, got: %v", ErrManagerClosed, err)
+		}
+	})
+	t.Run("WithDocuments", func(t *testing.T) {
+		var subscribed []string
+		cli := &mockDevice{mockClient: &mockmqtt.Client{
+			SubscribeFn: func(ctx context.Context, subs ...mqtt.Subscription) ([]mqtt.Subscription, error) {
+				for _, s := range subs {
+					subscribed = append(subscribed, s.Topic)
+				}
+				return subs, nil
+			},
+		}}
+		if _, err := NewManager(ctx, cli, WithDocuments()); err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]bool{
+			"$aws/things/test/shadow/update/documents":        true,
+			"$aws/things/test/shadow/name/+/update/documents": true,
+		}
+		for _, topic := range subscribed {
+			delete(expected, topic)
+		}
+		if len(expected) > 0 {
+			t.Errorf("Expected subscriptions %v are missing in %v", expected, subscribed)
+		}
+	})
+}
@@ -17,6 +17,7 @@ package shadow
 import (
 	"encoding/json"
 	"regexp"
+	"sort"
 )
 
 var regexLeafJSON = regexp.MustCompile(`^{[^{}]*}$`)
@@ -85,3 +86,120 @@ func unmarshalMetadataImpl(b []byte) (interface{}, error) {
 		return v2, nil
 	}
 }
+
+// DesiredTimestamp returns the time when the desired attribute pointed by
+// the dot separated path was last updated.
+// If the path points an object, the latest timestamp of its children is returned.
+// Empty path means whole desired state.
+// false is returned if the metadata of the attribute is not available.
+func (s *ThingDocument) DesiredTimestamp(path string) (int, bool) {
+	return metadataTimestamp(s.Metadata.Desired, splitPath(path))
+}
+
+// ReportedTimestamp returns the time when the reported attribute pointed by
+// the dot separated path was last updated.
+// If the path points an object, the latest timestamp of its children is returned.
+// Empty path means whole reported state.
+// false is returned if the metadata of the attribute is not available.
+func (s *ThingDocument) ReportedTimestamp(path string) (int, bool) {
+	return metadataTimestamp(s.Metadata.Reported, splitPath(path))
+}
+
+// DeltaAttribute represents a leaf attribute of the delta.
+type DeltaAttribute struct {
+	// Path is a dot separated path to the attribute.
+	// Arrays are treated as a single attribute.
+	Path  string
+	Value interface{}
+	// Timestamp is the time when the attribute was desired.
+	Timestamp int
+}
+
+// deltaAttributes returns the leaf attributes of the delta sorted by the path.
+// Timestamps are taken from the delta metadata and fall back to
+// the desired metadata and the document timestamp.
+func (s *ThingDocument) deltaAttributes() []DeltaAttribute {
+	var out []DeltaAttribute
+	var walk func(path []string, st map[string]interface{})
+	walk = func(path []string, st map[string]interface{}) {
+		for k, v := range st {
+			p := append(path[:len(path):len(path)], k)
+			if m, ok := asStateMap(v); ok && len(m) > 0 {
+				walk(p, m)
+				continue
+			}
+			ts, ok := metadataTimestamp(s.Metadata.Delta, p)
+			if !ok {
+				if ts, ok = metadataTimestamp(s.Metadata.Desired, p); !ok {
+					ts = s.Timestamp
+				}
+			}
+			out = append(out, DeltaAttribute{
+				Path:      joinPaths(p),
+				Value:     v,
+				Timestamp: ts,
+			})
+		}
+	}
+	walk(nil, s.State.Delta)
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+func asMetadataMap(in interface{}) (map[string]interface{}, bool) {
+	switch m := in.(type) {
+	case NestedMetadata:
+		return m, true
+	case map[string]interface{}:
+		return m, true
+	}
+	return nil, false
+}
+
+// metadataTimestamp returns the timestamp of the attribute pointed by the path.
+// Latest timestamp of the children is returned if the path points an object or an array.
+func metadataTimestamp(m NestedMetadata, path []string) (int, bool) {
+	var v interface{} = m
+	for _, k := range path {
+		mm, ok := asMetadataMap(v)
+		if !ok {
+			return 0, false
+		}
+		if v, ok = mm[k]; !ok {
+			return 0, false
+		}
+	}
+	return latestTimestamp(v)
+}
+
+func latestTimestamp(v interface{}) (int, bool) {
+	switch md := v.(type) {
+	case Metadata:
+		return md.Timestamp, true
+	case *Metadata:
+		return md.Timestamp, true
+	case []interface{}:
+		var latest int
+		var found bool
+		for _, e := range md {
+			if ts, ok := latestTimestamp(e); ok && (!found || ts > latest) {
+				latest, found = ts, true
+			}
+		}
+		return latest, found
+	}
+	mm, ok := asMetadataMap(v)
+	if !ok {
+		return 0, false
+	}
+	var latest int
+	var found bool
+	for _, e := range mm {
+		if ts, ok := latestTimestamp(e); ok && (!found || ts > latest) {
+			latest, found = ts, true
+		}
+	}
+	return latest, found
+}
@@ -41,3 +41,76 @@ func TestNestedMetadata(t *testing.T) {
 		t.Errorf("Expected:\n(%T) %+v\ngot:\n(%T) %+v", expected, expected, v, v)
 	}
 }
+
+func TestThingDocument_Timestamp(t *testing.T) {
+	doc := &ThingDocument{
+		Metadata: ThingStateMetadata{
+			Desired: NestedMetadata{
+				"a": NestedMetadata{
+					"b": Metadata{Timestamp: 10},
+					"c": Metadata{Timestamp: 20},
+				},
+				"d": []interface{}{
+					Metadata{Timestamp: 30},
+					Metadata{Timestamp: 25},
+				},
+			},
+			Reported: NestedMetadata{
+				"a": Metadata{Timestamp: 5},
+			},
+		},
+	}
+	testCases := map[string]struct {
+		fn       func(string) (int, bool)
+		path     string
+		expected int
+		ok       bool
+	}{
+		"DesiredLeaf":     {doc.DesiredTimestamp, "a.b", 10, true},
+		"DesiredObject":   {doc.DesiredTimestamp, "a", 20, true},
+		"DesiredArray":    {doc.DesiredTimestamp, "d", 30, true},
+		"DesiredAll":      {doc.DesiredTimestamp, "", 30, true},
+		"DesiredNotFound": {doc.DesiredTimestamp, "a.x", 0, false},
+		"ReportedLeaf":    {doc.ReportedTimestamp, "a", 5, true},
+		"ReportedChild":   {doc.ReportedTimestamp, "a.b", 0, false},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ts, ok := tt.fn(tt.path)
+			if ts != tt.expected || ok != tt.ok {
+				t.Errorf("Expected: (%d, %v), got: (%d, %v)", tt.expected, tt.ok, ts, ok)
+			}
+		})
+	}
+}
+
+func TestThingDocument_deltaAttributes(t *testing.T) {
+	doc := &ThingDocument{
+		State: ThingState{
+			Delta: NestedState{
+				"a": NestedState{"b": 1.0, "c": "x"},
+				"d": []interface{}{1.0},
+				"e": true,
+			},
+		},
+		Metadata: ThingStateMetadata{
+			Desired: NestedMetadata{
+				"a": NestedMetadata{"c": Metadata{Timestamp: 20}},
+			},
+			Delta: NestedMetadata{
+				"a": NestedMetadata{"b": Metadata{Timestamp: 10}},
+			},
+		},
+		Timestamp: 30,
+	}
+	expected := []DeltaAttribute{
+		{Path: "a.b", Value: 1.0, Timestamp: 10},
+		{Path: "a.c", Value: "x", Timestamp: 20},
+		{Path: "d", Value: []interface{}{1.0}, Timestamp: 30},
+		{Path: "e", Value: true, Timestamp: 30},
+	}
+	if attrs := doc.deltaAttributes(); !reflect.DeepEqual(expected, attrs) {
+		t.Errorf("Expected:\n%+v\ngot:\n%+v", expected, attrs)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+// queueable returns true if the error is caused by communication failure
+// and the request can be sent later.
+// Errors detected locally before sending are not queueable
+// since the request never succeeds.
+func queueable(err error) bool {
+	var (
+		errResponse  *ErrorResponse
+		errType      *json.UnsupportedTypeError
+		errValue     *json.UnsupportedValueError
+		errMarshaler *json.MarshalerError
+		errSyntax    *json.SyntaxError
+		errUnmarshal *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &errResponse),
+		errors.Is(err, ErrInvalidResponse),
+		errors.Is(err, ErrDocumentTooLarge),
+		errors.Is(err, ErrTooDeep),
+		errors.Is(err, ErrTooManyAttributes),
+		errors.Is(err, ErrUnsupportedMapKeyType),
+		errors.Is(err, errInvalidAttribute),
+		errors.As(err, &errType),
+		errors.As(err, &errValue),
+		errors.As(err, &errMarshaler),
+		errors.As(err, &errSyntax),
+		errors.As(err, &errUnmarshal):
+		return false
+	}
+	return true
+}
+
+func (s *shadow) reportOffline(ctx context.Context, state interface{}, opt ...UpdateOption) (*ThingDocument, error) {
+	opts := &UpdateOptions{}
+	for _, o := range opt {
+		o(opts)
+	}
+	if opts.ExpectedVersion != 0 {
+		return s.updateSection(ctx, state, false, opt...)
+	}
+
+	// Report must not overtake the pending state being flushed.
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.pendingMu.Lock()
+	hasPending := !s.pending.empty()
+	s.pendingMu.Unlock()
+
+	if !hasPending {
+		doc, err := s.updateSection(ctx, state, false, opt...)
+		if err == nil || !queueable(err) {
+			return doc, err
+		}
+	} else if err := s.checkReport(state); err != nil {
+		return nil, err
+	}
+	if err := s.enqueue(state); err != nil {
+		return nil, err
+	}
+	if hasPending {
+		if err := s.flushLocked(ctx); err != nil && !queueable(err) {
+			return nil, err
+		}
+	}
+	return s.Document(), nil
+}
+
+func (s *shadow) enqueue(state interface{}) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return ioterr.New(err, "marshaling state")
+	}
+	var patch NestedState
+	if err := json.Unmarshal(b, &patch); err != nil {
+		return ioterr.New(err, "unmarshaling state")
+	}
+
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if s.pending == nil {
+		s.pending = newPendingState()
+	}
+	s.pending.merge(patch, int(time.Now().Unix()))
+	if err := s.opts.OfflineQueue.Save(s.pending); err != nil {
+		return ioterr.New(err, "saving pending state")
+	}
+	return nil
+}
+
+// flush sends the pending state.
+// Pending state is restored on communication failure.
+// Reports are blocked until the flush is completed.
+func (s *shadow) flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	return s.flushLocked(ctx)
+}
+
+func (s *shadow) flushLocked(ctx context.Context) error {
+	s.pendingMu.Lock()
+	p := s.pending
+	s.pending = nil
+	s.pendingMu.Unlock()
+	if p.empty() {
+		return nil
+	}
+
+	err := s.flushPending(ctx, p)
+
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if err != nil && queueable(err) && !p.empty() {
+		s.pending = p
+	}
+	if errSave := s.opts.OfflineQueue.Save(s.pending); errSave != nil {
+		return ioterr.New(errSave, "saving pending state")
+	}
+	return err
+}
+
+func (s *shadow) flushPending(ctx context.Context, p *PendingState) error {
+	if s.opts.ConflictPolicy == PreferRemote {
+		doc, err := s.Get(ctx)
+		var e *ErrorResponse
+		switch {
+		case errors.As(err, &e) && e.Code == http.StatusNotFound:
+			// Shadow doesn't exist.
+		case err != nil:
+			return ioterr.New(err, "getting document")
+		default:
+			for _, l := range p.leaves() {
+				ts, ok := metadataTimestamp(doc.Metadata.Reported, l.path)
+				if ok && ts > l.timestamp {
+					p.remove(l.path)
+				}
+			}
+			if p.empty() {
+				return nil
+			}
+		}
+	}
+	if _, err := s.updateSection(ctx, p.State, false); err != nil {
+		return ioterr.New(err, "reporting pending state")
+	}
+	return nil
+}
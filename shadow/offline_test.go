@@ -0,0 +1,378 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/at-wat/mqtt-go"
+	mockmqtt "github.com/at-wat/mqtt-go/mock"
+)
+
+type offlineTestServer struct {
+	mu       sync.Mutex
+	online   bool
+	reject   bool
+	remote   *ThingDocument
+	chUpdate chan NestedState
+	hold     chan struct{} // delays update responses until closed
+}
+
+func newOfflineTestShadow(ctx context.Context, t *testing.T, srv *offlineTestServer, opt ...Option) (Shadow, *mockDevice) {
+	errOffline := errors.New("offline")
+
+	var s Shadow
+	var cli *mockDevice
+	cli = &mockDevice{
+		mockClient: &mockmqtt.Client{
+			PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+				srv.mu.Lock()
+				defer srv.mu.Unlock()
+				if !srv.online {
+					return errOffline
+				}
+				req := &struct {
+					State       ThingState `json:"state"`
+					ClientToken string     `json:"clientToken"`
+				}{}
+				if err := json.Unmarshal(msg.Payload, req); err != nil {
+					t.Error(err)
+					return err
+				}
+				var res interface{}
+				var topic string
+				switch msg.Topic {
+				case s.(*shadow).topic("get"):
+					res, topic = srv.remote, "get/accepted"
+				case s.(*shadow).topic("update"):
+					srv.chUpdate <- req.State.Reported
+					if srv.reject {
+						res, topic = &ErrorResponse{Code: 400, Message: "Bad request"}, "update/rejected"
+						break
+					}
+					srv.remote.Version++
+					res = &thingDocumentRaw{Version: srv.remote.Version}
+					topic = "update/accepted"
+				default:
+					return nil
+				}
+				setClientToken(res, req.ClientToken)
+				b, err := json.Marshal(res)
+				if err != nil {
+					t.Error(err)
+					return err
+				}
+				hold := srv.hold
+				go func() {
+					if hold != nil && topic == "update/accepted" {
+						<-hold
+					}
+					cli.Serve(&mqtt.Message{Topic: s.(*shadow).topic(topic), Payload: b})
+				}()
+				return nil
+			},
+		},
+		Retryer: &mockRetryer{},
+	}
+	opts := append([]Option{
+		func(o *Options) error {
+			o.reconnectCheckPeriod = 5 * time.Millisecond
+			return nil
+		},
+	}, opt...)
+	var err error
+	s, err = New(ctx, cli, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	cli.Handle(s)
+	return s, cli
+}
+
+func TestOfflineQueue(t *testing.T) {
+	now := int(time.Now().Unix())
+
+	testCases := map[string]struct {
+		opts     []Option
+		remote   *ThingDocument
+		reject   bool
+		expected NestedState
+	}{
+		"PreferPending": {
+			remote: &ThingDocument{
+				State:    ThingState{Reported: NestedState{"a": 0.0}},
+				Metadata: ThingStateMetadata{Reported: NestedMetadata{"a": Metadata{Timestamp: now + 100}}},
+				Version:  1,
+			},
+			expected: NestedState{"a": 1.0, "b": NestedState{"c": 2.0}},
+		},
+		"PreferRemote": {
+			opts: []Option{WithConflictPolicy(PreferRemote)},
+			remote: &ThingDocument{
+				State:    ThingState{Reported: NestedState{"a": 0.0}},
+				Metadata: ThingStateMetadata{Reported: NestedMetadata{"a": Metadata{Timestamp: now + 100}}},
+				Version:  1,
+			},
+			expected: NestedState{"b": NestedState{"c": 2.0}},
+		},
+		"Rejected": {
+			remote:   &ThingDocument{Version: 1},
+			reject:   true,
+			expected: NestedState{"a": 1.0, "b": NestedState{"c": 2.0}},
+		},
+	}
+
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			srv := &offlineTestServer{
+				remote:   tt.remote,
+				reject:   tt.reject,
+				chUpdate: make(chan NestedState, 10),
+			}
+			store := NewMemoryStore()
+			s, cli := newOfflineTestShadow(ctx, t, srv,
+				append([]Option{WithOfflineQueue(store)}, tt.opts...)...,
+			)
+			chErr := make(chan error, 10)
+			s.OnError(func(err error) { chErr <- err })
+
+			if _, err := s.Report(ctx, map[string]interface{}{"a": 1}); err != nil {
+				t.Fatal(err)
+			}
+			doc, err := s.Report(ctx, map[string]interface{}{"b": map[string]interface{}{"c": 2}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectedUnsynced := NestedState{"a": 1.0, "b": NestedState{"c": 2.0}}
+			if !reflect.DeepEqual(expectedUnsynced, doc.Unsynced) {
+				t.Errorf("Expected unsynced state:\n%+v\ngot:\n%+v", expectedUnsynced, doc.Unsynced)
+			}
+			if !reflect.DeepEqual(expectedUnsynced, doc.State.Reported) {
+				t.Errorf("Expected reported state:\n%+v\ngot:\n%+v", expectedUnsynced, doc.State.Reported)
+			}
+			p, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expectedUnsynced, p.State) {
+				t.Errorf("Expected stored state:\n%+v\ngot:\n%+v", expectedUnsynced, p.State)
+			}
+
+			srv.mu.Lock()
+			srv.online = true
+			srv.mu.Unlock()
+			cli.Retryer.(*mockRetryer).reconnect()
+
+			select {
+			case reported := <-srv.chUpdate:
+				if !reflect.DeepEqual(tt.expected, reported) {
+					t.Errorf("Expected flushed state:\n%+v\ngot:\n%+v", tt.expected, reported)
+				}
+			case <-ctx.Done():
+				t.Fatal("Timeout")
+			}
+			if tt.reject {
+				select {
+				case err := <-chErr:
+					var e *ErrorResponse
+					if !errors.As(err, &e) {
+						t.Errorf("Expected error type: %T, got: %T", e, err)
+					}
+				case <-ctx.Done():
+					t.Fatal("Timeout")
+				}
+			}
+
+			for {
+				p, err := store.Load()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if p == nil && s.Document().Unsynced == nil {
+					break
+				}
+				select {
+				case <-time.After(5 * time.Millisecond):
+				case <-ctx.Done():
+					t.Fatal("Timeout")
+				}
+			}
+		})
+	}
+
+	t.Run("ReportWithPending", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		srv := &offlineTestServer{
+			remote:   &ThingDocument{Version: 1},
+			chUpdate: make(chan NestedState, 10),
+		}
+		s, _ := newOfflineTestShadow(ctx, t, srv, WithOfflineQueue(nil))
+
+		if _, err := s.Report(ctx, map[string]interface{}{"a": 1}); err != nil {
+			t.Fatal(err)
+		}
+		srv.mu.Lock()
+		srv.online = true
+		srv.mu.Unlock()
+
+		doc, err := s.Report(ctx, map[string]interface{}{"b": 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Unsynced != nil {
+			t.Errorf("Expected no unsynced state, got: %+v", doc.Unsynced)
+		}
+		expected := NestedState{"a": 1.0, "b": 2.0}
+		select {
+		case reported := <-srv.chUpdate:
+			if !reflect.DeepEqual(expected, reported) {
+				t.Errorf("Expected reported state:\n%+v\ngot:\n%+v", expected, reported)
+			}
+		default:
+			t.Fatal("Update is not requested")
+		}
+	})
+	t.Run("ReportDuringFlush", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		srv := &offlineTestServer{
+			remote:   &ThingDocument{Version: 1},
+			chUpdate: make(chan NestedState, 10),
+			hold:     make(chan struct{}),
+		}
+		s, cli := newOfflineTestShadow(ctx, t, srv, WithOfflineQueue(nil))
+
+		if _, err := s.Report(ctx, map[string]interface{}{"a": 1}); err != nil {
+			t.Fatal(err)
+		}
+		srv.mu.Lock()
+		srv.online = true
+		srv.mu.Unlock()
+		cli.Retryer.(*mockRetryer).reconnect()
+
+		select {
+		case reported := <-srv.chUpdate:
+			if expected := (NestedState{"a": 1.0}); !reflect.DeepEqual(expected, reported) {
+				t.Errorf("Expected flushed state:\n%+v\ngot:\n%+v", expected, reported)
+			}
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		}
+
+		chReport := make(chan error)
+		go func() {
+			_, err := s.Report(ctx, map[string]interface{}{"a": 2})
+			chReport <- err
+		}()
+		select {
+		case reported := <-srv.chUpdate:
+			t.Errorf("Report must wait for the flush, got: %+v", reported)
+		case <-time.After(50 * time.Millisecond):
+		}
+		close(srv.hold)
+
+		select {
+		case reported := <-srv.chUpdate:
+			if expected := (NestedState{"a": 2.0}); !reflect.DeepEqual(expected, reported) {
+				t.Errorf("Expected reported state:\n%+v\ngot:\n%+v", expected, reported)
+			}
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		}
+		if err := <-chReport; err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("LocalError", func(t *testing.T) {
+		testCases := map[string]struct {
+			online bool
+			state  interface{}
+			err    error
+		}{
+			"TooLargeOnline": {
+				online: true,
+				state:  map[string]interface{}{"a": strings.Repeat("x", 100)},
+				err:    ErrDocumentTooLarge,
+			},
+			"TooLargeWithPending": {
+				state: map[string]interface{}{"a": strings.Repeat("x", 100)},
+				err:   ErrDocumentTooLarge,
+			},
+			"TooDeepWithPending": {
+				state: map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+				err:   ErrTooDeep,
+			},
+			"Marshal": {
+				online: true,
+				state:  map[string]interface{}{"a": func() {}},
+			},
+		}
+		for name, tt := range testCases {
+			tt := tt
+			t.Run(name, func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+
+				srv := &offlineTestServer{
+					remote:   &ThingDocument{Version: 1},
+					chUpdate: make(chan NestedState, 10),
+				}
+				s, _ := newOfflineTestShadow(ctx, t, srv,
+					WithOfflineQueue(nil),
+					WithDocumentLimits(DocumentLimits{MaxSize: 80, MaxDepth: 1}),
+				)
+				if !tt.online {
+					if _, err := s.Report(ctx, map[string]interface{}{"b": 1}); err != nil {
+						t.Fatal(err)
+					}
+				}
+				srv.mu.Lock()
+				srv.online = tt.online
+				srv.mu.Unlock()
+
+				_, err := s.Report(ctx, tt.state)
+				if err == nil {
+					t.Fatal("Expected error")
+				}
+				if tt.err != nil && !errors.Is(err, tt.err) {
+					t.Errorf("Expected error: %v, got: %v", tt.err, err)
+				}
+				doc := s.Document()
+				if _, ok := doc.Unsynced["a"]; ok {
+					t.Errorf("Invalid state must not be queued, got: %+v", doc.Unsynced)
+				}
+			})
+		}
+	})
+}
@@ -14,10 +14,24 @@
 
 package shadow
 
+import (
+	"time"
+)
+
 // Options stores Device Shadow options.
 type Options struct {
 	Name              string
 	IncrementalUpdate bool
+	AutoSync          bool
+	AutoSyncInterval  time.Duration
+	OfflineQueue      PendingStore
+	ConflictPolicy    ConflictPolicy
+	DocumentLimits    *DocumentLimits
+	AutoSplit         bool
+	Documents         bool
+
+	reconnectCheckPeriod time.Duration
+	offlineRetryPeriod   time.Duration
 }
 
 // DefaultOptions is a default Device Shadow options.
@@ -26,6 +40,9 @@ var DefaultOptions = Options{
 	// reduce data size to be sent.
 	// If you want to send full state, use WithIncrementalUpdate(false).
 	IncrementalUpdate: true,
+
+	reconnectCheckPeriod: time.Second,
+	offlineRetryPeriod:   10 * time.Second,
 }
 
 // Option is a functional option of UpdateJob.
@@ -47,3 +64,193 @@ func WithIncrementalUpdate(enable bool) Option {
 		return nil
 	}
 }
+
+// WithDocuments subscribes update/documents topic to receive the thing documents
+// before and after each update by the handler set by OnDocuments.
+// Note that the full documents are delivered on every update.
+// When using Manager, it must be passed to NewManager.
+func WithDocuments() Option {
+	return func(o *Options) error {
+		o.Documents = true
+		return nil
+	}
+}
+
+// WithAutoSync enables automatic synchronization of the local thing document.
+// The document is fetched from AWS IoT when dropped versions are detected,
+// when the MQTT connection is re-established, and on the interval specified by
+// WithAutoSyncInterval.
+// OnDelta handler is called with the delta of the fetched document.
+// Synchronization runs in background until Shadow.Close is called.
+func WithAutoSync() Option {
+	return func(o *Options) error {
+		o.AutoSync = true
+		return nil
+	}
+}
+
+// WithAutoSyncInterval sets interval of the periodic synchronization enabled by WithAutoSync.
+// Zero disables periodic synchronization.
+func WithAutoSyncInterval(d time.Duration) Option {
+	return func(o *Options) error {
+		o.AutoSyncInterval = d
+		return nil
+	}
+}
+
+// WithDocumentLimits enables validation of the update requests.
+// Requests exceeding the limits are not sent and
+// ErrDocumentTooLarge, ErrTooDeep or ErrTooManyAttributes is returned.
+func WithDocumentLimits(l DocumentLimits) Option {
+	return func(o *Options) error {
+		o.DocumentLimits = &l
+		return nil
+	}
+}
+
+// WithAutoSplit enables automatic split of the reported state exceeding
+// the document size or attribute number limits.
+// The parts are sent by the sequential updates and the last document is returned.
+// If the update fails in the middle, the parts sent before are kept updated.
+// DefaultDocumentLimits is used if WithDocumentLimits is not specified.
+func WithAutoSplit() Option {
+	return func(o *Options) error {
+		o.AutoSplit = true
+		return nil
+	}
+}
+
+// WithOfflineQueue enables offline queue mode.
+// Reports failed due to communication errors are merged into a pending state
+// and persisted to the store.
+// Report returns local document with Unsynced state in that case.
+// Once the pending state exists, subsequent reports are merged into it
+// and sent together with the pending state.
+// The pending state is also sent on reconnection and periodically
+// until Shadow.Close is called.
+// If AWS IoT rejects the pending state, it is discarded and the error is
+// notified to the OnError handler.
+// Conditional reports by WithExpectedVersion are not queued.
+// If nil store is given, pending state is stored on memory.
+func WithOfflineQueue(store PendingStore) Option {
+	return func(o *Options) error {
+		if store == nil {
+			store = NewMemoryStore()
+		}
+		o.OfflineQueue = store
+		return nil
+	}
+}
+
+// WithConflictPolicy sets the policy to resolve conflicts between the pending
+// state of the offline queue and the state reported by others in the meantime.
+func WithConflictPolicy(p ConflictPolicy) Option {
+	return func(o *Options) error {
+		o.ConflictPolicy = p
+		return nil
+	}
+}
+
+// ConflictPolicy represents conflict resolution policy of the offline queue.
+type ConflictPolicy int
+
+// Conflict resolution policies.
+const (
+	// PreferPending overwrites the reported state by the pending state.
+	PreferPending ConflictPolicy = iota
+	// PreferRemote drops the pending attributes which was reported
+	// by others after the pending report.
+	PreferRemote
+)
+
+// UpdateOptions stores options of Report and Desire.
+type UpdateOptions struct {
+	// ExpectedVersion makes the update conditional.
+	// The update is rejected with ErrVersionConflict if the version of the
+	// thing document on AWS IoT doesn't match.
+	// Zero means unconditional update.
+	ExpectedVersion int
+}
+
+// UpdateOption is a functional option of Report and Desire.
+type UpdateOption func(options *UpdateOptions)
+
+// WithExpectedVersion sets expected version of the thing document.
+func WithExpectedVersion(v int) UpdateOption {
+	return func(o *UpdateOptions) {
+		o.ExpectedVersion = v
+	}
+}
+
+// RetryPolicy represents a retry policy of the operation.
+type RetryPolicy struct {
+	// MaxRetries is a maximum number of retries.
+	// Zero disables retry.
+	MaxRetries int
+	// Wait is a wait duration before the first retry.
+	// The wait is doubled on each retry.
+	Wait time.Duration
+	// MaxWait is an upper limit of the wait duration.
+	MaxWait time.Duration
+}
+
+// DefaultRetryPolicy is a default retry policy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	Wait:       100 * time.Millisecond,
+	MaxWait:    2 * time.Second,
+}
+
+func (p RetryPolicy) wait(retry int) time.Duration {
+	d := p.Wait
+	for i := 0; i < retry; i++ {
+		d *= 2
+		if p.MaxWait > 0 && d >= p.MaxWait {
+			return p.MaxWait
+		}
+	}
+	return d
+}
+
+// ModifyOptions stores options of Modify.
+type ModifyOptions struct {
+	// Desired updates desired state instead of reported state.
+	Desired bool
+	// RetryPolicy controls retries on version conflict.
+	RetryPolicy RetryPolicy
+}
+
+// ModifyOption is a functional option of Modify.
+type ModifyOption func(options *ModifyOptions)
+
+// WithModifyDesired makes Modify update desired state.
+func WithModifyDesired() ModifyOption {
+	return func(o *ModifyOptions) {
+		o.Desired = true
+	}
+}
+
+// WithRetryPolicy sets retry policy on version conflict.
+func WithRetryPolicy(p RetryPolicy) ModifyOption {
+	return func(o *ModifyOptions) {
+		o.RetryPolicy = p
+	}
+}
+
+// AcknowledgeOptions stores options of Acknowledge.
+type AcknowledgeOptions struct {
+	// KeepDesired leaves the desired state intact.
+	// By default, the acknowledged attributes are removed from the desired state.
+	KeepDesired bool
+}
+
+// AcknowledgeOption is a functional option of Acknowledge.
+type AcknowledgeOption func(options *AcknowledgeOptions)
+
+// WithKeepDesired makes Acknowledge only report the applied values
+// and leave the desired state intact.
+func WithKeepDesired() AcknowledgeOption {
+	return func(o *AcknowledgeOptions) {
+		o.KeepDesired = true
+	}
+}
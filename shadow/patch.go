@@ -0,0 +1,159 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"errors"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+// ErrInvalidPatch is returned if the patch operation can not be applied.
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// PatchOp is a path based update operation of the state.
+// Path is a dot separated attribute names.
+// e.g. "a.b.c" points {"a": {"b": {"c": value}}}.
+type PatchOp interface {
+	apply(update, current NestedState) error
+}
+
+type patchSet struct {
+	path  string
+	value interface{}
+}
+
+type patchAppend struct {
+	path   string
+	values []interface{}
+}
+
+// Set returns PatchOp to set the value to the attribute.
+// Intermediate objects are created if not exist.
+func Set(path string, v interface{}) PatchOp {
+	return &patchSet{path: path, value: v}
+}
+
+// Delete returns PatchOp to delete the attribute.
+// It is sent as null in the update.
+func Delete(path string) PatchOp {
+	return &patchSet{path: path}
+}
+
+// AppendTo returns PatchOp to append the values to the array attribute.
+// Since AWS IoT replaces whole array on update, the values are appended
+// to the array in the local document and the resulting array is sent.
+// The array is created if not exist.
+func AppendTo(path string, v ...interface{}) PatchOp {
+	return &patchAppend{path: path, values: v}
+}
+
+func (p *patchSet) apply(update, current NestedState) error {
+	if p.path == "" {
+		return ioterr.New(ErrInvalidPatch, "setting empty path")
+	}
+	setState(update, splitPath(p.path), p.value)
+	return nil
+}
+
+func (p *patchAppend) apply(update, current NestedState) error {
+	if p.path == "" {
+		return ioterr.New(ErrInvalidPatch, "appending to empty path")
+	}
+	path := splitPath(p.path)
+	v, ok := lookupState(update, path)
+	if !ok {
+		v, _ = lookupState(current, path)
+	}
+	var arr []interface{}
+	switch a := v.(type) {
+	case nil:
+	case []interface{}:
+		arr = append(arr, a...)
+	default:
+		return ioterr.Newf(ErrInvalidPatch, "appending to non-array %s", pathName(p.path))
+	}
+	setState(update, path, append(arr, p.values...))
+	return nil
+}
+
+// newPatch converts patch operations into the state update.
+func newPatch(current NestedState, ops []PatchOp) (NestedState, error) {
+	update := NestedState{}
+	for _, op := range ops {
+		if err := op.apply(update, current); err != nil {
+			return nil, err
+		}
+	}
+	return update, nil
+}
+
+func (s *shadow) ReportPatch(ctx context.Context, ops ...PatchOp) (*ThingDocument, error) {
+	var current NestedState
+	if doc := s.Document(); doc != nil {
+		current = doc.State.Reported
+	}
+	update, err := newPatch(current, ops)
+	if err != nil {
+		return nil, err
+	}
+	return s.Report(ctx, update)
+}
+
+func (s *shadow) DesirePatch(ctx context.Context, ops ...PatchOp) (*ThingDocument, error) {
+	var current NestedState
+	if doc := s.Document(); doc != nil {
+		current = doc.State.Desired
+	}
+	update, err := newPatch(current, ops)
+	if err != nil {
+		return nil, err
+	}
+	return s.Desire(ctx, update)
+}
+
+// MergePatch returns a copy of the state applied RFC 7396 JSON merge patch.
+// null in the patch deletes the attribute, objects are merged recursively
+// and other values including arrays replace the attribute
+// as AWS IoT applies the state update.
+// The receiver is not modified.
+func (n NestedState) MergePatch(patch map[string]interface{}) NestedState {
+	out := NestedState(cloneState(n))
+	mergePatch(out, patch)
+	return out
+}
+
+func mergePatch(target NestedState, patch map[string]interface{}) {
+	for k, v := range patch {
+		if v == nil {
+			delete(target, k)
+			continue
+		}
+		p, ok := asStateMap(v)
+		if !ok {
+			target[k] = v
+			continue
+		}
+		var child NestedState
+		if t, ok := asStateMap(target[k]); ok {
+			child = NestedState(t)
+		} else {
+			child = NestedState{}
+		}
+		mergePatch(child, p)
+		target[k] = child
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/at-wat/mqtt-go"
+	mockmqtt "github.com/at-wat/mqtt-go/mock"
+)
+
+func TestNewPatch(t *testing.T) {
+	current := NestedState{
+		"list": []interface{}{"a"},
+		"str":  "value",
+	}
+	testCases := map[string]struct {
+		ops      []PatchOp
+		expected NestedState
+		err      error
+	}{
+		"Set": {
+			ops: []PatchOp{Set("a.b.c", 1), Set("a.b.d", "x"), Set("e", true)},
+			expected: NestedState{
+				"a": NestedState{"b": NestedState{"c": 1, "d": "x"}},
+				"e": true,
+			},
+		},
+		"Delete": {
+			ops: []PatchOp{Delete("a.b"), Delete("c")},
+			expected: NestedState{
+				"a": NestedState{"b": nil},
+				"c": nil,
+			},
+		},
+		"AppendTo": {
+			ops: []PatchOp{AppendTo("list", "b", "c"), AppendTo("list", "d")},
+			expected: NestedState{
+				"list": []interface{}{"a", "b", "c", "d"},
+			},
+		},
+		"AppendToNew": {
+			ops: []PatchOp{AppendTo("x.list", 1)},
+			expected: NestedState{
+				"x": NestedState{"list": []interface{}{1}},
+			},
+		},
+		"AppendToNonArray": {
+			ops: []PatchOp{AppendTo("str", 1)},
+			err: ErrInvalidPatch,
+		},
+		"EmptyPath": {
+			ops: []PatchOp{Set("", 1)},
+			err: ErrInvalidPatch,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			update, err := newPatch(current, tt.ops)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error: %v, got: %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(tt.expected, update) {
+				t.Errorf("Expected update:\n%+v\ngot:\n%+v", tt.expected, update)
+			}
+		})
+	}
+	if expected := []interface{}{"a"}; !reflect.DeepEqual(expected, current["list"]) {
+		t.Errorf("Current state must not be modified, expected: %v, got: %v", expected, current["list"])
+	}
+}
+
+func TestNestedState_MergePatch(t *testing.T) {
+	testCases := map[string]struct {
+		state    NestedState
+		patch    map[string]interface{}
+		expected NestedState
+	}{
+		"Replace": {
+			state:    NestedState{"a": "b"},
+			patch:    map[string]interface{}{"a": "c"},
+			expected: NestedState{"a": "c"},
+		},
+		"Add": {
+			state:    NestedState{"a": "b"},
+			patch:    map[string]interface{}{"b": "c"},
+			expected: NestedState{"a": "b", "b": "c"},
+		},
+		"Delete": {
+			state:    NestedState{"a": "b", "b": "c"},
+			patch:    map[string]interface{}{"a": nil},
+			expected: NestedState{"b": "c"},
+		},
+		"DeleteNonExistent": {
+			state:    NestedState{"a": "b"},
+			patch:    map[string]interface{}{"c": nil},
+			expected: NestedState{"a": "b"},
+		},
+		"Nested": {
+			state: NestedState{"a": NestedState{"b": "c", "d": "e"}},
+			patch: map[string]interface{}{
+				"a": map[string]interface{}{"b": nil, "f": "g"},
+			},
+			expected: NestedState{"a": NestedState{"d": "e", "f": "g"}},
+		},
+		"ObjectOverValue": {
+			state: NestedState{"a": "b"},
+			patch: map[string]interface{}{
+				"a": NestedState{"c": "d", "e": nil},
+			},
+			expected: NestedState{"a": NestedState{"c": "d"}},
+		},
+		"ArrayReplace": {
+			state:    NestedState{"a": []interface{}{1, 2}},
+			patch:    map[string]interface{}{"a": []interface{}{3}},
+			expected: NestedState{"a": []interface{}{3}},
+		},
+		"Nil": {
+			state:    nil,
+			patch:    map[string]interface{}{"a": "b"},
+			expected: NestedState{"a": "b"},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			var orig NestedState
+			if tt.state != nil {
+				orig = NestedState(cloneState(tt.state))
+			}
+			out := tt.state.MergePatch(tt.patch)
+			if !reflect.DeepEqual(tt.expected, out) {
+				t.Errorf("Expected:\n%+v\ngot:\n%+v", tt.expected, out)
+			}
+			if !reflect.DeepEqual(orig, tt.state) {
+				t.Errorf("Receiver must not be modified, expected: %v, got: %v", orig, tt.state)
+			}
+		})
+	}
+}
+
+func TestReportPatch(t *testing.T) {
+	for name, incremental := range map[string]bool{
+		"Incremental": true,
+		"Full":        false,
+	} {
+		incremental := incremental
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			var s Shadow
+			var cli *mockDevice
+			var payload []byte
+			cli = &mockDevice{
+				mockClient: &mockmqtt.Client{
+					PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+						payload = msg.Payload
+						req := &simpleRequest{}
+						if err := json.Unmarshal(msg.Payload, req); err != nil {
+							t.Error(err)
+							return err
+						}
+						res := &ThingDocument{Version: 1}
+						setClientToken(res, req.ClientToken)
+						bres, err := json.Marshal(res)
+						if err != nil {
+							t.Error(err)
+							return err
+						}
+						cli.Serve(&mqtt.Message{
+							Topic:   s.(*shadow).topic("update/accepted"),
+							Payload: bres,
+						})
+						return nil
+					},
+				},
+			}
+			var err error
+			s, err = New(ctx, cli, WithIncrementalUpdate(incremental))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cli.Handle(s)
+
+			if _, err := s.ReportPatch(ctx, Set("a.b", 1), Delete("c")); err != nil {
+				t.Fatal(err)
+			}
+			var req struct {
+				State map[string]interface{} `json:"state"`
+			}
+			if err := json.Unmarshal(payload, &req); err != nil {
+				t.Fatal(err)
+			}
+			expected := map[string]interface{}{
+				"reported": map[string]interface{}{
+					"a": map[string]interface{}{"b": float64(1)},
+					"c": nil,
+				},
+			}
+			if !reflect.DeepEqual(expected, req.State) {
+				t.Errorf("Expected state:\n%+v\ngot:\n%+v", expected, req.State)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"strconv"
+	"strings"
+)
+
+// pathSeparator separates attribute names in the attribute path.
+// e.g. "a.b.c" points {"a": {"b": {"c": value}}}.
+const pathSeparator = "."
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + pathSeparator + key
+}
+
+func joinPaths(path []string) string {
+	return strings.Join(path, pathSeparator)
+}
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, pathSeparator)
+}
+
+func indexName(i int) string {
+	return strconv.Itoa(i)
+}
+
+func pathName(path string) string {
+	if path == "" {
+		return "state"
+	}
+	return "attribute \"" + path + "\""
+}
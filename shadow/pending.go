@@ -0,0 +1,211 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/atomicfile"
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+// PendingState represents reported state not yet synchronized with AWS IoT.
+type PendingState struct {
+	// State is a patch of the reported state.
+	// nil attribute value means deletion of the attribute.
+	State NestedState `json:"state"`
+	// Metadata stores the time of the reports in Unix time seconds
+	// in the same format as the thing document metadata.
+	Metadata NestedMetadata `json:"metadata"`
+}
+
+// PendingStore is an interface of the storage of the pending reported state.
+type PendingStore interface {
+	// Load returns the stored state.
+	// nil is returned if nothing is stored.
+	Load() (*PendingState, error)
+	// Save stores the state.
+	// Stored state is removed if nil is given.
+	Save(*PendingState) error
+}
+
+type pendingLeaf struct {
+	path      []string
+	value     interface{}
+	timestamp int
+}
+
+func newPendingState() *PendingState {
+	return &PendingState{
+		State:    NestedState{},
+		Metadata: NestedMetadata{},
+	}
+}
+
+func (p *PendingState) empty() bool {
+	return p == nil || len(p.State) == 0
+}
+
+// leaves returns the list of the leaf attributes.
+// Arrays and empty objects are treated as leaves.
+func (p *PendingState) leaves() []pendingLeaf {
+	var out []pendingLeaf
+	var walk func(path []string, s map[string]interface{})
+	walk = func(path []string, s map[string]interface{}) {
+		for k, v := range s {
+			pp := append(path[:len(path):len(path)], k)
+			if m, ok := asStateMap(v); ok && len(m) > 0 {
+				walk(pp, m)
+				continue
+			}
+			ts, _ := metadataTimestamp(p.Metadata, pp)
+			out = append(out, pendingLeaf{path: pp, value: v, timestamp: ts})
+		}
+	}
+	walk(nil, p.State)
+	return out
+}
+
+// set sets the value and the timestamp of the attribute.
+func (p *PendingState) set(path []string, v interface{}, ts int) {
+	s, m := p.State, p.Metadata
+	for _, k := range path[:len(path)-1] {
+		ss, ok := s[k].(NestedState)
+		if !ok {
+			ss = NestedState{}
+			s[k] = ss
+		}
+		mm, ok := m[k].(NestedMetadata)
+		if !ok {
+			mm = NestedMetadata{}
+			m[k] = mm
+		}
+		s, m = ss, mm
+	}
+	k := path[len(path)-1]
+	s[k] = v
+	m[k] = Metadata{Timestamp: ts}
+}
+
+// remove removes the attribute and the parent objects became empty.
+func (p *PendingState) remove(path []string) {
+	var remove func(s NestedState, m NestedMetadata, path []string)
+	remove = func(s NestedState, m NestedMetadata, path []string) {
+		k := path[0]
+		if len(path) > 1 {
+			ss, ok := s[k].(NestedState)
+			if !ok {
+				return
+			}
+			mm, _ := m[k].(NestedMetadata)
+			remove(ss, mm, path[1:])
+			if len(ss) > 0 {
+				return
+			}
+		}
+		delete(s, k)
+		delete(m, k)
+	}
+	remove(p.State, p.Metadata, path)
+}
+
+// merge sets the leaves of the given patch with the timestamp.
+func (p *PendingState) merge(patch NestedState, ts int) {
+	for _, l := range (&PendingState{State: patch}).leaves() {
+		p.set(l.path, l.value, ts)
+	}
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	pending []byte
+}
+
+// NewMemoryStore returns PendingStore which stores the state on memory.
+func NewMemoryStore() PendingStore {
+	return &memoryStore{}
+}
+
+func (s *memoryStore) Load() (*PendingState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == nil {
+		return nil, nil
+	}
+	p := &PendingState{}
+	if err := json.Unmarshal(s.pending, p); err != nil {
+		return nil, ioterr.New(err, "unmarshaling pending state")
+	}
+	return p, nil
+}
+
+func (s *memoryStore) Save(p *PendingState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p == nil {
+		s.pending = nil
+		return nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return ioterr.New(err, "marshaling pending state")
+	}
+	s.pending = b
+	return nil
+}
+
+type fileStore struct {
+	path string
+}
+
+// NewFileStore returns PendingStore which stores the state in the given file.
+// The file is synced on each save to keep the pending state on power loss.
+func NewFileStore(path string) PendingStore {
+	return &fileStore{path: path}
+}
+
+func (s *fileStore) Load() (*PendingState, error) {
+	b, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ioterr.New(err, "reading pending state")
+	}
+	p := &PendingState{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, ioterr.New(err, "unmarshaling pending state")
+	}
+	return p, nil
+}
+
+func (s *fileStore) Save(p *PendingState) error {
+	if p == nil {
+		if err := atomicfile.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return ioterr.New(err, "removing pending state")
+		}
+		return nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return ioterr.New(err, "marshaling pending state")
+	}
+	if err := atomicfile.WriteFile(s.path, b, 0600); err != nil {
+		return ioterr.New(err, "writing pending state")
+	}
+	return nil
+}
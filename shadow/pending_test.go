@@ -0,0 +1,109 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPendingState(t *testing.T) {
+	p := newPendingState()
+	p.merge(NestedState{"a": 1.0, "b": NestedState{"c": "x", "d": nil}}, 10)
+	p.merge(NestedState{"b": NestedState{"c": "y"}, "e": []interface{}{1.0}}, 20)
+
+	expected := &PendingState{
+		State: NestedState{
+			"a": 1.0,
+			"b": NestedState{"c": "y", "d": nil},
+			"e": []interface{}{1.0},
+		},
+		Metadata: NestedMetadata{
+			"a": Metadata{Timestamp: 10},
+			"b": NestedMetadata{
+				"c": Metadata{Timestamp: 20},
+				"d": Metadata{Timestamp: 10},
+			},
+			"e": Metadata{Timestamp: 20},
+		},
+	}
+	if !reflect.DeepEqual(expected, p) {
+		t.Fatalf("Expected:\n%+v\ngot:\n%+v", expected, p)
+	}
+
+	p.remove([]string{"b", "c"})
+	p.remove([]string{"b", "d"})
+	p.remove([]string{"e"})
+	expected = &PendingState{
+		State:    NestedState{"a": 1.0},
+		Metadata: NestedMetadata{"a": Metadata{Timestamp: 10}},
+	}
+	if !reflect.DeepEqual(expected, p) {
+		t.Fatalf("Expected:\n%+v\ngot:\n%+v", expected, p)
+	}
+}
+
+func TestPendingStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending.json")
+
+	testCases := map[string]PendingStore{
+		"Memory": NewMemoryStore(),
+		"File":   NewFileStore(path),
+	}
+	for name, store := range testCases {
+		store := store
+		t.Run(name, func(t *testing.T) {
+			p, err := store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p != nil {
+				t.Fatalf("Expected nil, got: %+v", p)
+			}
+
+			saved := newPendingState()
+			saved.merge(NestedState{"a": NestedState{"b": 1.0}, "c": nil}, 10)
+			if err := store.Save(saved); err != nil {
+				t.Fatal(err)
+			}
+			p, err = store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(saved, p) {
+				t.Errorf("Expected:\n%+v\ngot:\n%+v", saved, p)
+			}
+
+			if err := store.Save(nil); err != nil {
+				t.Fatal(err)
+			}
+			p, err = store.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p != nil {
+				t.Fatalf("Expected nil, got: %+v", p)
+			}
+		})
+	}
+
+	t.Run("FileRemoved", func(t *testing.T) {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("File must be removed, got: %v", err)
+		}
+	})
+}
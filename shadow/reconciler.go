@@ -0,0 +1,394 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+// ReconcileHandler applies the desired value of the attribute and returns
+// the value to be reported.
+// If the returned value is nil, the desired value is reported as is.
+type ReconcileHandler func(ctx context.Context, desired interface{}) (reported interface{}, err error)
+
+// ReconcileStatus represents a reconciliation status of the attribute.
+type ReconcileStatus string
+
+// Reconciliation statuses.
+const (
+	// ReconcilePending means that the desired value is not yet applied.
+	ReconcilePending ReconcileStatus = "pending"
+	// ReconcileFailed means that the handler or the report failed.
+	ReconcileFailed ReconcileStatus = "failed"
+	// ReconcileConverged means that the desired value is applied and reported.
+	ReconcileConverged ReconcileStatus = "converged"
+)
+
+// AttributeStatus represents a reconciliation status of the attribute path.
+type AttributeStatus struct {
+	// Path is a dot separated path to the attribute.
+	Path   string
+	Status ReconcileStatus
+	// Desired is the last desired value received by the delta.
+	Desired interface{}
+	// Attempts is a number of the failed attempts since the last delta.
+	Attempts int
+	// Err is the last error. nil unless Status is ReconcileFailed.
+	Err error
+	// NextRetry is the time of the next retry.
+	// Zero if no retry is scheduled.
+	NextRetry time.Time
+}
+
+// Reconciler is an interface of the desired state reconciler.
+// Reconciler calls the handlers of the attributes included in the delta
+// and reports the results by one Report call.
+type Reconciler interface {
+	// Handle registers the handler of the attribute path.
+	// e.g. "network.wifi.ssid" handles {"network": {"wifi": {"ssid": value}}}.
+	// If the current delta has the attribute, the handler is called immediately.
+	Handle(path string, h ReconcileHandler)
+	// Status returns reconciliation status of the registered attributes
+	// sorted by the path.
+	// Attributes which have never received the delta are not included.
+	Status() []AttributeStatus
+	// Close stops the reconciliation, removes the delta handler and
+	// waits for the running handlers to be returned.
+	// The context passed to the running handlers is canceled.
+	Close() error
+}
+
+// ReconcilerOptions stores options of Reconciler.
+type ReconcilerOptions struct {
+	// RetryPolicy controls retries of the failed handlers and reports.
+	RetryPolicy RetryPolicy
+}
+
+// ReconcilerOption is a functional option of Reconciler.
+type ReconcilerOption func(options *ReconcilerOptions)
+
+// DefaultReconcileRetryPolicy is a default retry policy of Reconciler.
+var DefaultReconcileRetryPolicy = RetryPolicy{
+	MaxRetries: 10,
+	Wait:       time.Second,
+	MaxWait:    time.Minute,
+}
+
+// WithReconcileRetryPolicy sets retry policy of the failed handlers and reports.
+func WithReconcileRetryPolicy(p RetryPolicy) ReconcilerOption {
+	return func(o *ReconcilerOptions) {
+		o.RetryPolicy = p
+	}
+}
+
+type reconcileEntry struct {
+	handler ReconcileHandler
+	status  AttributeStatus
+	// gen is incremented on each delta to detect the update during reconciliation.
+	gen      uint64
+	received bool
+}
+
+type reconcileResult struct {
+	path     string
+	gen      uint64
+	reported interface{}
+	err      error
+}
+
+type reconciler struct {
+	shadow  Shadow
+	opts    ReconcilerOptions
+	mu      sync.Mutex
+	entries map[string]*reconcileEntry
+	chKick  chan struct{}
+
+	cancel func()
+	done   chan struct{}
+}
+
+// NewReconciler creates desired state reconciler of the Shadow.
+// Reconciler overwrites the delta handler set by Shadow.OnDelta.
+// Handlers are called in background until Close is called.
+func NewReconciler(s Shadow, opt ...ReconcilerOption) Reconciler {
+	opts := ReconcilerOptions{
+		RetryPolicy: DefaultReconcileRetryPolicy,
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+	r := &reconciler{
+		shadow:  s,
+		opts:    opts,
+		entries: make(map[string]*reconcileEntry),
+		chKick:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	var ctx context.Context
+	ctx, r.cancel = context.WithCancel(context.Background())
+	s.OnDelta(r.onDelta)
+	go func() {
+		defer close(r.done)
+		r.run(ctx)
+	}()
+	return r
+}
+
+func (r *reconciler) Close() error {
+	r.shadow.OnDelta(nil)
+	r.cancel()
+	<-r.done
+	return nil
+}
+
+func (r *reconciler) Handle(path string, h ReconcileHandler) {
+	r.mu.Lock()
+	r.entries[path] = &reconcileEntry{
+		handler: h,
+		status:  AttributeStatus{Path: path},
+	}
+	r.mu.Unlock()
+
+	if doc := r.shadow.Document(); doc != nil {
+		r.onDelta(doc.State.Delta)
+	}
+}
+
+func (r *reconciler) Status() []AttributeStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ret []AttributeStatus
+	for _, e := range r.entries {
+		if e.received {
+			ret = append(ret, e.status)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Path < ret[j].Path
+	})
+	return ret
+}
+
+func (r *reconciler) onDelta(delta NestedState) {
+	updated := false
+	r.mu.Lock()
+	for path, e := range r.entries {
+		v, ok := lookupState(delta, splitPath(path))
+		if !ok {
+			continue
+		}
+		e.gen++
+		e.received = true
+		e.status = AttributeStatus{
+			Path:    path,
+			Status:  ReconcilePending,
+			Desired: v,
+		}
+		updated = true
+	}
+	r.mu.Unlock()
+
+	if updated {
+		r.kick()
+	}
+}
+
+func (r *reconciler) kick() {
+	select {
+	case r.chKick <- struct{}{}:
+	default:
+	}
+}
+
+func (r *reconciler) run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		next := r.reconcile(ctx)
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		var chTimer <-chan time.Time
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+			chTimer = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.chKick:
+		case <-chTimer:
+		}
+	}
+}
+
+// reconcile calls the handlers of the due attributes and reports the results.
+// It returns the time of the next retry.
+func (r *reconciler) reconcile(ctx context.Context) time.Time {
+	type task struct {
+		path    string
+		gen     uint64
+		desired interface{}
+		handler ReconcileHandler
+	}
+	now := time.Now()
+	var tasks []task
+	r.mu.Lock()
+	for path, e := range r.entries {
+		switch e.status.Status {
+		case ReconcilePending:
+		case ReconcileFailed:
+			if e.status.NextRetry.IsZero() || e.status.NextRetry.After(now) {
+				continue
+			}
+		default:
+			continue
+		}
+		tasks = append(tasks, task{
+			path:    path,
+			gen:     e.gen,
+			desired: e.status.Desired,
+			handler: e.handler,
+		})
+	}
+	r.mu.Unlock()
+
+	if len(tasks) == 0 {
+		return r.nextRetry()
+	}
+	// Parent attributes are processed first to allow overwriting
+	// children by the handlers of the nested paths.
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].path < tasks[j].path
+	})
+
+	results := make([]reconcileResult, 0, len(tasks))
+	reported := NestedState{}
+	for _, t := range tasks {
+		res := reconcileResult{path: t.path, gen: t.gen}
+		res.reported, res.err = t.handler(ctx, t.desired)
+		if res.err != nil {
+			res.err = ioterr.Newf(res.err, "reconciling %s", pathName(t.path))
+		} else {
+			if res.reported == nil {
+				res.reported = t.desired
+			}
+			setState(reported, splitPath(t.path), res.reported)
+		}
+		results = append(results, res)
+	}
+	if len(reported) > 0 {
+		if _, err := r.shadow.Report(ctx, reported); err != nil {
+			err = ioterr.New(err, "reporting reconciled state")
+			for i := range results {
+				if results[i].err == nil {
+					results[i].err = err
+				}
+			}
+		}
+	}
+
+	now = time.Now()
+	r.mu.Lock()
+	for _, res := range results {
+		e, ok := r.entries[res.path]
+		if !ok || e.gen != res.gen {
+			// Handler is replaced or new delta is received during reconciliation.
+			continue
+		}
+		if res.err == nil {
+			e.status.Status = ReconcileConverged
+			e.status.Attempts = 0
+			e.status.Err = nil
+			e.status.NextRetry = time.Time{}
+			continue
+		}
+		e.status.Status = ReconcileFailed
+		e.status.Err = res.err
+		e.status.NextRetry = time.Time{}
+		if e.status.Attempts < r.opts.RetryPolicy.MaxRetries {
+			e.status.NextRetry = now.Add(r.opts.RetryPolicy.wait(e.status.Attempts))
+		}
+		e.status.Attempts++
+	}
+	r.mu.Unlock()
+
+	return r.nextRetry()
+}
+
+func (r *reconciler) nextRetry() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var next time.Time
+	for _, e := range r.entries {
+		switch e.status.Status {
+		case ReconcilePending:
+			return time.Now()
+		case ReconcileFailed:
+			t := e.status.NextRetry
+			if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+				next = t
+			}
+		}
+	}
+	return next
+}
+
+// lookupState returns the value at the path.
+func lookupState(s map[string]interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return nil, false
+	}
+	v, ok := s[path[0]]
+	if !ok {
+		return nil, false
+	}
+	if len(path) == 1 {
+		return v, true
+	}
+	m, ok := asStateMap(v)
+	if !ok {
+		return nil, false
+	}
+	return lookupState(m, path[1:])
+}
+
+// setState sets the value at the path.
+// Intermediate objects are created or copied to avoid modifying
+// the values returned by the handlers.
+func setState(s NestedState, path []string, v interface{}) {
+	if len(path) == 1 {
+		s[path[0]] = v
+		return
+	}
+	var child NestedState
+	if m, ok := asStateMap(s[path[0]]); ok {
+		child = NestedState(cloneState(m))
+	} else {
+		child = NestedState{}
+	}
+	setState(child, path[1:], v)
+	s[path[0]] = child
+}
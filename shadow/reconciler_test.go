@@ -0,0 +1,295 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type reconcileStubShadow struct {
+	Shadow
+	mu        sync.Mutex
+	doc       *ThingDocument
+	onDelta   func(NestedState)
+	reportErr error
+	chReport  chan interface{}
+}
+
+func (s *reconcileStubShadow) Report(ctx context.Context, state interface{}, opt ...UpdateOption) (*ThingDocument, error) {
+	s.mu.Lock()
+	err := s.reportErr
+	s.mu.Unlock()
+	s.chReport <- state
+	return nil, err
+}
+
+func (s *reconcileStubShadow) Document() *ThingDocument {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.doc
+}
+
+func (s *reconcileStubShadow) OnDelta(cb func(NestedState)) {
+	s.mu.Lock()
+	s.onDelta = cb
+	s.mu.Unlock()
+}
+
+func (s *reconcileStubShadow) delta(d NestedState) {
+	s.mu.Lock()
+	cb := s.onDelta
+	s.mu.Unlock()
+	cb(d)
+}
+
+func newReconcileStubShadow() *reconcileStubShadow {
+	return &reconcileStubShadow{chReport: make(chan interface{}, 10)}
+}
+
+func waitReport(t *testing.T, ch chan interface{}) interface{} {
+	t.Helper()
+	select {
+	case r := <-ch:
+		return r
+	case <-time.After(time.Second):
+		t.Fatal("Timeout")
+	}
+	return nil
+}
+
+func waitStatus(t *testing.T, r Reconciler, expected []AttributeStatus) {
+	t.Helper()
+	var st []AttributeStatus
+	for i := 0; i < 100; i++ {
+		st = r.Status()
+		for j := range st {
+			st[j].NextRetry = time.Time{}
+			if st[j].Err != nil {
+				st[j].Err = errDummy
+			}
+		}
+		if reflect.DeepEqual(expected, st) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Expected status:\n%+v\ngot:\n%+v", expected, st)
+}
+
+var errDummy = errors.New("dummy")
+
+func TestReconciler(t *testing.T) {
+	t.Run("Batch", func(t *testing.T) {
+		s := newReconcileStubShadow()
+		r := NewReconciler(s)
+		defer r.Close()
+
+		var mu sync.Mutex
+		var called []string
+		handler := func(name string, reported interface{}) ReconcileHandler {
+			return func(ctx context.Context, desired interface{}) (interface{}, error) {
+				mu.Lock()
+				called = append(called, name)
+				mu.Unlock()
+				return reported, nil
+			}
+		}
+		r.Handle("network.wifi.ssid", handler("ssid", nil))
+		r.Handle("network.wifi.channel", handler("channel", float64(6)))
+		r.Handle("led", handler("led", nil))
+
+		s.delta(NestedState{
+			"network": map[string]interface{}{
+				"wifi": map[string]interface{}{
+					"ssid":    "home",
+					"channel": float64(0),
+				},
+			},
+			"unknown": "value",
+		})
+
+		expected := NestedState{
+			"network": NestedState{
+				"wifi": NestedState{
+					"ssid":    "home",
+					"channel": float64(6),
+				},
+			},
+		}
+		if rep := waitReport(t, s.chReport); !reflect.DeepEqual(expected, rep) {
+			t.Errorf("Expected report:\n%+v\ngot:\n%+v", expected, rep)
+		}
+		waitStatus(t, r, []AttributeStatus{
+			{Path: "network.wifi.channel", Status: ReconcileConverged, Desired: float64(0)},
+			{Path: "network.wifi.ssid", Status: ReconcileConverged, Desired: "home"},
+		})
+		mu.Lock()
+		if expectedCalled := []string{"channel", "ssid"}; !reflect.DeepEqual(expectedCalled, called) {
+			t.Errorf("Expected handlers %v to be called, got %v", expectedCalled, called)
+		}
+		mu.Unlock()
+	})
+
+	t.Run("InitialDelta", func(t *testing.T) {
+		s := newReconcileStubShadow()
+		s.doc = &ThingDocument{
+			State: ThingState{Delta: NestedState{"led": true}},
+		}
+		r := NewReconciler(s)
+		defer r.Close()
+		r.Handle("led", func(ctx context.Context, desired interface{}) (interface{}, error) {
+			return desired, nil
+		})
+
+		expected := NestedState{"led": true}
+		if rep := waitReport(t, s.chReport); !reflect.DeepEqual(expected, rep) {
+			t.Errorf("Expected report:\n%+v\ngot:\n%+v", expected, rep)
+		}
+	})
+
+	t.Run("RetryHandler", func(t *testing.T) {
+		s := newReconcileStubShadow()
+		r := NewReconciler(s,
+			WithReconcileRetryPolicy(RetryPolicy{
+				MaxRetries: 2,
+				Wait:       10 * time.Millisecond,
+			}),
+		)
+		defer r.Close()
+		var mu sync.Mutex
+		var cnt int
+		r.Handle("led", func(ctx context.Context, desired interface{}) (interface{}, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			cnt++
+			if cnt < 3 {
+				return nil, errDummy
+			}
+			return nil, nil
+		})
+		s.delta(NestedState{"led": true})
+
+		expected := NestedState{"led": true}
+		if rep := waitReport(t, s.chReport); !reflect.DeepEqual(expected, rep) {
+			t.Errorf("Expected report:\n%+v\ngot:\n%+v", expected, rep)
+		}
+		waitStatus(t, r, []AttributeStatus{
+			{Path: "led", Status: ReconcileConverged, Desired: true},
+		})
+		mu.Lock()
+		if cnt != 3 {
+			t.Errorf("Expected 3 calls, got %d", cnt)
+		}
+		mu.Unlock()
+	})
+
+	t.Run("RetryExhausted", func(t *testing.T) {
+		s := newReconcileStubShadow()
+		r := NewReconciler(s,
+			WithReconcileRetryPolicy(RetryPolicy{
+				MaxRetries: 1,
+				Wait:       10 * time.Millisecond,
+			}),
+		)
+		defer r.Close()
+		r.Handle("led", func(ctx context.Context, desired interface{}) (interface{}, error) {
+			return nil, errDummy
+		})
+		s.delta(NestedState{"led": true})
+
+		waitStatus(t, r, []AttributeStatus{
+			{Path: "led", Status: ReconcileFailed, Desired: true, Attempts: 2, Err: errDummy},
+		})
+		st := r.Status()
+		if !errors.Is(st[0].Err, errDummy) {
+			t.Errorf("Expected error: %v, got: %v", errDummy, st[0].Err)
+		}
+		if !st[0].NextRetry.IsZero() {
+			t.Errorf("Retry must not be scheduled after exhausted, got: %v", st[0].NextRetry)
+		}
+		select {
+		case rep := <-s.chReport:
+			t.Errorf("Unexpected report: %v", rep)
+		default:
+		}
+	})
+
+	t.Run("RetryReport", func(t *testing.T) {
+		s := newReconcileStubShadow()
+		s.reportErr = errDummy
+		r := NewReconciler(s,
+			WithReconcileRetryPolicy(RetryPolicy{
+				MaxRetries: 3,
+				Wait:       10 * time.Millisecond,
+			}),
+		)
+		defer r.Close()
+		r.Handle("led", func(ctx context.Context, desired interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		s.delta(NestedState{"led": true})
+
+		waitReport(t, s.chReport)
+		waitStatus(t, r, []AttributeStatus{
+			{Path: "led", Status: ReconcileFailed, Desired: true, Attempts: 1, Err: errDummy},
+		})
+		s.mu.Lock()
+		s.reportErr = nil
+		s.mu.Unlock()
+
+		waitReport(t, s.chReport)
+		waitStatus(t, r, []AttributeStatus{
+			{Path: "led", Status: ReconcileConverged, Desired: true},
+		})
+	})
+	t.Run("Close", func(t *testing.T) {
+		s := newReconcileStubShadow()
+		r := NewReconciler(s)
+
+		chStarted := make(chan struct{})
+		chCanceled := make(chan struct{})
+		r.Handle("led", func(ctx context.Context, desired interface{}) (interface{}, error) {
+			close(chStarted)
+			<-ctx.Done()
+			close(chCanceled)
+			return nil, ctx.Err()
+		})
+		s.delta(NestedState{"led": true})
+
+		select {
+		case <-chStarted:
+		case <-time.After(time.Second):
+			t.Fatal("Timeout")
+		}
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-chCanceled:
+		default:
+			t.Error("Close must wait for the running handler")
+		}
+		s.mu.Lock()
+		if s.onDelta != nil {
+			t.Error("Delta handler must be removed")
+		}
+		s.mu.Unlock()
+	})
+}
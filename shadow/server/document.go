@@ -0,0 +1,233 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"reflect"
+)
+
+type state map[string]interface{}
+
+type document struct {
+	desired          state
+	reported         state
+	desiredMetadata  state
+	reportedMetadata state
+	version          int
+	timestamp        int
+}
+
+type stateSection struct {
+	Desired  state `json:"desired,omitempty"`
+	Reported state `json:"reported,omitempty"`
+	Delta    state `json:"delta,omitempty"`
+}
+
+type documentJSON struct {
+	State       stateSection `json:"state"`
+	Metadata    stateSection `json:"metadata"`
+	Version     int          `json:"version"`
+	Timestamp   int          `json:"timestamp,omitempty"`
+	ClientToken string       `json:"clientToken,omitempty"`
+}
+
+func newDocument() *document {
+	return &document{
+		desired:          state{},
+		reported:         state{},
+		desiredMetadata:  state{},
+		reportedMetadata: state{},
+	}
+}
+
+func (d *document) clone() *document {
+	c := *d
+	c.desired = cloneValue(d.desired).(state)
+	c.reported = cloneValue(d.reported).(state)
+	c.desiredMetadata = cloneValue(d.desiredMetadata).(state)
+	c.reportedMetadata = cloneValue(d.reportedMetadata).(state)
+	return &c
+}
+
+// json returns document in the format of get/accepted response.
+func (d *document) json() *documentJSON {
+	delta := deltaState(d.desired, d.reported)
+	return &documentJSON{
+		State: stateSection{
+			Desired:  d.desired,
+			Reported: d.reported,
+			Delta:    delta,
+		},
+		Metadata: stateSection{
+			Desired:  d.desiredMetadata,
+			Reported: d.reportedMetadata,
+		},
+		Version:   d.version,
+		Timestamp: d.timestamp,
+	}
+}
+
+// documentsJSON returns document in the format of update/documents message.
+func (d *document) documentsJSON() *documentJSON {
+	j := d.json()
+	j.State.Delta = nil
+	j.Timestamp = 0
+	return j
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case state:
+		c := make(state, len(vv))
+		for k, e := range vv {
+			c[k] = cloneValue(e)
+		}
+		return c
+	case map[string]interface{}:
+		c := make(state, len(vv))
+		for k, e := range vv {
+			c[k] = cloneValue(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(vv))
+		for i, e := range vv {
+			c[i] = cloneValue(e)
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+func asState(v interface{}) (state, bool) {
+	switch vv := v.(type) {
+	case state:
+		return vv, true
+	case map[string]interface{}:
+		return vv, true
+	}
+	return nil, false
+}
+
+// merge applies the update to the state.
+// null removes the attribute and objects are merged recursively.
+// Objects became empty are removed.
+func merge(s, meta, update state, ts int) {
+	for k, v := range update {
+		if v == nil {
+			delete(s, k)
+			delete(meta, k)
+			continue
+		}
+		u, ok := asState(v)
+		if !ok {
+			s[k] = cloneValue(v)
+			meta[k] = metadataOf(v, ts)
+			continue
+		}
+		child, ok := asState(s[k])
+		if !ok {
+			child = state{}
+			s[k] = child
+			meta[k] = state{}
+		}
+		childMeta, _ := asState(meta[k])
+		merge(child, childMeta, u, ts)
+		if len(child) == 0 && len(u) != 0 {
+			delete(s, k)
+			delete(meta, k)
+		}
+	}
+}
+
+// metadataOf returns metadata of the value updated at the timestamp.
+func metadataOf(v interface{}, ts int) interface{} {
+	if s, ok := asState(v); ok {
+		m := make(state, len(s))
+		for k, e := range s {
+			m[k] = metadataOf(e, ts)
+		}
+		return m
+	}
+	if a, ok := v.([]interface{}); ok {
+		m := make([]interface{}, len(a))
+		for i, e := range a {
+			m[i] = metadataOf(e, ts)
+		}
+		return m
+	}
+	return state{"timestamp": ts}
+}
+
+// deltaState returns desired attributes differ from reported.
+func deltaState(desired, reported state) state {
+	delta := state{}
+	for k, d := range desired {
+		r, ok := reported[k]
+		if !ok {
+			delta[k] = d
+			continue
+		}
+		ds, dok := asState(d)
+		rs, rok := asState(r)
+		if dok && rok {
+			if dd := deltaState(ds, rs); len(dd) > 0 {
+				delta[k] = dd
+			}
+			continue
+		}
+		if !reflect.DeepEqual(d, r) {
+			delta[k] = d
+		}
+	}
+	return delta
+}
+
+// filterState returns the attributes of s which are also in the filter.
+func filterState(s, filter state) state {
+	out := state{}
+	for k, f := range filter {
+		v, ok := s[k]
+		if !ok {
+			continue
+		}
+		fs, fok := asState(f)
+		vs, vok := asState(v)
+		if fok && vok && len(fs) > 0 {
+			if c := filterState(vs, fs); len(c) > 0 {
+				out[k] = c
+			}
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// depth returns the nesting level of the objects.
+func depth(v interface{}) int {
+	s, ok := asState(v)
+	if !ok {
+		return 0
+	}
+	max := 0
+	for _, e := range s {
+		if d := depth(e); d > max {
+			max = d
+		}
+	}
+	return max + 1
+}
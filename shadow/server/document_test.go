@@ -0,0 +1,85 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	testCases := map[string]struct {
+		base, update     state
+		expected, exMeta state
+	}{
+		"Add": {
+			base:     state{"a": 1.0},
+			update:   state{"b": state{"c": []interface{}{1.0}}},
+			expected: state{"a": 1.0, "b": state{"c": []interface{}{1.0}}},
+			exMeta: state{
+				"a": state{"timestamp": 1},
+				"b": state{"c": []interface{}{state{"timestamp": 2}}},
+			},
+		},
+		"Replace": {
+			base:     state{"a": 1.0},
+			update:   state{"a": state{"b": 1.0}},
+			expected: state{"a": state{"b": 1.0}},
+			exMeta:   state{"a": state{"b": state{"timestamp": 2}}},
+		},
+		"Delete": {
+			base:     state{"a": 1.0, "b": state{"c": 1.0}},
+			update:   state{"a": nil, "b": state{"c": nil}},
+			expected: state{},
+			exMeta:   state{},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			s, meta := state{}, state{}
+			merge(s, meta, tt.base, 1)
+			merge(s, meta, tt.update, 2)
+			if !reflect.DeepEqual(tt.expected, s) {
+				t.Errorf("Expected state: %v, got: %v", tt.expected, s)
+			}
+			if !reflect.DeepEqual(tt.exMeta, meta) {
+				t.Errorf("Expected metadata: %v, got: %v", tt.exMeta, meta)
+			}
+		})
+	}
+}
+
+func TestDeltaState(t *testing.T) {
+	desired := state{
+		"a": 1.0,
+		"b": state{"c": 1.0, "d": 2.0},
+		"e": []interface{}{1.0},
+		"f": "same",
+	}
+	reported := state{
+		"a": 2.0,
+		"b": state{"c": 1.0},
+		"e": []interface{}{1.0},
+		"f": "same",
+	}
+	expected := state{
+		"a": 1.0,
+		"b": state{"d": 2.0},
+	}
+	if delta := deltaState(desired, reported); !reflect.DeepEqual(expected, delta) {
+		t.Errorf("Expected delta: %v, got: %v", expected, delta)
+	}
+}
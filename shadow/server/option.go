@@ -0,0 +1,55 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+)
+
+// Option is a functional option of Server.
+type Option func(*Server)
+
+// WithClock sets the function to get current time.
+// It is used to fill the timestamps of the documents.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// WithMaxPayloadSize sets maximum size of the request payload.
+// Larger requests are rejected with 413.
+func WithMaxPayloadSize(size int) Option {
+	return func(s *Server) {
+		s.maxPayloadSize = size
+	}
+}
+
+// WithMaxDocumentSize sets maximum size of the desired and reported state
+// of the document.
+// Updates exceeding the size are rejected with 413.
+func WithMaxDocumentSize(size int) Option {
+	return func(s *Server) {
+		s.maxDocumentSize = size
+	}
+}
+
+// WithMaxDepth sets maximum nesting level of the desired and reported state.
+// Updates exceeding the depth are rejected with 400.
+func WithMaxDepth(depth int) Option {
+	return func(s *Server) {
+		s.maxDepth = depth
+	}
+}
@@ -0,0 +1,405 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package server implements AWS IoT Device Shadow service emulator
+// for testing and offline development.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/at-wat/mqtt-go"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/shadow"
+)
+
+const (
+	defaultMaxPayloadSize  = 128 * 1024
+	defaultMaxDocumentSize = 8 * 1024
+	defaultMaxDepth        = 6
+)
+
+type shadowKey struct {
+	thingName  string
+	shadowName string
+}
+
+// Server emulates AWS IoT Device Shadow service over MQTT.
+// Server must be registered to the MQTT client as a message handler.
+type Server struct {
+	cli             mqtt.Client
+	now             func() time.Time
+	maxPayloadSize  int
+	maxDocumentSize int
+	maxDepth        int
+
+	mu       sync.Mutex
+	docs     map[shadowKey]*document
+	versions map[shadowKey]int
+
+	ctx   context.Context
+	chPub chan []*mqtt.Message
+}
+
+// New creates Device Shadow service emulator and subscribes the request
+// topics of the classic and named shadows of all things.
+// Responses are published in background until the context is canceled.
+func New(ctx context.Context, cli mqtt.Client, opts ...Option) (*Server, error) {
+	s := &Server{
+		ctx:             ctx,
+		chPub:           make(chan []*mqtt.Message, 64),
+		cli:             cli,
+		now:             time.Now,
+		maxPayloadSize:  defaultMaxPayloadSize,
+		maxDocumentSize: defaultMaxDocumentSize,
+		maxDepth:        defaultMaxDepth,
+		docs:            make(map[shadowKey]*document),
+		versions:        make(map[shadowKey]int),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	if _, err := cli.Subscribe(ctx,
+		mqtt.Subscription{Topic: "$aws/things/+/shadow/+", QoS: mqtt.QoS1},
+		mqtt.Subscription{Topic: "$aws/things/+/shadow/name/+/+", QoS: mqtt.QoS1},
+	); err != nil {
+		return nil, ioterr.New(err, "subscribing shadow topics")
+	}
+	go s.publish()
+	return s, nil
+}
+
+// publish publishes the responses in order.
+// Messages are published outside of the message handler to avoid blocking
+// the MQTT client.
+func (s *Server) publish() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case msgs := <-s.chPub:
+			for _, m := range msgs {
+				if err := s.cli.Publish(s.ctx, m); err != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Document returns the document of the shadow.
+// Empty shadowName means the classic shadow.
+func (s *Server) Document(thingName, shadowName string) (*shadow.ThingDocument, bool) {
+	s.mu.Lock()
+	d, ok := s.docs[shadowKey{thingName, shadowName}]
+	var b []byte
+	var err error
+	if ok {
+		b, err = json.Marshal(d.json())
+	}
+	s.mu.Unlock()
+	if !ok || err != nil {
+		return nil, false
+	}
+	doc := &shadow.ThingDocument{}
+	if err := json.Unmarshal(b, doc); err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+// parseTopic parses request topic.
+// Returns prefix of the response topics, shadow key and operation.
+func parseTopic(topic string) (string, shadowKey, string, bool) {
+	t := strings.Split(topic, "/")
+	switch {
+	case len(t) == 5 && t[0] == "$aws" && t[1] == "things" && t[3] == "shadow":
+		return strings.Join(t[:4], "/"), shadowKey{thingName: t[2]}, t[4], true
+	case len(t) == 7 && t[0] == "$aws" && t[1] == "things" && t[3] == "shadow" && t[4] == "name":
+		return strings.Join(t[:6], "/"), shadowKey{thingName: t[2], shadowName: t[5]}, t[6], true
+	}
+	return "", shadowKey{}, "", false
+}
+
+// Serve implements mqtt.Handler.
+func (s *Server) Serve(msg *mqtt.Message) {
+	prefix, key, op, ok := parseTopic(msg.Topic)
+	if !ok {
+		return
+	}
+	var msgs []*mqtt.Message
+	switch op {
+	case "get":
+		msgs = s.get(prefix, key, msg.Payload)
+	case "update":
+		msgs = s.update(prefix, key, msg.Payload)
+	case "delete":
+		msgs = s.delete(prefix, key, msg.Payload)
+	default:
+		return
+	}
+	select {
+	case s.chPub <- msgs:
+	case <-s.ctx.Done():
+	}
+}
+
+type errorResponse struct {
+	Code        int    `json:"code"`
+	Message     string `json:"message"`
+	Timestamp   int    `json:"timestamp"`
+	ClientToken string `json:"clientToken,omitempty"`
+}
+
+type request struct {
+	State       *json.RawMessage `json:"state"`
+	Version     int              `json:"version"`
+	ClientToken string           `json:"clientToken"`
+}
+
+type requestState struct {
+	Desired  json.RawMessage `json:"desired"`
+	Reported json.RawMessage `json:"reported"`
+}
+
+func (s *Server) message(topic string, v interface{}) *mqtt.Message {
+	b, err := json.Marshal(v)
+	if err != nil {
+		// Unreachable since all values are unmarshaled from JSON.
+		panic(err)
+	}
+	return &mqtt.Message{Topic: topic, QoS: mqtt.QoS1, Payload: b}
+}
+
+func (s *Server) rejected(prefix, op string, code int, msg, token string) []*mqtt.Message {
+	return []*mqtt.Message{
+		s.message(prefix+"/"+op+"/rejected", &errorResponse{
+			Code:        code,
+			Message:     msg,
+			Timestamp:   int(s.now().Unix()),
+			ClientToken: token,
+		}),
+	}
+}
+
+func notFound(key shadowKey) string {
+	return fmt.Sprintf("No shadow exists with name: '%s'", key.thingName)
+}
+
+func (s *Server) get(prefix string, key shadowKey, payload []byte) []*mqtt.Message {
+	req := &request{}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, req); err != nil {
+			return s.rejected(prefix, "get", http.StatusBadRequest, "Invalid JSON", "")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.docs[key]
+	if !ok {
+		return s.rejected(prefix, "get", http.StatusNotFound, notFound(key), req.ClientToken)
+	}
+	res := d.json()
+	res.ClientToken = req.ClientToken
+	return []*mqtt.Message{s.message(prefix+"/get/accepted", res)}
+}
+
+func (s *Server) delete(prefix string, key shadowKey, payload []byte) []*mqtt.Message {
+	req := &request{}
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, req); err != nil {
+			return s.rejected(prefix, "delete", http.StatusBadRequest, "Invalid JSON", "")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.docs[key]
+	if !ok {
+		return s.rejected(prefix, "delete", http.StatusNotFound, notFound(key), req.ClientToken)
+	}
+	if req.Version != 0 && req.Version != d.version {
+		return s.rejected(prefix, "delete", http.StatusConflict, "Version conflict", req.ClientToken)
+	}
+	delete(s.docs, key)
+	// Version number is not reset by deletion.
+	s.versions[key] = d.version
+	return []*mqtt.Message{
+		s.message(prefix+"/delete/accepted", &struct {
+			Version     int    `json:"version"`
+			Timestamp   int    `json:"timestamp"`
+			ClientToken string `json:"clientToken,omitempty"`
+		}{
+			Version:     d.version,
+			Timestamp:   int(s.now().Unix()),
+			ClientToken: req.ClientToken,
+		}),
+	}
+}
+
+// parseSection parses desired or reported section of the update request.
+// Returns nil state if the section is null.
+func parseSection(b json.RawMessage) (state, bool, error) {
+	if len(b) == 0 {
+		return nil, false, nil
+	}
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		return nil, true, nil
+	}
+	var st state
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, false, err
+	}
+	return st, true, nil
+}
+
+func (s *Server) update(prefix string, key shadowKey, payload []byte) []*mqtt.Message {
+	if len(payload) > s.maxPayloadSize {
+		return s.rejected(prefix, "update", http.StatusRequestEntityTooLarge, "The payload exceeds the maximum size allowed", "")
+	}
+	req := &request{}
+	if err := json.Unmarshal(payload, req); err != nil {
+		return s.rejected(prefix, "update", http.StatusBadRequest, "Invalid JSON", "")
+	}
+	if req.State == nil {
+		return s.rejected(prefix, "update", http.StatusBadRequest, "Missing required node: state", req.ClientToken)
+	}
+	rs := &requestState{}
+	if err := json.Unmarshal(*req.State, rs); err != nil {
+		return s.rejected(prefix, "update", http.StatusBadRequest, "State node must be an object", req.ClientToken)
+	}
+	desired, hasDesired, errDesired := parseSection(rs.Desired)
+	reported, hasReported, errReported := parseSection(rs.Reported)
+	if errDesired != nil || errReported != nil {
+		return s.rejected(prefix, "update", http.StatusBadRequest, "Desired and reported nodes must be objects", req.ClientToken)
+	}
+	if !hasDesired && !hasReported {
+		return s.rejected(prefix, "update", http.StatusBadRequest, "State contains an invalid node", req.ClientToken)
+	}
+	if depth(desired) > s.maxDepth || depth(reported) > s.maxDepth {
+		return s.rejected(prefix, "update", http.StatusBadRequest,
+			fmt.Sprintf("JSON contains too many levels of nesting; maximum is %d", s.maxDepth), req.ClientToken,
+		)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, exists := s.docs[key]
+	var d *document
+	if exists {
+		if req.Version != 0 && req.Version != prev.version {
+			return s.rejected(prefix, "update", http.StatusConflict, "Version conflict", req.ClientToken)
+		}
+		d = prev.clone()
+	} else {
+		d = newDocument()
+		d.version = s.versions[key]
+	}
+
+	ts := int(s.now().Unix())
+	if hasDesired {
+		if desired == nil {
+			d.desired, d.desiredMetadata = state{}, state{}
+		} else {
+			merge(d.desired, d.desiredMetadata, desired, ts)
+		}
+	}
+	if hasReported {
+		if reported == nil {
+			d.reported, d.reportedMetadata = state{}, state{}
+		} else {
+			merge(d.reported, d.reportedMetadata, reported, ts)
+		}
+	}
+	b, err := json.Marshal(&stateSection{Desired: d.desired, Reported: d.reported})
+	if err != nil || len(b) > s.maxDocumentSize {
+		return s.rejected(prefix, "update", http.StatusRequestEntityTooLarge, "The payload exceeds the maximum size allowed", req.ClientToken)
+	}
+	d.version++
+	d.timestamp = ts
+	s.docs[key] = d
+
+	accepted := &struct {
+		State       map[string]state `json:"state"`
+		Metadata    map[string]state `json:"metadata"`
+		Version     int              `json:"version"`
+		Timestamp   int              `json:"timestamp"`
+		ClientToken string           `json:"clientToken,omitempty"`
+	}{
+		State:       make(map[string]state),
+		Metadata:    make(map[string]state),
+		Version:     d.version,
+		Timestamp:   ts,
+		ClientToken: req.ClientToken,
+	}
+	if hasDesired {
+		// Null section is kept as null.
+		accepted.State["desired"] = desired
+		if desired != nil {
+			accepted.Metadata["desired"] = metadataOf(desired, ts).(state)
+		}
+	}
+	if hasReported {
+		accepted.State["reported"] = reported
+		if reported != nil {
+			accepted.Metadata["reported"] = metadataOf(reported, ts).(state)
+		}
+	}
+	msgs := []*mqtt.Message{s.message(prefix+"/update/accepted", accepted)}
+
+	if len(desired) > 0 {
+		delta := filterState(deltaState(d.desired, d.reported), desired)
+		if len(delta) > 0 {
+			msgs = append(msgs, s.message(prefix+"/update/delta", &struct {
+				State       state  `json:"state"`
+				Metadata    state  `json:"metadata"`
+				Version     int    `json:"version"`
+				Timestamp   int    `json:"timestamp"`
+				ClientToken string `json:"clientToken,omitempty"`
+			}{
+				State:       delta,
+				Metadata:    filterState(d.desiredMetadata, delta),
+				Version:     d.version,
+				Timestamp:   ts,
+				ClientToken: req.ClientToken,
+			}))
+		}
+	}
+
+	docs := &struct {
+		Previous    *documentJSON `json:"previous,omitempty"`
+		Current     *documentJSON `json:"current"`
+		Timestamp   int           `json:"timestamp"`
+		ClientToken string        `json:"clientToken,omitempty"`
+	}{
+		Current:     d.documentsJSON(),
+		Timestamp:   ts,
+		ClientToken: req.ClientToken,
+	}
+	if exists {
+		docs.Previous = prev.documentsJSON()
+	}
+	msgs = append(msgs, s.message(prefix+"/update/documents", docs))
+	return msgs
+}
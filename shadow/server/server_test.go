@@ -0,0 +1,256 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/at-wat/mqtt-go"
+	mockmqtt "github.com/at-wat/mqtt-go/mock"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/shadow"
+)
+
+type mockClient interface {
+	mqtt.Client
+	mqtt.Handler
+}
+
+type mockDevice struct {
+	mockClient
+	mqtt.Retryer
+}
+
+func (d *mockDevice) ThingName() string {
+	return "test"
+}
+
+// newTestServer connects the server and the device by mock MQTT clients.
+func newTestServer(ctx context.Context, t *testing.T, opts ...Option) (*Server, *mockDevice) {
+	var srv *Server
+	cliSrv := &mockmqtt.Client{}
+	cliDev := &mockDevice{
+		mockClient: &mockmqtt.Client{
+			PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+				srv.Serve(msg)
+				return nil
+			},
+		},
+	}
+	cliSrv.PublishFn = func(ctx context.Context, msg *mqtt.Message) error {
+		cliDev.Serve(msg)
+		return nil
+	}
+	var err error
+	srv, err = New(ctx, cliSrv, append([]Option{
+		WithClock(func() time.Time { return time.Unix(1000, 0) }),
+	}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, cliDev
+}
+
+func TestServer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv, cli := newTestServer(ctx, t)
+	s, err := shadow.New(ctx, cli, shadow.WithIncrementalUpdate(false), shadow.WithDocuments())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.Handle(s)
+
+	chDelta := make(chan shadow.NestedState, 10)
+	s.OnDelta(func(delta shadow.NestedState) { chDelta <- delta })
+	chDocs := make(chan *shadow.ThingDocument, 10)
+	s.OnDocuments(func(prev, cur *shadow.ThingDocument) { chDocs <- prev })
+
+	t.Run("GetNotFound", func(t *testing.T) {
+		_, err := s.Get(ctx)
+		var e *shadow.ErrorResponse
+		if !errors.As(err, &e) || e.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 error, got: %v", err)
+		}
+	})
+	t.Run("Report", func(t *testing.T) {
+		doc, err := s.Report(ctx, map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Version != 1 {
+			t.Errorf("Expected version: 1, got: %d", doc.Version)
+		}
+		if prev := <-chDocs; prev != nil {
+			t.Errorf("Expected no previous document, got: %+v", prev)
+		}
+	})
+	t.Run("Desire", func(t *testing.T) {
+		if _, err := s.Desire(ctx, map[string]interface{}{"a": 2, "b": map[string]interface{}{"c": 1}}); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case delta := <-chDelta:
+			expected := shadow.NestedState{"a": 2.0}
+			if !reflect.DeepEqual(expected, delta) {
+				t.Errorf("Expected delta: %v, got: %v", expected, delta)
+			}
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		}
+		if prev := <-chDocs; prev == nil || prev.Version != 1 {
+			t.Errorf("Expected previous document of version 1, got: %+v", prev)
+		}
+	})
+	t.Run("DeleteAttribute", func(t *testing.T) {
+		if _, err := s.Report(ctx, map[string]interface{}{"b": nil}); err != nil {
+			t.Fatal(err)
+		}
+		<-chDocs
+		doc, err := s.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-chDelta
+		expected := &shadow.ThingDocument{
+			State: shadow.ThingState{
+				Desired:  shadow.NestedState{"a": 2.0, "b": shadow.NestedState{"c": 1.0}},
+				Reported: shadow.NestedState{"a": 1.0},
+				Delta:    shadow.NestedState{"a": 2.0, "b": shadow.NestedState{"c": 1.0}},
+			},
+			Metadata: shadow.ThingStateMetadata{
+				Desired: shadow.NestedMetadata{
+					"a": shadow.Metadata{Timestamp: 1000},
+					"b": shadow.NestedMetadata{"c": shadow.Metadata{Timestamp: 1000}},
+				},
+				Reported: shadow.NestedMetadata{"a": shadow.Metadata{Timestamp: 1000}},
+				// Get response doesn't have delta metadata.
+				// Client keeps the one received on the last delta message.
+				Delta: shadow.NestedMetadata{"a": shadow.Metadata{Timestamp: 1000}},
+			},
+			Version:   3,
+			Timestamp: 1000,
+		}
+		if !reflect.DeepEqual(expected, doc) {
+			t.Errorf("Expected document:\n%+v\ngot:\n%+v", expected, doc)
+		}
+		if d, ok := srv.Document("test", ""); !ok || !reflect.DeepEqual(expected.State, d.State) {
+			t.Errorf("Expected server document:\n%+v\ngot:\n%+v", expected.State, d)
+		}
+	})
+	t.Run("VersionConflict", func(t *testing.T) {
+		_, err := s.Report(ctx, map[string]interface{}{"a": 3}, shadow.WithExpectedVersion(1))
+		if !errors.Is(err, shadow.ErrVersionConflict) {
+			t.Errorf("Expected error: %v, got: %v", shadow.ErrVersionConflict, err)
+		}
+	})
+	t.Run("Delete", func(t *testing.T) {
+		if err := s.Delete(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := srv.Document("test", ""); ok {
+			t.Error("Document must be deleted")
+		}
+		if err := s.Delete(ctx); err == nil {
+			t.Error("Deleting non-existent shadow must fail")
+		}
+		doc, err := s.Report(ctx, map[string]interface{}{"a": 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if doc.Version != 4 {
+			t.Errorf("Version must not be reset by deletion, expected: 4, got: %d", doc.Version)
+		}
+	})
+	t.Run("NamedShadow", func(t *testing.T) {
+		named, err := shadow.New(ctx, cli, shadow.WithName("named"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cli.Handle(named)
+		if _, err := named.Report(ctx, map[string]interface{}{"x": "y"}); err != nil {
+			t.Fatal(err)
+		}
+		d, ok := srv.Document("test", "named")
+		if !ok {
+			t.Fatal("Named shadow is not created")
+		}
+		if expected := (shadow.NestedState{"x": "y"}); !reflect.DeepEqual(expected, d.State.Reported) {
+			t.Errorf("Expected reported state: %v, got: %v", expected, d.State.Reported)
+		}
+	})
+}
+
+func TestServer_Rejected(t *testing.T) {
+	testCases := map[string]struct {
+		payload string
+		code    int
+	}{
+		"InvalidJSON":    {`{`, http.StatusBadRequest},
+		"MissingState":   {`{"clientToken":"token"}`, http.StatusBadRequest},
+		"StateNotObject": {`{"state":1}`, http.StatusBadRequest},
+		"EmptyState":     {`{"state":{}}`, http.StatusBadRequest},
+		"TooDeep":        {`{"state":{"reported":{"a":{"b":{"c":1}}}}}`, http.StatusBadRequest},
+		"TooLargeDoc":    {`{"state":{"reported":{"a":"0123456789012345678901234567890123456789"}}}`, http.StatusRequestEntityTooLarge},
+		"TooLarge":       {`{"state":{"reported":{"a":"` + string(make([]byte, 128)) + `"}}}`, http.StatusRequestEntityTooLarge},
+		"Conflict":       {`{"state":{"reported":{"a":1}},"version":10}`, http.StatusConflict},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			srv, cli := newTestServer(ctx, t,
+				WithMaxPayloadSize(128),
+				WithMaxDocumentSize(48),
+				WithMaxDepth(2),
+			)
+			chRes := make(chan *mqtt.Message, 1)
+			cli.Handle(mqtt.HandlerFunc(func(msg *mqtt.Message) {
+				if msg.Topic == "$aws/things/test/shadow/update/rejected" {
+					chRes <- msg
+				}
+			}))
+			srv.Serve(&mqtt.Message{
+				Topic:   "$aws/things/test/shadow/update",
+				Payload: []byte(`{"state":{"reported":{"a":0}}}`),
+			})
+			srv.Serve(&mqtt.Message{
+				Topic:   "$aws/things/test/shadow/update",
+				Payload: []byte(tt.payload),
+			})
+			select {
+			case msg := <-chRes:
+				e := &shadow.ErrorResponse{}
+				if err := json.Unmarshal(msg.Payload, e); err != nil {
+					t.Fatal(err)
+				}
+				if e.Code != tt.code {
+					t.Errorf("Expected code: %d, got: %d (%s)", tt.code, e.Code, e.Message)
+				}
+			case <-ctx.Done():
+				t.Fatal("Timeout")
+			}
+		})
+	}
+}
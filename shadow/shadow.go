@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/at-wat/mqtt-go"
 
@@ -34,17 +35,47 @@ type Shadow interface {
 	// Get thing state and update local state document.
 	Get(ctx context.Context) (*ThingDocument, error)
 	// Report thing state and update local state document.
-	Report(ctx context.Context, state interface{}) (*ThingDocument, error)
+	Report(ctx context.Context, state interface{}, opt ...UpdateOption) (*ThingDocument, error)
 	// Desire sets desired thing state and update local state document.
-	Desire(ctx context.Context, state interface{}) (*ThingDocument, error)
+	Desire(ctx context.Context, state interface{}, opt ...UpdateOption) (*ThingDocument, error)
+	// ReportPatch reports thing state by the path based operations.
+	// Operations are applied in order and sent as one update.
+	ReportPatch(ctx context.Context, ops ...PatchOp) (*ThingDocument, error)
+	// DesirePatch sets desired thing state by the path based operations.
+	// Operations are applied in order and sent as one update.
+	DesirePatch(ctx context.Context, ops ...PatchOp) (*ThingDocument, error)
+	// Acknowledge reports the applied delta and removes the corresponding
+	// attributes from the desired state in one update to stop receiving
+	// the same delta.
+	// Use WithKeepDesired to leave the desired state intact.
+	Acknowledge(ctx context.Context, delta NestedState, opt ...AcknowledgeOption) (*ThingDocument, error)
+	// Modify gets thing document, passes it to the given function and
+	// updates the state returned by the function on the condition that
+	// the document version is not changed.
+	// The state is reported by default; use WithModifyDesired to update desired state.
+	// If the function returns nil state, the document is not updated.
+	// Update is retried on version conflict according to the retry policy.
+	Modify(ctx context.Context, fn func(doc *ThingDocument) (interface{}, error), opt ...ModifyOption) (*ThingDocument, error)
 	// Document returns full thing document.
 	Document() *ThingDocument
 	// Delete thing shadow.
 	Delete(ctx context.Context) error
 	// OnDelta sets handler of state deltas.
 	OnDelta(func(delta NestedState))
+	// OnDeltaWithMetadata sets handler of state deltas with the timestamps.
+	// Each leaf attribute of the delta is passed with the time when it was desired.
+	// It is called in addition to the handler set by OnDelta.
+	OnDeltaWithMetadata(func(delta []DeltaAttribute))
+	// OnDocuments sets handler of the thing documents before and after the update.
+	// prev is nil if the shadow is newly created.
+	// DiffDocuments can be used to list the changed attributes.
+	// The handler is called only if WithDocuments is specified.
+	OnDocuments(func(prev, cur *ThingDocument))
 	// OnError sets handler of asynchronous errors.
 	OnError(func(error))
+	// Close stops the background tasks enabled by WithAutoSync and
+	// WithOfflineQueue and waits for them to be finished.
+	Close() error
 }
 
 // ErrRejected is returned if AWS IoT responded on rejected topic.
@@ -53,19 +84,32 @@ var ErrRejected = errors.New("rejected")
 // ErrInvalidResponse is returned if failed to parse response from AWS IoT.
 var ErrInvalidResponse = errors.New("invalid response from AWS IoT")
 
+const backgroundRequestTimeout = 30 * time.Second
+
 type shadow struct {
 	mqtt.ServeMux
-	cli       mqtt.Client
-	thingName string
-	opts      *Options
-	doc       *ThingDocument
-	mu        sync.Mutex
-	onDelta   func(delta NestedState)
-	onError   func(err error)
+	cli         mqtt.Client
+	thingName   string
+	opts        *Options
+	doc         *ThingDocument
+	mu          sync.Mutex
+	onDelta     func(delta NestedState)
+	onDeltaMeta func(delta []DeltaAttribute)
+	onDocuments func(prev, cur *ThingDocument)
+	onError     func(err error)
 
 	chResps map[string]chan interface{}
+	chSync  chan struct{}
+
+	pendingMu sync.Mutex
+	pending   *PendingState
+	flushMu   sync.Mutex // serializes flush and offline reports
 
 	msgToken uint32
+
+	ctx    context.Context
+	cancel func()
+	wg     sync.WaitGroup
 }
 
 func (s *shadow) token() string {
@@ -106,12 +150,13 @@ func (s *shadow) getAccepted(msg *mqtt.Message) {
 	}
 	s.mu.Lock()
 	// For some reason, AWS IoT omits Metadata.Delta from Get response. Keep previous Metadata.Delta.
-	doc.Metadata.Delta = s.doc.Metadata.Delta
+	doc.Metadata.Delta = s.localDoc().Metadata.Delta
 	s.doc = doc
+	attrs := doc.deltaAttributes()
 	s.mu.Unlock()
 	s.handleResponse(doc)
 
-	s.handleDelta(doc.State.Delta)
+	s.handleDelta(doc.State.Delta, attrs)
 }
 
 func (s *shadow) rejected(msg *mqtt.Message) {
@@ -130,7 +175,7 @@ func (s *shadow) updateAccepted(msg *mqtt.Message) {
 		return
 	}
 	s.mu.Lock()
-	err := s.doc.update(doc)
+	err := s.localDoc().update(doc)
 	s.mu.Unlock()
 	if err != nil {
 		s.handleError(ioterr.New(err, "updating local thing document"))
@@ -146,12 +191,36 @@ func (s *shadow) updateDelta(msg *mqtt.Message) {
 		return
 	}
 	s.mu.Lock()
-	ok := s.doc.updateDelta(state)
+	ok := s.localDoc().updateDelta(state)
 	delta := cloneState(s.doc.State.Delta)
+	attrs := s.doc.deltaAttributes()
+	incomplete := ok && s.doc.MaybeIncomplete
 	s.mu.Unlock()
 	if ok {
-		s.handleDelta(delta)
+		s.handleDelta(delta, attrs)
+	}
+	if incomplete && s.opts.AutoSync {
+		s.requestSync()
+	}
+}
+
+func (s *shadow) updateDocuments(msg *mqtt.Message) {
+	docs := &thingDocuments{}
+	if err := json.Unmarshal(msg.Payload, docs); err != nil {
+		s.handleError(ioterr.New(err, "unmarshaling thing documents"))
+		return
+	}
+	s.handleDocuments(docs.Previous, docs.Current)
+}
+
+// localDoc returns local thing document.
+// Empty document is created if the shadow is deleted.
+// s.mu must be locked by the caller.
+func (s *shadow) localDoc() *ThingDocument {
+	if s.doc == nil {
+		s.doc = newThingDocument()
 	}
+	return s.doc
 }
 
 func (s *shadow) deleteAccepted(msg *mqtt.Message) {
@@ -167,6 +236,8 @@ func (s *shadow) deleteAccepted(msg *mqtt.Message) {
 }
 
 // New creates Thing Shadow interface.
+// The context is used to subscribe the shadow topics.
+// Background tasks run until Close is called.
 func New(ctx context.Context, cli awsiotdev.Device, opt ...Option) (Shadow, error) {
 	opts := DefaultOptions
 	for _, o := range opt {
@@ -174,116 +245,185 @@ func New(ctx context.Context, cli awsiotdev.Device, opt ...Option) (Shadow, erro
 			return nil, ioterr.New(err, "applying option")
 		}
 	}
+	s, err := newShadow(cli, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []mqtt.Subscription
+	for _, op := range topicOperations {
+		subs = append(subs, mqtt.Subscription{Topic: s.topic(op), QoS: mqtt.QoS1})
+	}
+	if opts.Documents {
+		subs = append(subs, mqtt.Subscription{Topic: s.topic("update/documents"), QoS: mqtt.QoS1})
+	}
+	if _, err := cli.Subscribe(ctx, subs...); err != nil {
+		return nil, ioterr.New(err, "subscribing shadow topics")
+	}
+	s.start(cli)
+	return s, nil
+}
+
+var topicOperations = []string{
+	"update/delta",
+	"update/accepted",
+	"update/rejected",
+	"get/accepted",
+	"get/rejected",
+	"delete/accepted",
+	"delete/rejected",
+}
+
+// newShadow creates shadow and registers message handlers without subscribing topics.
+func newShadow(cli awsiotdev.Device, opts *Options) (*shadow, error) {
 	s := &shadow{
 		cli:       cli,
 		thingName: cli.ThingName(),
-		opts:      &opts,
+		opts:      opts,
 		doc:       newThingDocument(),
 
 		chResps: make(map[string]chan interface{}),
+		chSync:  make(chan struct{}, 1),
 	}
-	for _, sub := range []struct {
-		topic   string
-		handler mqtt.Handler
-	}{
-		{s.topic("update/delta"), mqtt.HandlerFunc(s.updateDelta)},
-		{s.topic("update/accepted"), mqtt.HandlerFunc(s.updateAccepted)},
-		{s.topic("update/rejected"), mqtt.HandlerFunc(s.rejected)},
-		{s.topic("get/accepted"), mqtt.HandlerFunc(s.getAccepted)},
-		{s.topic("get/rejected"), mqtt.HandlerFunc(s.rejected)},
-		{s.topic("delete/accepted"), mqtt.HandlerFunc(s.deleteAccepted)},
-		{s.topic("delete/rejected"), mqtt.HandlerFunc(s.rejected)},
-	} {
-		if err := s.ServeMux.Handle(sub.topic, sub.handler); err != nil {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	if opts.OfflineQueue != nil {
+		p, err := opts.OfflineQueue.Load()
+		if err != nil {
+			return nil, ioterr.New(err, "loading pending state")
+		}
+		s.pending = p
+	}
+	handlers := map[string]mqtt.HandlerFunc{
+		"update/delta":     s.updateDelta,
+		"update/accepted":  s.updateAccepted,
+		"update/rejected":  s.rejected,
+		"update/documents": s.updateDocuments,
+		"get/accepted":     s.getAccepted,
+		"get/rejected":     s.rejected,
+		"delete/accepted":  s.deleteAccepted,
+		"delete/rejected":  s.rejected,
+	}
+	ops := topicOperations
+	if opts.Documents {
+		ops = append(ops[:len(ops):len(ops)], "update/documents")
+	}
+	for _, op := range ops {
+		if err := s.ServeMux.Handle(s.topic(op), handlers[op]); err != nil {
 			return nil, ioterr.New(err, "registering message handlers")
 		}
 	}
+	return s, nil
+}
 
-	_, err := cli.Subscribe(ctx,
-		mqtt.Subscription{Topic: s.topic("update/delta"), QoS: mqtt.QoS1},
-		mqtt.Subscription{Topic: s.topic("update/accepted"), QoS: mqtt.QoS1},
-		mqtt.Subscription{Topic: s.topic("update/rejected"), QoS: mqtt.QoS1},
-		mqtt.Subscription{Topic: s.topic("get/accepted"), QoS: mqtt.QoS1},
-		mqtt.Subscription{Topic: s.topic("get/rejected"), QoS: mqtt.QoS1},
-		mqtt.Subscription{Topic: s.topic("delete/accepted"), QoS: mqtt.QoS1},
-		mqtt.Subscription{Topic: s.topic("delete/rejected"), QoS: mqtt.QoS1},
-	)
-	if err != nil {
-		return nil, ioterr.New(err, "subscribing shadow topics")
+// start starts background tasks which run until Close is called.
+func (s *shadow) start(cli awsiotdev.Device) {
+	if s.opts.AutoSync || s.opts.OfflineQueue != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.background(s.ctx, cli)
+		}()
 	}
-	return s, nil
 }
 
-func (s *shadow) Report(ctx context.Context, state interface{}) (*ThingDocument, error) {
-	if s.opts.IncrementalUpdate {
-		var hasDiff bool
-		var err error
-		state, hasDiff, err = stateDiff(s.doc.State.Reported, state)
-		if err != nil {
-			return nil, err
+func (s *shadow) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *shadow) requestSync() {
+	select {
+	case s.chSync <- struct{}{}:
+	default:
+	}
+}
+
+func (s *shadow) background(ctx context.Context, cli mqtt.Retryer) {
+	check := time.NewTicker(s.opts.reconnectCheckPeriod)
+	defer check.Stop()
+
+	var chInterval, chRetry <-chan time.Time
+	if s.opts.AutoSync && s.opts.AutoSyncInterval > 0 {
+		interval := time.NewTicker(s.opts.AutoSyncInterval)
+		defer interval.Stop()
+		chInterval = interval.C
+	}
+	if s.opts.OfflineQueue != nil {
+		retry := time.NewTicker(s.opts.offlineRetryPeriod)
+		defer retry.Stop()
+		chRetry = retry.C
+	}
+
+	var countConnect int
+	for {
+		var sync, flush bool
+		select {
+		case <-ctx.Done():
+			return
+		case <-check.C:
+			n := cli.Stats().CountConnect
+			if n == countConnect {
+				continue
+			}
+			// (Re)connected.
+			countConnect = n
+			sync, flush = true, true
+		case <-chInterval:
+			sync = true
+		case <-s.chSync:
+			sync = true
+		case <-chRetry:
+			flush = true
 		}
-		if !hasDiff {
-			s.mu.Lock()
-			doc := s.doc.clone()
-			s.mu.Unlock()
-			return doc, nil
+		if flush && s.opts.OfflineQueue != nil {
+			ctxFlush, cancel := context.WithTimeout(ctx, backgroundRequestTimeout)
+			if err := s.flush(ctxFlush); err != nil && !queueable(err) {
+				s.handleError(ioterr.New(err, "flushing pending state"))
+			}
+			cancel()
+		}
+		if sync && s.opts.AutoSync {
+			ctxGet, cancel := context.WithTimeout(ctx, backgroundRequestTimeout)
+			if _, err := s.Get(ctxGet); err != nil {
+				s.handleError(ioterr.New(err, "synchronizing document"))
+			}
+			cancel()
 		}
 	}
-	rawState, err := json.Marshal(state)
-	if err != nil {
-		return nil, ioterr.New(err, "marshaling state")
-	}
-	token := s.token()
-	rawStateJSON := json.RawMessage(rawState)
-	data, err := json.Marshal(&thingDocumentRaw{
-		State:       thingStateRaw{Reported: rawStateJSON},
-		ClientToken: token,
-	})
-	if err != nil {
-		return nil, ioterr.New(err, "marshaling request")
+}
+
+func (s *shadow) Report(ctx context.Context, state interface{}, opt ...UpdateOption) (*ThingDocument, error) {
+	if s.opts.OfflineQueue != nil {
+		return s.reportOffline(ctx, state, opt...)
 	}
+	return s.updateSection(ctx, state, false, opt...)
+}
 
-	ch := make(chan interface{}, 1)
-	s.mu.Lock()
-	s.chResps[token] = ch
-	s.mu.Unlock()
-	defer func() {
-		s.mu.Lock()
-		delete(s.chResps, token)
-		s.mu.Unlock()
-	}()
+func (s *shadow) Desire(ctx context.Context, state interface{}, opt ...UpdateOption) (*ThingDocument, error) {
+	return s.updateSection(ctx, state, true, opt...)
+}
 
-	if err := s.cli.Publish(ctx, &mqtt.Message{
-		Topic:   s.topic("update"),
-		QoS:     mqtt.QoS1,
-		Payload: data,
-	}); err != nil {
-		return nil, ioterr.New(err, "sending request")
+func (s *shadow) updateSection(ctx context.Context, state interface{}, desired bool, opt ...UpdateOption) (*ThingDocument, error) {
+	opts := &UpdateOptions{}
+	for _, o := range opt {
+		o(opts)
 	}
-
-	select {
-	case <-ctx.Done():
-		return nil, ioterr.New(ctx.Err(), "updating reported state")
-	case res := <-ch:
-		switch r := res.(type) {
-		case *thingDocumentRaw:
-			s.mu.Lock()
-			doc := s.doc.clone()
-			s.mu.Unlock()
-			return doc, nil
-		case *ErrorResponse:
-			return nil, r
-		default:
-			return nil, ioterr.New(ErrInvalidResponse, "updating reported state")
-		}
+	failure := "updating reported state"
+	if desired {
+		failure = "updating desired state"
 	}
-}
 
-func (s *shadow) Desire(ctx context.Context, state interface{}) (*ThingDocument, error) {
 	if s.opts.IncrementalUpdate {
 		var hasDiff bool
 		var err error
-		state, hasDiff, err = stateDiff(s.doc.State.Desired, state)
+		s.mu.Lock()
+		if desired {
+			state, hasDiff, err = stateDiff(s.localDoc().State.Desired, state)
+		} else {
+			state, hasDiff, err = stateDiff(s.localDoc().State.Reported, state)
+		}
+		s.mu.Unlock()
 		if err != nil {
 			return nil, err
 		}
@@ -298,12 +438,31 @@ func (s *shadow) Desire(ctx context.Context, state interface{}) (*ThingDocument,
 	if err != nil {
 		return nil, ioterr.New(err, "marshaling state")
 	}
+	req := &thingDocumentRaw{Version: opts.ExpectedVersion}
+	if desired {
+		req.State.Desired = json.RawMessage(rawState)
+	} else {
+		req.State.Reported = json.RawMessage(rawState)
+	}
+	limits := s.opts.DocumentLimits
+	if limits == nil && s.opts.AutoSplit {
+		limits = &DefaultDocumentLimits
+	}
+	if limits != nil {
+		if err := limits.check(req); err != nil {
+			if !s.opts.AutoSplit || desired || errors.Is(err, ErrTooDeep) {
+				return nil, ioterr.New(err, failure)
+			}
+			return s.updateSplit(ctx, rawState, opts.ExpectedVersion, limits, failure)
+		}
+	}
+	return s.update(ctx, req, failure)
+}
+
+func (s *shadow) update(ctx context.Context, req *thingDocumentRaw, failure string) (*ThingDocument, error) {
 	token := s.token()
-	rawStateJSON := json.RawMessage(rawState)
-	data, err := json.Marshal(&thingDocumentRaw{
-		State:       thingStateRaw{Desired: rawStateJSON},
-		ClientToken: token,
-	})
+	req.ClientToken = token
+	data, err := json.Marshal(req)
 	if err != nil {
 		return nil, ioterr.New(err, "marshaling request")
 	}
@@ -328,7 +487,7 @@ func (s *shadow) Desire(ctx context.Context, state interface{}) (*ThingDocument,
 
 	select {
 	case <-ctx.Done():
-		return nil, ioterr.New(ctx.Err(), "updating desired state")
+		return nil, ioterr.New(ctx.Err(), failure)
 	case res := <-ch:
 		switch r := res.(type) {
 		case *thingDocumentRaw:
@@ -339,7 +498,41 @@ func (s *shadow) Desire(ctx context.Context, state interface{}) (*ThingDocument,
 		case *ErrorResponse:
 			return nil, r
 		default:
-			return nil, ioterr.New(ErrInvalidResponse, "updating desired state")
+			return nil, ioterr.New(ErrInvalidResponse, failure)
+		}
+	}
+}
+
+func (s *shadow) Modify(ctx context.Context, fn func(doc *ThingDocument) (interface{}, error), opt ...ModifyOption) (*ThingDocument, error) {
+	opts := &ModifyOptions{
+		RetryPolicy: DefaultRetryPolicy,
+	}
+	for _, o := range opt {
+		o(opts)
+	}
+	for i := 0; ; i++ {
+		doc, err := s.Get(ctx)
+		if err != nil {
+			return nil, ioterr.New(err, "getting document")
+		}
+		state, err := fn(doc)
+		if err != nil {
+			return nil, ioterr.New(err, "modifying document")
+		}
+		if state == nil {
+			return doc, nil
+		}
+		doc, err = s.updateSection(ctx, state, opts.Desired, WithExpectedVersion(doc.Version))
+		if err == nil {
+			return doc, nil
+		}
+		if !errors.Is(err, ErrVersionConflict) || i >= opts.RetryPolicy.MaxRetries {
+			return nil, err
+		}
+		select {
+		case <-time.After(opts.RetryPolicy.wait(i)):
+		case <-ctx.Done():
+			return nil, ioterr.New(ctx.Err(), "waiting retry")
 		}
 	}
 }
@@ -431,8 +624,19 @@ func (s *shadow) Delete(ctx context.Context) error {
 
 func (s *shadow) Document() *ThingDocument {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	return s.doc.clone()
+	doc := s.doc.clone()
+	s.mu.Unlock()
+
+	if doc == nil || s.opts.OfflineQueue == nil {
+		return doc
+	}
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if !s.pending.empty() {
+		doc.Unsynced = NestedState(cloneState(s.pending.State))
+		updateState(doc.State.Reported, NestedState(cloneState(s.pending.State)))
+	}
+	return doc
 }
 
 func (s *shadow) OnDelta(cb func(delta NestedState)) {
@@ -441,13 +645,38 @@ func (s *shadow) OnDelta(cb func(delta NestedState)) {
 	s.mu.Unlock()
 }
 
-func (s *shadow) handleDelta(delta NestedState) {
+func (s *shadow) OnDeltaWithMetadata(cb func(delta []DeltaAttribute)) {
+	s.mu.Lock()
+	s.onDeltaMeta = cb
+	s.mu.Unlock()
+}
+
+func (s *shadow) handleDelta(delta NestedState, attrs []DeltaAttribute) {
 	s.mu.Lock()
 	cb := s.onDelta
+	cbMeta := s.onDeltaMeta
 	s.mu.Unlock()
 	if cb != nil {
 		cb(delta)
 	}
+	if cbMeta != nil {
+		cbMeta(attrs)
+	}
+}
+
+func (s *shadow) OnDocuments(cb func(prev, cur *ThingDocument)) {
+	s.mu.Lock()
+	s.onDocuments = cb
+	s.mu.Unlock()
+}
+
+func (s *shadow) handleDocuments(prev, cur *ThingDocument) {
+	s.mu.Lock()
+	cb := s.onDocuments
+	s.mu.Unlock()
+	if cb != nil {
+		cb(prev, cur)
+	}
 }
 
 func (s *shadow) OnError(cb func(err error)) {
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -101,6 +102,40 @@ func TestNew(t *testing.T) {
 				})
 			}
 		})
+		t.Run("WithDocuments", func(t *testing.T) {
+			testCases := map[string]struct {
+				opts     []Option
+				expected bool
+			}{
+				"Default":       {},
+				"WithDocuments": {opts: []Option{WithDocuments()}, expected: true},
+			}
+			for name, tt := range testCases {
+				tt := tt
+				t.Run(name, func(t *testing.T) {
+					ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+					defer cancel()
+
+					var subscribed bool
+					cli := &mockDevice{mockClient: &mockmqtt.Client{
+						SubscribeFn: func(ctx context.Context, subs ...mqtt.Subscription) ([]mqtt.Subscription, error) {
+							for _, s := range subs {
+								if s.Topic == "$aws/things/test/shadow/update/documents" {
+									subscribed = true
+								}
+							}
+							return subs, nil
+						},
+					}}
+					if _, err := New(ctx, cli, tt.opts...); err != nil {
+						t.Fatal(err)
+					}
+					if subscribed != tt.expected {
+						t.Errorf("Expected update/documents subscription: %v, got: %v", tt.expected, subscribed)
+					}
+				})
+			}
+		})
 	})
 }
 
@@ -395,6 +430,7 @@ func TestHandlers_InvalidResponse(t *testing.T) {
 		"update/delta",
 		"update/accepted",
 		"update/rejected",
+		"update/documents",
 		"get/accepted",
 		"get/rejected",
 		"delete/accepted",
@@ -408,7 +444,7 @@ func TestHandlers_InvalidResponse(t *testing.T) {
 			var cli *mockDevice
 			cli = &mockDevice{mockClient: &mockmqtt.Client{}}
 
-			s, err := New(ctx, cli)
+			s, err := New(ctx, cli, WithDocuments())
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -598,6 +634,140 @@ func TestOnDelta(t *testing.T) {
 	}
 }
 
+func TestOnDeltaWithMetadata(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cli := &mockDevice{mockClient: &mockmqtt.Client{}}
+	s, err := New(ctx, cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.Handle(s)
+
+	chDelta := make(chan []DeltaAttribute, 1)
+	s.OnDeltaWithMetadata(func(delta []DeltaAttribute) {
+		chDelta <- delta
+	})
+
+	cli.Serve(&mqtt.Message{
+		Topic: s.(*shadow).topic("update/delta"),
+		Payload: []byte(`{
+  "version": 1,
+  "timestamp": 30,
+  "state": {"key1": "value1", "key2": {"key3": 1}},
+  "metadata": {"key1": {"timestamp": 10}, "key2": {"key3": {"timestamp": 20}}}
+}`),
+	})
+	expected := []DeltaAttribute{
+		{Path: "key1", Value: "value1", Timestamp: 10},
+		{Path: "key2.key3", Value: 1.0, Timestamp: 20},
+	}
+	select {
+	case delta := <-chDelta:
+		if !reflect.DeepEqual(expected, delta) {
+			t.Errorf("Expected delta:\n%+v\ngot:\n%+v", expected, delta)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timeout")
+	}
+
+	doc := s.Document()
+	if ts, ok := doc.DesiredTimestamp("key2"); !ok || ts != 20 {
+		t.Errorf("Expected desired timestamp: 20, got: %d (%v)", ts, ok)
+	}
+}
+
+func TestOnDocuments(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	cli := &mockDevice{mockClient: &mockmqtt.Client{}}
+	s, err := New(ctx, cli, WithDocuments())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.Handle(s)
+
+	type documents struct {
+		prev, cur *ThingDocument
+	}
+	chDocs := make(chan documents, 1)
+	s.OnDocuments(func(prev, cur *ThingDocument) {
+		chDocs <- documents{prev, cur}
+	})
+
+	testCases := map[string]struct {
+		payload  string
+		expected documents
+	}{
+		"Updated": {
+			payload: `{
+  "previous": {
+    "state": {"reported": {"key": "value1"}},
+    "metadata": {"reported": {"key": {"timestamp": 1}}},
+    "version": 1
+  },
+  "current": {
+    "state": {"reported": {"key": "value2"}},
+    "metadata": {"reported": {"key": {"timestamp": 2}}},
+    "version": 2
+  },
+  "timestamp": 2
+}`,
+			expected: documents{
+				prev: &ThingDocument{
+					State:    ThingState{Reported: NestedState{"key": "value1"}},
+					Metadata: ThingStateMetadata{Reported: NestedMetadata{"key": Metadata{Timestamp: 1}}},
+					Version:  1,
+				},
+				cur: &ThingDocument{
+					State:    ThingState{Reported: NestedState{"key": "value2"}},
+					Metadata: ThingStateMetadata{Reported: NestedMetadata{"key": Metadata{Timestamp: 2}}},
+					Version:  2,
+				},
+			},
+		},
+		"Created": {
+			payload: `{
+  "current": {
+    "state": {"desired": {"key": "value1"}},
+    "metadata": {"desired": {"key": {"timestamp": 1}}},
+    "version": 1
+  },
+  "timestamp": 1
+}`,
+			expected: documents{
+				cur: &ThingDocument{
+					State:    ThingState{Desired: NestedState{"key": "value1"}},
+					Metadata: ThingStateMetadata{Desired: NestedMetadata{"key": Metadata{Timestamp: 1}}},
+					Version:  1,
+				},
+			},
+		},
+	}
+
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			cli.Serve(&mqtt.Message{
+				Topic:   s.(*shadow).topic("update/documents"),
+				Payload: []byte(tt.payload),
+			})
+			select {
+			case docs := <-chDocs:
+				if !reflect.DeepEqual(tt.expected, docs) {
+					t.Errorf("Expected documents:\n%+v\n%+v\ngot:\n%+v\n%+v",
+						tt.expected.prev, tt.expected.cur, docs.prev, docs.cur,
+					)
+				}
+			case <-ctx.Done():
+				t.Fatal("Timeout")
+			}
+		})
+	}
+}
+
 func TestGet(t *testing.T) {
 	testCases := map[string]struct {
 		initial       *ThingDocument
@@ -999,3 +1169,264 @@ func TestDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestModify(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	version := 5
+	var nUpdate int
+	var expectedVersions []int
+
+	var s Shadow
+	var cli *mockDevice
+	cli = &mockDevice{
+		mockClient: &mockmqtt.Client{
+			PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+				req := &thingDocumentRaw{}
+				if err := json.Unmarshal(msg.Payload, req); err != nil {
+					t.Error(err)
+					cancel()
+					return err
+				}
+				var res interface{}
+				var topic string
+				switch msg.Topic {
+				case s.(*shadow).topic("get"):
+					res = &ThingDocument{
+						Version: version,
+						State: ThingState{
+							Reported: NestedState{"count": float64(version)},
+						},
+					}
+					topic = "get/accepted"
+				case s.(*shadow).topic("update"):
+					nUpdate++
+					expectedVersions = append(expectedVersions, req.Version)
+					if nUpdate == 1 {
+						// Another client updated the document.
+						version++
+						res = &ErrorResponse{Code: 409, Message: "Version conflict"}
+						topic = "update/rejected"
+						break
+					}
+					version++
+					res = &thingDocumentRaw{
+						Version: version,
+						State:   thingStateRaw{Reported: req.State.Reported},
+					}
+					topic = "update/accepted"
+				}
+				setClientToken(res, req.ClientToken)
+				bres, err := json.Marshal(res)
+				if err != nil {
+					t.Error(err)
+					cancel()
+					return err
+				}
+				cli.Serve(&mqtt.Message{
+					Topic:   s.(*shadow).topic(topic),
+					Payload: bres,
+				})
+				return nil
+			},
+		},
+	}
+	var err error
+	s, err = New(ctx, cli)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.Handle(s)
+
+	doc, err := s.Modify(ctx, func(doc *ThingDocument) (interface{}, error) {
+		cnt := doc.State.Reported["count"].(float64)
+		return map[string]interface{}{"count": cnt + 1}, nil
+	}, WithRetryPolicy(RetryPolicy{MaxRetries: 1, Wait: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]int{5, 6}, expectedVersions) {
+		t.Errorf("Expected versions: [5 6], got: %v", expectedVersions)
+	}
+	if cnt := doc.State.Reported["count"]; cnt != float64(7) {
+		t.Errorf("Expected count: 7, got: %v", cnt)
+	}
+
+	t.Run("RetryExceeded", func(t *testing.T) {
+		nUpdate = 0
+		_, err := s.Modify(ctx, func(doc *ThingDocument) (interface{}, error) {
+			return map[string]interface{}{"count": 0}, nil
+		}, WithRetryPolicy(RetryPolicy{}))
+		if !errors.Is(err, ErrVersionConflict) {
+			t.Errorf("Expected error: %v, got: %v", ErrVersionConflict, err)
+		}
+	})
+}
+
+type mockRetryer struct {
+	mqtt.Retryer
+	mu           sync.Mutex
+	countConnect int
+}
+
+func (r *mockRetryer) Stats() mqtt.RetryStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return mqtt.RetryStats{CountConnect: r.countConnect}
+}
+
+func (r *mockRetryer) reconnect() {
+	r.mu.Lock()
+	r.countConnect++
+	r.mu.Unlock()
+}
+
+func TestAutoSync(t *testing.T) {
+	testCases := map[string]struct {
+		opts    []Option
+		trigger func(cli *mockDevice, s Shadow)
+	}{
+		"VersionGap": {
+			trigger: func(cli *mockDevice, s Shadow) {
+				cli.Serve(&mqtt.Message{
+					Topic:   s.(*shadow).topic("update/delta"),
+					Payload: []byte(`{"version":3,"state":{"key":"value1"}}`),
+				})
+			},
+		},
+		"Reconnect": {
+			trigger: func(cli *mockDevice, s Shadow) {
+				cli.Retryer.(*mockRetryer).reconnect()
+			},
+		},
+		"Interval": {
+			opts: []Option{WithAutoSyncInterval(10 * time.Millisecond)},
+		},
+	}
+
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			chGet := make(chan struct{}, 10)
+			var s Shadow
+			var cli *mockDevice
+			cli = &mockDevice{
+				mockClient: &mockmqtt.Client{
+					PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+						if msg.Topic != s.(*shadow).topic("get") {
+							return nil
+						}
+						req := &simpleRequest{}
+						if err := json.Unmarshal(msg.Payload, req); err != nil {
+							t.Error(err)
+							return err
+						}
+						cli.Serve(&mqtt.Message{
+							Topic: s.(*shadow).topic("get/accepted"),
+							Payload: []byte(fmt.Sprintf(
+								`{"version":5,"state":{"desired":{"key":"value2"},"delta":{"key":"value2"}},"clientToken":"%s"}`,
+								req.ClientToken,
+							)),
+						})
+						chGet <- struct{}{}
+						return nil
+					},
+				},
+				Retryer: &mockRetryer{},
+			}
+			opts := append([]Option{
+				WithAutoSync(),
+				func(o *Options) error {
+					o.reconnectCheckPeriod = 5 * time.Millisecond
+					return nil
+				},
+			}, tt.opts...)
+			// Synchronization must continue after the context passed to New is canceled.
+			ctxNew, cancelNew := context.WithCancel(ctx)
+			var err error
+			s, err = New(ctxNew, cli, opts...)
+			cancelNew()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if err := s.Close(); err != nil {
+					t.Error(err)
+				}
+			}()
+			cli.Handle(s)
+
+			chDelta := make(chan NestedState, 10)
+			s.OnDelta(func(delta NestedState) {
+				chDelta <- delta
+			})
+
+			if tt.trigger != nil {
+				// Wait a while to ensure that nothing is requested without the trigger.
+				select {
+				case <-chGet:
+					t.Fatal("Unexpected get request")
+				case <-time.After(20 * time.Millisecond):
+				}
+				tt.trigger(cli, s)
+			}
+			select {
+			case <-chGet:
+			case <-ctx.Done():
+				t.Fatal("Timeout")
+			}
+			for {
+				select {
+				case delta := <-chDelta:
+					if delta["key"] == "value2" {
+						if doc := s.Document(); doc.Version != 5 || doc.MaybeIncomplete {
+							t.Errorf("Expected synchronized document, got: %+v", doc)
+						}
+						return
+					}
+				case <-ctx.Done():
+					t.Fatal("Timeout")
+				}
+			}
+		})
+	}
+	t.Run("Close", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		chGet := make(chan struct{}, 10)
+		cli := &mockDevice{
+			mockClient: &mockmqtt.Client{
+				PublishFn: func(ctx context.Context, msg *mqtt.Message) error {
+					chGet <- struct{}{}
+					return nil
+				},
+			},
+			Retryer: &mockRetryer{},
+		}
+		s, err := New(ctx, cli, WithAutoSync(), WithAutoSyncInterval(5*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-chGet:
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		for len(chGet) > 0 {
+			<-chGet
+		}
+		select {
+		case <-chGet:
+			t.Error("Unexpected get request after Close")
+		case <-time.After(20 * time.Millisecond):
+		}
+	})
+}
@@ -18,6 +18,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
 )
@@ -48,6 +49,12 @@ func (e *ErrorResponse) Error() string {
 	return fmt.Sprintf("%d (%s): %s", e.Code, e.ClientToken, e.Message)
 }
 
+// Is returns true if the target is ErrVersionConflict and
+// the response code is 409.
+func (e *ErrorResponse) Is(target error) bool {
+	return target == ErrVersionConflict && e.Code == http.StatusConflict
+}
+
 // ThingState represents Thing Shadow State.
 type ThingState struct {
 	Desired  NestedState `json:"desired,omitempty"`
@@ -64,6 +71,10 @@ type ThingDocument struct {
 	ClientToken string             `json:"clientToken,omitempty"`
 
 	MaybeIncomplete bool `json:"-"`
+	// Unsynced is reported state not yet synchronized with AWS IoT.
+	// Only set on the offline queue mode.
+	// Values are also applied to State.Reported.
+	Unsynced NestedState `json:"-"`
 }
 
 type thingStateRaw struct {
@@ -81,6 +92,13 @@ type thingDocumentRaw struct {
 	ClientToken string        `json:"clientToken,omitempty"`
 }
 
+type thingDocuments struct {
+	Previous    *ThingDocument `json:"previous"`
+	Current     *ThingDocument `json:"current"`
+	Timestamp   int            `json:"timestamp,omitempty"`
+	ClientToken string         `json:"clientToken,omitempty"`
+}
+
 type thingDelta struct {
 	State     NestedState    `json:"state"`
 	Metadata  NestedMetadata `json:"metadata"`
@@ -196,7 +214,7 @@ func updateState(state map[string]interface{}, update map[string]interface{}) {
 		case NestedState:
 			if s, ok := state[key].(NestedState); ok {
 				updateState(s, v)
-				return
+				continue
 			}
 			state[key] = v
 		case nil:
@@ -113,6 +113,31 @@ func TestThingDocument_update(t *testing.T) {
 		)
 	}
 
+	if err := doc.update(&thingDocumentRaw{
+		State:     thingStateRaw{Desired: json.RawMessage(`{"key": {"key2": "value2"}, "key2": "value"}`)},
+		Version:   7,
+		Timestamp: 12349,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected4 := &ThingDocument{
+		State: ThingState{
+			Desired: NestedState{
+				"key":  NestedState{"key": "value", "key2": "value2"},
+				"key2": "value",
+			},
+		},
+		Version:   7,
+		Timestamp: 12349,
+	}
+	if !reflect.DeepEqual(*expected4, *doc) {
+		t.Errorf(
+			"Nested state must be merged\nexpected: %v\ngot: %v",
+			*expected4, *doc,
+		)
+	}
+
 	t.Run("InvalidState", func(t *testing.T) {
 		err := doc.update(&thingDocumentRaw{
 			State:     thingStateRaw{Desired: json.RawMessage(`{"broken"}`)},
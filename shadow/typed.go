@@ -0,0 +1,190 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"sync"
+
+	"github.com/at-wat/mqtt-go"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+// TypedShadow is an interface of Thing Shadow which state is decoded into
+// the user defined types.
+// D is a type of desired state and R is a type of reported state.
+// Attributes are mapped to the struct fields by json tags.
+type TypedShadow[D, R any] interface {
+	mqtt.Handler
+	// Get thing state and update local state document.
+	Get(ctx context.Context) (*TypedDocument[D, R], error)
+	// Report thing state and update local state document.
+	Report(ctx context.Context, state R, opt ...UpdateOption) (*TypedDocument[D, R], error)
+	// Desire sets desired thing state and update local state document.
+	Desire(ctx context.Context, state D, opt ...UpdateOption) (*TypedDocument[D, R], error)
+	// Document returns full thing document.
+	Document() (*TypedDocument[D, R], error)
+	// Delete thing shadow.
+	Delete(ctx context.Context) error
+	// OnDelta sets handler of state deltas.
+	// Only the attributes included in the delta are set.
+	OnDelta(func(delta D))
+	// OnDocuments sets handler of the thing documents before and after the update.
+	// prev is nil if the shadow is newly created.
+	// The handler is called only if WithDocuments is specified.
+	OnDocuments(func(prev, cur *TypedDocument[D, R]))
+	// OnError sets handler of asynchronous errors.
+	OnError(func(error))
+	// Shadow returns underlying Shadow.
+	Shadow() Shadow
+}
+
+// TypedDocument represents Thing Shadow Document with typed state.
+type TypedDocument[D, R any] struct {
+	Desired   D
+	Reported  R
+	Delta     D
+	Metadata  ThingStateMetadata
+	Version   int
+	Timestamp int
+
+	MaybeIncomplete bool
+}
+
+type typedShadow[D, R any] struct {
+	shadow  Shadow
+	mu      sync.Mutex
+	onError func(err error)
+}
+
+// NewTyped wraps Shadow to handle the state as the given types.
+// Decoding failures are returned or notified as ErrIncompatibleMapping.
+func NewTyped[D, R any](s Shadow) TypedShadow[D, R] {
+	return &typedShadow[D, R]{shadow: s}
+}
+
+func newTypedDocument[D, R any](doc *ThingDocument) (*TypedDocument[D, R], error) {
+	if doc == nil {
+		return nil, nil
+	}
+	td := &TypedDocument[D, R]{
+		Metadata:        doc.Metadata,
+		Version:         doc.Version,
+		Timestamp:       doc.Timestamp,
+		MaybeIncomplete: doc.MaybeIncomplete,
+	}
+	if err := decodeState(map[string]interface{}(doc.State.Desired), &td.Desired); err != nil {
+		return nil, ioterr.New(err, "decoding desired state")
+	}
+	if err := decodeState(map[string]interface{}(doc.State.Reported), &td.Reported); err != nil {
+		return nil, ioterr.New(err, "decoding reported state")
+	}
+	if err := decodeState(map[string]interface{}(doc.State.Delta), &td.Delta); err != nil {
+		return nil, ioterr.New(err, "decoding delta state")
+	}
+	return td, nil
+}
+
+func (t *typedShadow[D, R]) Serve(msg *mqtt.Message) {
+	t.shadow.Serve(msg)
+}
+
+func (t *typedShadow[D, R]) Get(ctx context.Context) (*TypedDocument[D, R], error) {
+	doc, err := t.shadow.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newTypedDocument[D, R](doc)
+}
+
+func (t *typedShadow[D, R]) Report(ctx context.Context, state R, opt ...UpdateOption) (*TypedDocument[D, R], error) {
+	doc, err := t.shadow.Report(ctx, state, opt...)
+	if err != nil {
+		return nil, err
+	}
+	return newTypedDocument[D, R](doc)
+}
+
+func (t *typedShadow[D, R]) Desire(ctx context.Context, state D, opt ...UpdateOption) (*TypedDocument[D, R], error) {
+	doc, err := t.shadow.Desire(ctx, state, opt...)
+	if err != nil {
+		return nil, err
+	}
+	return newTypedDocument[D, R](doc)
+}
+
+func (t *typedShadow[D, R]) Document() (*TypedDocument[D, R], error) {
+	return newTypedDocument[D, R](t.shadow.Document())
+}
+
+func (t *typedShadow[D, R]) Delete(ctx context.Context) error {
+	return t.shadow.Delete(ctx)
+}
+
+func (t *typedShadow[D, R]) OnDelta(cb func(delta D)) {
+	if cb == nil {
+		t.shadow.OnDelta(nil)
+		return
+	}
+	t.shadow.OnDelta(func(delta NestedState) {
+		var d D
+		if err := decodeState(map[string]interface{}(delta), &d); err != nil {
+			t.handleError(ioterr.New(err, "decoding delta state"))
+			return
+		}
+		cb(d)
+	})
+}
+
+func (t *typedShadow[D, R]) OnDocuments(cb func(prev, cur *TypedDocument[D, R])) {
+	if cb == nil {
+		t.shadow.OnDocuments(nil)
+		return
+	}
+	t.shadow.OnDocuments(func(prev, cur *ThingDocument) {
+		p, err := newTypedDocument[D, R](prev)
+		if err != nil {
+			t.handleError(ioterr.New(err, "decoding previous document"))
+			return
+		}
+		c, err := newTypedDocument[D, R](cur)
+		if err != nil {
+			t.handleError(ioterr.New(err, "decoding current document"))
+			return
+		}
+		cb(p, c)
+	})
+}
+
+func (t *typedShadow[D, R]) OnError(cb func(err error)) {
+	t.mu.Lock()
+	t.onError = cb
+	t.mu.Unlock()
+	t.shadow.OnError(cb)
+}
+
+func (t *typedShadow[D, R]) handleError(err error) {
+	t.mu.Lock()
+	cb := t.onError
+	t.mu.Unlock()
+	if cb != nil {
+		cb(err)
+	}
+}
+
+func (t *typedShadow[D, R]) Shadow() Shadow {
+	return t.shadow
+}
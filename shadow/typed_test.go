@@ -0,0 +1,181 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testDesired struct {
+	Speed int    `json:"speed"`
+	Mode  string `json:"mode,omitempty"`
+}
+
+type testReported struct {
+	Speed   int  `json:"speed"`
+	Running bool `json:"running"`
+}
+
+type stubShadow struct {
+	Shadow
+	doc      *ThingDocument
+	reported interface{}
+	desired  interface{}
+	onDelta  func(NestedState)
+	onDocs   func(prev, cur *ThingDocument)
+	onError  func(error)
+}
+
+func (s *stubShadow) Get(ctx context.Context) (*ThingDocument, error) {
+	return s.doc, nil
+}
+
+func (s *stubShadow) Report(ctx context.Context, state interface{}, opt ...UpdateOption) (*ThingDocument, error) {
+	s.reported = state
+	return s.doc, nil
+}
+
+func (s *stubShadow) Desire(ctx context.Context, state interface{}, opt ...UpdateOption) (*ThingDocument, error) {
+	s.desired = state
+	return s.doc, nil
+}
+
+func (s *stubShadow) Document() *ThingDocument {
+	return s.doc
+}
+
+func (s *stubShadow) OnDelta(cb func(NestedState)) {
+	s.onDelta = cb
+}
+
+func (s *stubShadow) OnDocuments(cb func(prev, cur *ThingDocument)) {
+	s.onDocs = cb
+}
+
+func (s *stubShadow) OnError(cb func(error)) {
+	s.onError = cb
+}
+
+func TestTypedShadow(t *testing.T) {
+	s := &stubShadow{
+		doc: &ThingDocument{
+			State: ThingState{
+				Desired:  NestedState{"speed": float64(10), "mode": "fast"},
+				Reported: NestedState{"speed": float64(5), "running": true},
+				Delta:    NestedState{"speed": float64(10), "mode": "fast"},
+			},
+			Version: 3,
+		},
+	}
+	ts := NewTyped[testDesired, testReported](s)
+	expected := &TypedDocument[testDesired, testReported]{
+		Desired:  testDesired{Speed: 10, Mode: "fast"},
+		Reported: testReported{Speed: 5, Running: true},
+		Delta:    testDesired{Speed: 10, Mode: "fast"},
+		Version:  3,
+	}
+
+	ctx := context.Background()
+
+	t.Run("Get", func(t *testing.T) {
+		doc, err := ts.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, doc) {
+			t.Errorf("Expected document:\n%+v\ngot:\n%+v", expected, doc)
+		}
+	})
+	t.Run("Document", func(t *testing.T) {
+		doc, err := ts.Document()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, doc) {
+			t.Errorf("Expected document:\n%+v\ngot:\n%+v", expected, doc)
+		}
+	})
+	t.Run("Report", func(t *testing.T) {
+		state := testReported{Speed: 10, Running: true}
+		if _, err := ts.Report(ctx, state); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(state, s.reported) {
+			t.Errorf("Expected reported state: %+v, got: %+v", state, s.reported)
+		}
+	})
+	t.Run("Desire", func(t *testing.T) {
+		state := testDesired{Speed: 1}
+		if _, err := ts.Desire(ctx, state); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(state, s.desired) {
+			t.Errorf("Expected desired state: %+v, got: %+v", state, s.desired)
+		}
+	})
+	t.Run("OnDelta", func(t *testing.T) {
+		var delta testDesired
+		ts.OnDelta(func(d testDesired) {
+			delta = d
+		})
+		s.onDelta(NestedState{"mode": "slow"})
+		expectedDelta := testDesired{Mode: "slow"}
+		if !reflect.DeepEqual(expectedDelta, delta) {
+			t.Errorf("Expected delta: %+v, got: %+v", expectedDelta, delta)
+		}
+	})
+	t.Run("OnDocuments", func(t *testing.T) {
+		var prev, cur *TypedDocument[testDesired, testReported]
+		ts.OnDocuments(func(p, c *TypedDocument[testDesired, testReported]) {
+			prev, cur = p, c
+		})
+		s.onDocs(nil, &ThingDocument{
+			State:   ThingState{Reported: NestedState{"speed": float64(1)}},
+			Version: 1,
+		})
+		expectedCur := &TypedDocument[testDesired, testReported]{
+			Reported: testReported{Speed: 1},
+			Version:  1,
+		}
+		if prev != nil {
+			t.Errorf("Expected nil previous document, got: %+v", prev)
+		}
+		if !reflect.DeepEqual(expectedCur, cur) {
+			t.Errorf("Expected current document:\n%+v\ngot:\n%+v", expectedCur, cur)
+		}
+	})
+	t.Run("OnDeltaIncompatible", func(t *testing.T) {
+		var errAsync error
+		ts.OnError(func(err error) {
+			errAsync = err
+		})
+		ts.OnDelta(func(d testDesired) {
+			t.Error("OnDelta must not be called on decode error")
+		})
+		s.onDelta(NestedState{"speed": "fast"})
+		if !errors.Is(errAsync, ErrIncompatibleMapping) {
+			t.Errorf("Expected error: %v, got: %v", ErrIncompatibleMapping, errAsync)
+		}
+	})
+	t.Run("Incompatible", func(t *testing.T) {
+		s.doc.State.Reported["running"] = "yes"
+		if _, err := ts.Get(ctx); !errors.Is(err, ErrIncompatibleMapping) {
+			t.Errorf("Expected error: %v, got: %v", ErrIncompatibleMapping, err)
+		}
+	})
+}
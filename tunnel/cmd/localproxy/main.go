@@ -15,11 +15,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/seqsense/aws-iot-device-sdk-go/v6/tunnel"
 )
@@ -32,6 +37,7 @@ var (
 	destinationApp  = flag.String("destination-app", "", "Assigns destination mode and set the endpoint in address:port format")
 	noSSLHostVerify = flag.Bool("no-ssl-host-verify", false, "Turn off SSL host verification")
 	proxyScheme     = flag.String("proxy-scheme", "wss", "Proxy server protocol scheme")
+	reconnect       = flag.Bool("reconnect", false, "Reconnect to the proxy server on disconnection")
 )
 
 func main() {
@@ -61,6 +67,12 @@ func main() {
 			log.Print(err)
 		})),
 	}
+	if *reconnect {
+		proxyOpts = append(proxyOpts, tunnel.WithReconnect(tunnel.DefaultReconnectPolicy))
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
 
 	switch {
 	case *sourcePort > 0 && *destinationApp == "":
@@ -68,16 +80,16 @@ func main() {
 		if err != nil {
 			log.Fatalf("error: %v", err)
 		}
-		err = tunnel.ProxySource(listener, endpoint, *accessToken, proxyOpts...)
-		if err != nil {
+		err = tunnel.ProxySourceContext(ctx, listener, endpoint, *accessToken, proxyOpts...)
+		if err != nil && !errors.Is(err, context.Canceled) {
 			log.Fatalf("error: %v", err)
 		}
 
 	case *destinationApp != "" && *sourcePort == 0:
-		err := tunnel.ProxyDestination(func() (io.ReadWriteCloser, error) {
+		err := tunnel.ProxyDestinationContext(ctx, func() (io.ReadWriteCloser, error) {
 			return net.Dial("tcp", *destinationApp)
 		}, endpoint, *accessToken, proxyOpts...)
-		if err != nil {
+		if err != nil && !errors.Is(err, context.Canceled) {
 			log.Fatalf("error: %v", err)
 		}
 
@@ -15,108 +15,76 @@
 package tunnel
 
 import (
+	"context"
 	"io"
-	"sync"
-
-	"google.golang.org/protobuf/proto"
 
 	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
 	"github.com/seqsense/aws-iot-device-sdk-go/v6/tunnel/msg"
 )
 
-func proxyDestination(ws io.ReadWriter, dialer Dialer, eh ErrorHandler, stat Stat) error {
-	var muConns sync.Mutex
-	conns := make(map[int32]io.ReadWriteCloser)
-	sz := make([]byte, 2)
-	b := make([]byte, 8192)
+func proxyDestination(ws io.ReadWriteCloser, dialer Dialer, eh ErrorHandler, stat Stat) error {
+	return proxyDestinationServices(context.Background(), ws, ProtocolV1, map[string]Dialer{"": dialer}, nil, eh, stat)
+}
 
-	updateStat := func() {
-		if stat != nil {
-			muConns.Lock()
-			n := len(conns)
-			muConns.Unlock()
-			stat.Update(func(stat *Statistics) {
-				stat.NumConn = n
-			})
-		}
+func proxyDestinationServices(ctx context.Context, ws io.ReadWriteCloser, version ProtocolVersion, dialers map[string]Dialer, limiter *streamLimiter, eh ErrorHandler, stat Stat) error {
+	if version == ProtocolV1 && len(dialers) > 1 {
+		return ioterr.Newf(ErrMultipleServices, "%d services on protocol version %d", len(dialers), version)
 	}
-
-	for {
-		if _, err := io.ReadFull(ws, sz); err != nil {
-			if err == io.EOF {
-				return nil
+	s := newSession(ws, version, eh, stat)
+	return s.run(ctx, func(m *msg.Message) {
+		switch m.Type {
+		case msg.Message_CONNECTION_START:
+			if version < ProtocolV3 {
+				return
 			}
-			return ioterr.New(err, "reading length header")
+		case msg.Message_STREAM_START:
+		default:
+			return
 		}
-		l := int(sz[0])<<8 | int(sz[1])
-		if cap(b) < l {
-			b = make([]byte, l)
+		k := s.key(m)
+		dialer, ok := lookupDialer(dialers, version, k.serviceID)
+		if !ok {
+			s.handleError(ioterr.Newf(ErrUnknownService, "%q", k.serviceID))
+			s.reset(k)
+			return
 		}
-		b = b[:l]
-		if _, err := io.ReadFull(ws, b); err != nil {
-			if err == io.EOF {
-				return nil
-			}
-			return ioterr.New(err, "reading message")
+		if err := limiter.check(k.serviceID, s.numConns(k, m.Type)); err != nil {
+			s.updateStatFunc(func(stat *Statistics) {
+				stat.StreamsRejected++
+			})
+			s.handleError(ioterr.Newf(err, "service %q", k.serviceID))
+			s.reject(k, m.Type)
+			return
 		}
-		m := &msg.Message{}
-		if err := proto.Unmarshal(b, m); err != nil {
-			if eh != nil {
-				eh.HandleError(ioterr.New(err, "unmarshaling message"))
-			}
-			continue
+		conn, err := dialer()
+		if err != nil {
+			s.updateStatFunc(func(stat *Statistics) {
+				stat.DialFailures++
+			})
+			s.handleError(ioterr.New(err, "dialing to destination"))
+			s.reset(k)
+			return
 		}
-		switch m.Type {
-		case msg.Message_STREAM_START:
-			conn, err := dialer()
-			if err != nil {
-				if eh != nil {
-					eh.HandleError(ioterr.New(err, "dialing to destination"))
-				}
-				continue
-			}
-
-			muConns.Lock()
-			conns[m.StreamId] = conn
-			muConns.Unlock()
-			go func() {
-				readProxy(ws, conn, m.StreamId, eh)
-				muConns.Lock()
-				if conn, ok := conns[m.StreamId]; ok {
-					_ = conn.Close()
-					delete(conns, m.StreamId)
-				}
-				muConns.Unlock()
-				updateStat()
-			}()
-
-		case msg.Message_STREAM_RESET:
-			muConns.Lock()
-			if conn, ok := conns[m.StreamId]; ok {
-				_ = conn.Close()
-				delete(conns, m.StreamId)
-			}
-			muConns.Unlock()
-
-		case msg.Message_SESSION_RESET:
-			muConns.Lock()
-			for id, c := range conns {
-				_ = c.Close()
-				delete(conns, id)
-			}
-			muConns.Unlock()
-			return io.EOF
+		if s.add(k, conn) {
+			s.start(k, conn)
+		}
+	})
+}
 
-		case msg.Message_DATA:
-			muConns.Lock()
-			conn, ok := conns[m.StreamId]
-			muConns.Unlock()
-			if ok {
-				if _, err := conn.Write(m.Payload); err != nil {
-					eh.HandleError(ioterr.New(err, "writing message"))
-				}
-			}
+// lookupDialer returns Dialer of the service.
+// Dialer registered with empty service ID is used as a fallback.
+// ProtocolV1 has no service ID and the only Dialer is used.
+func lookupDialer(dialers map[string]Dialer, version ProtocolVersion, serviceID string) (Dialer, bool) {
+	if version == ProtocolV1 && len(dialers) == 1 {
+		for _, d := range dialers {
+			return d, true
 		}
-		updateStat()
 	}
+	if d, ok := dialers[serviceID]; ok {
+		return d, true
+	}
+	if d, ok := dialers[""]; ok {
+		return d, true
+	}
+	return nil, false
 }
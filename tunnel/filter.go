@@ -0,0 +1,100 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTunnelRejected indicate that the tunnel notification is rejected by
+// NotificationFilter.
+var ErrTunnelRejected = errors.New("tunnel rejected")
+
+// NotificationFilter decides whether to accept the tunnel notification.
+// Returning error rejects the tunnel.
+// The filter is called before connecting to the proxy endpoint and
+// may block, e.g. to wait for an approval.
+// ctx is canceled on Tunnel.Close.
+type NotificationFilter func(ctx context.Context, n *Notification) error
+
+// WithNotificationFilter appends NotificationFilters.
+// Rejected notifications are reported to the error handler
+// with ErrTunnelRejected.
+func WithNotificationFilter(f ...NotificationFilter) Option {
+	return func(o *Options) error {
+		o.NotificationFilters = append(o.NotificationFilters, f...)
+		return nil
+	}
+}
+
+// AllowRegions returns NotificationFilter accepting the tunnels of the regions.
+func AllowRegions(regions ...string) NotificationFilter {
+	allowed := make(map[string]struct{}, len(regions))
+	for _, r := range regions {
+		allowed[r] = struct{}{}
+	}
+	return func(ctx context.Context, n *Notification) error {
+		if _, ok := allowed[n.Region]; !ok {
+			return fmt.Errorf("region %q is not allowed", n.Region)
+		}
+		return nil
+	}
+}
+
+// AllowServices returns NotificationFilter accepting the tunnels
+// only if all requested services are allowed.
+func AllowServices(services ...string) NotificationFilter {
+	allowed := make(map[string]struct{}, len(services))
+	for _, s := range services {
+		allowed[s] = struct{}{}
+	}
+	return func(ctx context.Context, n *Notification) error {
+		for _, s := range n.Services {
+			if _, ok := allowed[s]; !ok {
+				return fmt.Errorf("service %q is not allowed", s)
+			}
+		}
+		return nil
+	}
+}
+
+// AllowTimeWindow returns NotificationFilter accepting the tunnels
+// during the time window of the day.
+// from and to are the durations from midnight in the location.
+// If from is later than to, the window wraps over midnight.
+func AllowTimeWindow(from, to time.Duration, loc *time.Location) NotificationFilter {
+	return allowTimeWindow(from, to, loc, time.Now)
+}
+
+func allowTimeWindow(from, to time.Duration, loc *time.Location, now func() time.Time) NotificationFilter {
+	return func(ctx context.Context, n *Notification) error {
+		t := now().In(loc)
+		y, m, d := t.Date()
+		tod := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, loc))
+		var ok bool
+		if from <= to {
+			ok = from <= tod && tod < to
+		} else {
+			ok = from <= tod || tod < to
+		}
+		if !ok {
+			return fmt.Errorf("out of time window %v-%v", from, to)
+		}
+		return nil
+	}
+}
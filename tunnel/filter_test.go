@@ -0,0 +1,119 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	mqtt "github.com/at-wat/mqtt-go"
+	mockmqtt "github.com/at-wat/mqtt-go/mock"
+)
+
+func TestNotificationFilter(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*3600)
+	at := func(h, m int) func() time.Time {
+		return func() time.Time {
+			return time.Date(2026, 1, 1, h, m, 0, 0, tokyo).UTC()
+		}
+	}
+	n := &Notification{Region: "ap-northeast-1", Services: []string{"SSH", "HTTP"}}
+
+	testCases := map[string]struct {
+		filter NotificationFilter
+		ok     bool
+	}{
+		"RegionAllowed":    {filter: AllowRegions("us-east-1", "ap-northeast-1"), ok: true},
+		"RegionDenied":     {filter: AllowRegions("us-east-1")},
+		"ServicesAllowed":  {filter: AllowServices("SSH", "HTTP", "VNC"), ok: true},
+		"ServiceDenied":    {filter: AllowServices("SSH")},
+		"InWindow":         {filter: allowTimeWindow(9*time.Hour, 17*time.Hour, tokyo, at(9, 0)), ok: true},
+		"BeforeWindow":     {filter: allowTimeWindow(9*time.Hour, 17*time.Hour, tokyo, at(8, 59))},
+		"AfterWindow":      {filter: allowTimeWindow(9*time.Hour, 17*time.Hour, tokyo, at(17, 0))},
+		"InWrappedWindow":  {filter: allowTimeWindow(22*time.Hour, 6*time.Hour, tokyo, at(1, 0)), ok: true},
+		"OutWrappedWindow": {filter: allowTimeWindow(22*time.Hour, 6*time.Hour, tokyo, at(12, 0))},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			err := tt.filter(context.Background(), n)
+			if tt.ok && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Error("Notification must be rejected")
+			}
+		})
+	}
+}
+
+func TestTunnel_NotificationFilter(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	chFiltered := make(chan *Notification, 1)
+	cli := &mockDevice{mockClient: &mockmqtt.Client{}}
+	tu, err := New(ctx, cli,
+		map[string]Dialer{
+			"SSH": func() (io.ReadWriteCloser, error) { return nil, errConnect },
+		},
+		WithNotificationFilter(
+			func(ctx context.Context, n *Notification) error {
+				chFiltered <- n
+				return nil
+			},
+			AllowRegions("us-east-1"),
+		),
+		func(opts *Options) error {
+			opts.EndpointHostFunc = func(string) string {
+				t.Error("Proxy must not be started")
+				return ""
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tu.Close()
+	chErr := make(chan error, 1)
+	tu.OnError(func(err error) { chErr <- err })
+	cli.Handle(tu)
+
+	cli.Serve(&mqtt.Message{
+		Topic:   "$aws/things/test/tunnels/notify",
+		Payload: []byte(`{"clientMode": "destination", "region": "ap-northeast-1", "services": ["SSH"]}`),
+	})
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("Timeout")
+	case n := <-chFiltered:
+		if n.Region != "ap-northeast-1" {
+			t.Errorf("Expected region: ap-northeast-1, got: %s", n.Region)
+		}
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatal("Timeout")
+	case err := <-chErr:
+		if !errors.Is(err, ErrTunnelRejected) {
+			t.Errorf("Expected error: %v, got: %v", ErrTunnelRejected, err)
+		}
+	}
+}
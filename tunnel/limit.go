@@ -0,0 +1,140 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrStreamLimitExceeded indicate that the stream is rejected since
+// the number of concurrent connections of the service exceeds the limit.
+var ErrStreamLimitExceeded = errors.New("stream limit exceeded")
+
+// ErrStreamRateLimited indicate that the stream is rejected since
+// the streams of the service are started too frequently.
+var ErrStreamRateLimited = errors.New("stream rate limited")
+
+// StreamLimit represents limits of the streams of the service on the destination.
+type StreamLimit struct {
+	// MaxConns is a maximum number of concurrent local connections.
+	// Zero means unlimited.
+	MaxConns int
+	// Interval is an average interval of the stream starts.
+	// Zero means unlimited.
+	Interval time.Duration
+	// Burst is a number of the stream starts allowed at once.
+	// Treated as 1 if Interval is set and Burst is less than 1.
+	Burst int
+}
+
+// WithStreamLimit sets the limits of the streams of the service.
+// Limit set to the empty service ID is applied to each service without
+// dedicated limit and on ProtocolV1.
+// Both MaxConns and the rate are counted per service.
+// The rate is kept across the reconnects enabled by WithReconnect.
+// Rejected streams are reset and reported to ErrorHandler.
+func WithStreamLimit(serviceID string, l StreamLimit) ProxyOption {
+	return func(opt *ProxyOptions) error {
+		if opt.StreamLimits == nil {
+			opt.StreamLimits = make(map[string]StreamLimit)
+		}
+		opt.StreamLimits[serviceID] = l
+		return nil
+	}
+}
+
+// tokenBucket is a rate limiter refilled every interval up to burst.
+type tokenBucket struct {
+	interval time.Duration
+	burst    int
+	tokens   float64
+	last     time.Time
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	burst := b.burst
+	if burst < 1 {
+		burst = 1
+	}
+	if b.last.IsZero() {
+		b.tokens = float64(burst)
+	} else {
+		b.tokens += float64(now.Sub(b.last)) / float64(b.interval)
+		if b.tokens > float64(burst) {
+			b.tokens = float64(burst)
+		}
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// streamLimiter applies StreamLimits to the stream starts.
+type streamLimiter struct {
+	limits map[string]StreamLimit
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newStreamLimiter(limits map[string]StreamLimit) *streamLimiter {
+	return &streamLimiter{
+		limits:  limits,
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+func (l *streamLimiter) limit(serviceID string) (StreamLimit, bool) {
+	if lim, ok := l.limits[serviceID]; ok {
+		return lim, true
+	}
+	lim, ok := l.limits[""]
+	return lim, ok
+}
+
+// check returns error if the new connection of the service is not allowed.
+// conns is a number of the current connections of the service.
+func (l *streamLimiter) check(serviceID string, conns int) error {
+	if l == nil {
+		return nil
+	}
+	lim, ok := l.limit(serviceID)
+	if !ok {
+		return nil
+	}
+	if lim.MaxConns > 0 && conns >= lim.MaxConns {
+		return ErrStreamLimitExceeded
+	}
+	if lim.Interval > 0 {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		b, ok := l.buckets[serviceID]
+		if !ok {
+			b = &tokenBucket{interval: lim.Interval, burst: lim.Burst}
+			l.buckets[serviceID] = b
+		}
+		if !b.allow(l.now()) {
+			return ErrStreamRateLimited
+		}
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/tunnel/msg"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := &tokenBucket{interval: time.Second, burst: 2}
+	steps := []struct {
+		elapsed time.Duration
+		allowed bool
+	}{
+		{0, true},
+		{0, true},
+		{0, false},
+		{500 * time.Millisecond, false},
+		{500 * time.Millisecond, true},
+		{10 * time.Second, true},
+		{0, true},
+		{0, false},
+	}
+	for i, s := range steps {
+		now = now.Add(s.elapsed)
+		if allowed := b.allow(now); allowed != s.allowed {
+			t.Errorf("Step %d: expected %v, got %v", i, s.allowed, allowed)
+		}
+	}
+}
+
+func TestStreamLimiter(t *testing.T) {
+	type start struct {
+		serviceID string
+		conns     int
+		err       error
+	}
+	testCases := map[string]struct {
+		limits map[string]StreamLimit
+		starts []start
+	}{
+		"NoLimit": {
+			starts: []start{{serviceID: "SSH", conns: 100}},
+		},
+		"MaxConns": {
+			limits: map[string]StreamLimit{"SSH": {MaxConns: 2}},
+			starts: []start{
+				{serviceID: "SSH", conns: 1},
+				{serviceID: "SSH", conns: 2, err: ErrStreamLimitExceeded},
+				{serviceID: "HTTP", conns: 2},
+			},
+		},
+		"FallbackRatePerService": {
+			limits: map[string]StreamLimit{"": {Interval: time.Hour}},
+			starts: []start{
+				{serviceID: "SSH"},
+				{serviceID: "SSH", err: ErrStreamRateLimited},
+				{serviceID: "HTTP"},
+				{serviceID: "HTTP", err: ErrStreamRateLimited},
+			},
+		},
+		"DedicatedLimit": {
+			limits: map[string]StreamLimit{
+				"":    {Interval: time.Hour},
+				"SSH": {MaxConns: 1},
+			},
+			starts: []start{
+				{serviceID: "SSH"},
+				{serviceID: "SSH"},
+				{serviceID: "SSH", conns: 1, err: ErrStreamLimitExceeded},
+				{serviceID: "HTTP"},
+				{serviceID: "HTTP", err: ErrStreamRateLimited},
+			},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			l := newStreamLimiter(tt.limits)
+			now := time.Unix(0, 0)
+			l.now = func() time.Time { return now }
+			for i, s := range tt.starts {
+				if err := l.check(s.serviceID, s.conns); !errors.Is(err, s.err) {
+					t.Errorf("Start %d: expected error: %v, got: %v", i, s.err, err)
+				}
+			}
+		})
+	}
+}
+
+func TestProxyDestination_StreamLimit(t *testing.T) {
+	testCases := map[string]struct {
+		version ProtocolVersion
+		limits  map[string]StreamLimit
+		start   []*msg.Message
+		reset   *msg.Message
+		err     error
+	}{
+		"MaxConnsV1": {
+			version: ProtocolV1,
+			limits:  map[string]StreamLimit{"": {MaxConns: 1}},
+			start: []*msg.Message{
+				{Type: msg.Message_STREAM_START, StreamId: 1},
+				{Type: msg.Message_STREAM_START, StreamId: 2},
+			},
+			reset: &msg.Message{Type: msg.Message_STREAM_RESET, StreamId: 2},
+			err:   ErrStreamLimitExceeded,
+		},
+		"MaxConnsV3": {
+			version: ProtocolV3,
+			limits:  map[string]StreamLimit{"SSH": {MaxConns: 1}},
+			start: []*msg.Message{
+				{Type: msg.Message_STREAM_START, ServiceId: "SSH", StreamId: 1, ConnectionId: 1},
+				{Type: msg.Message_CONNECTION_START, ServiceId: "SSH", StreamId: 1, ConnectionId: 2},
+			},
+			reset: &msg.Message{Type: msg.Message_CONNECTION_RESET, ServiceId: "SSH", StreamId: 1, ConnectionId: 2},
+			err:   ErrStreamLimitExceeded,
+		},
+		"Rate": {
+			version: ProtocolV2,
+			limits:  map[string]StreamLimit{"": {Interval: time.Hour}},
+			start: []*msg.Message{
+				{Type: msg.Message_STREAM_START, ServiceId: "SSH", StreamId: 1},
+				{Type: msg.Message_STREAM_START, ServiceId: "SSH", StreamId: 2},
+			},
+			reset: &msg.Message{Type: msg.Message_STREAM_RESET, ServiceId: "SSH", StreamId: 2},
+			err:   ErrStreamRateLimited,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			tca, tcb := net.Pipe()
+
+			var wg sync.WaitGroup
+			defer wg.Wait()
+
+			var conns []net.Conn
+			defer func() {
+				for _, c := range conns {
+					_ = c.Close()
+				}
+			}()
+			dialer := func() (io.ReadWriteCloser, error) {
+				ca, cb := net.Pipe()
+				conns = append(conns, ca)
+				return cb, nil
+			}
+
+			stat := NewStat()
+			chErr := make(chan error, 1)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := proxyDestinationServices(context.Background(), tca, tt.version,
+					map[string]Dialer{"": dialer},
+					newStreamLimiter(tt.limits),
+					ErrorHandlerFunc(func(err error) { chErr <- err }),
+					stat,
+				)
+				if err != nil {
+					t.Error(err)
+				}
+			}()
+
+			for _, m := range tt.start {
+				writeTestMessage(t, tcb, m)
+			}
+			expectTestMessage(t, tcb, tt.reset)
+			select {
+			case <-time.After(time.Second):
+				t.Fatal("Timeout")
+			case err := <-chErr:
+				if !errors.Is(err, tt.err) {
+					t.Errorf("Expected error: %v, got: %v", tt.err, err)
+				}
+			}
+			if err := tcb.Close(); err != nil {
+				t.Fatal(err)
+			}
+			wg.Wait()
+			if s := stat.Statistics(); s.StreamsStarted != 1 || s.StreamsRejected != 1 {
+				t.Errorf("Expected 1 started and 1 rejected, got: %+v", s)
+			}
+		})
+	}
+}
+
+func TestProxyDestination_StreamLimitReconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	chConn := make(chan *websocket.Conn)
+	endpoint := newTestTunnelServer(t, func(ws *websocket.Conn) {
+		chConn <- ws
+		<-ctx.Done()
+	})
+	recv := func() *websocket.Conn {
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		case ws := <-chConn:
+			return ws
+		}
+		return nil
+	}
+
+	chDial := make(chan struct{}, 2)
+	dialer := func() (io.ReadWriteCloser, error) {
+		chDial <- struct{}{}
+		_, cb := net.Pipe()
+		return cb, nil
+	}
+	chErr := make(chan error, 10)
+	go func() {
+		_ = ProxyDestinationContext(ctx, dialer, endpoint, "token",
+			func(opt *ProxyOptions) error {
+				opt.Scheme = "ws"
+				return nil
+			},
+			WithReconnect(ReconnectPolicy{Wait: 10 * time.Millisecond}),
+			WithStreamLimit("", StreamLimit{Interval: time.Hour}),
+			WithErrorHandler(ErrorHandlerFunc(func(err error) { chErr <- err })),
+		)
+	}()
+
+	ws := recv()
+	writeTestMessage(t, ws, &msg.Message{Type: msg.Message_STREAM_START, StreamId: 1})
+	select {
+	case <-ctx.Done():
+		t.Fatal("Timeout")
+	case <-chDial:
+	}
+
+	// Reconnect must not refill the bucket.
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ws = recv()
+	writeTestMessage(t, ws, &msg.Message{Type: msg.Message_STREAM_START, StreamId: 2})
+	expectTestMessage(t, ws, &msg.Message{Type: msg.Message_STREAM_RESET, StreamId: 2})
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		case err := <-chErr:
+			if errors.Is(err, ErrReconnecting) {
+				continue
+			}
+			if !errors.Is(err, ErrStreamRateLimited) {
+				t.Errorf("Expected error: %v, got: %v", ErrStreamRateLimited, err)
+			}
+		}
+		break
+	}
+	select {
+	case <-chDial:
+		t.Error("Rate limited stream must not be dialed")
+	default:
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// MetricsHandler exposes the proxy statistics in Prometheus text format.
+type MetricsHandler struct {
+	stats map[string]Stat
+	now   func() time.Time
+}
+
+// NewMetricsHandler creates http.Handler exposing the statistics in
+// Prometheus/OpenMetrics compatible text format.
+// Key of the map is exported as "tunnel" label to distinguish the proxies.
+// Active streams are aggregated by the service ID; per-stream details
+// are available in Statistics.Streams.
+func NewMetricsHandler(stats map[string]Stat) *MetricsHandler {
+	return &MetricsHandler{
+		stats: stats,
+		now:   time.Now,
+	}
+}
+
+type metric struct {
+	name, typ, help string
+	values          func(s *Statistics, now time.Time) []metricValue
+}
+
+type metricValue struct {
+	labels [][2]string
+	value  float64
+}
+
+func single(v float64) []metricValue {
+	return []metricValue{{value: v}}
+}
+
+func directions(sent, received uint64) []metricValue {
+	return []metricValue{
+		{labels: [][2]string{{"direction", "sent"}}, value: float64(sent)},
+		{labels: [][2]string{{"direction", "received"}}, value: float64(received)},
+	}
+}
+
+// perService aggregates the active streams by the service ID.
+// Streams are not exported individually to keep the number of the series bounded.
+func perService(s *Statistics, fn func([]StreamStatistics) float64) []metricValue {
+	streams := make(map[string][]StreamStatistics)
+	var ids []string
+	for _, st := range s.Streams {
+		if _, ok := streams[st.ServiceID]; !ok {
+			ids = append(ids, st.ServiceID)
+		}
+		streams[st.ServiceID] = append(streams[st.ServiceID], st)
+	}
+	sort.Strings(ids)
+	ret := make([]metricValue, 0, len(ids))
+	for _, id := range ids {
+		ret = append(ret, metricValue{
+			labels: [][2]string{{"service_id", id}},
+			value:  fn(streams[id]),
+		})
+	}
+	return ret
+}
+
+var metrics = []metric{
+	{
+		name: "tunnel_connections", typ: "gauge",
+		help: "Number of active local connections.",
+		values: func(s *Statistics, _ time.Time) []metricValue {
+			return single(float64(s.NumConn))
+		},
+	},
+	{
+		name: "tunnel_bytes_total", typ: "counter",
+		help: "Total payload bytes of the data messages.",
+		values: func(s *Statistics, _ time.Time) []metricValue {
+			return directions(s.BytesSent, s.BytesReceived)
+		},
+	},
+	{
+		name: "tunnel_messages_total", typ: "counter",
+		help: "Total number of the tunnel messages.",
+		values: func(s *Statistics, _ time.Time) []metricValue {
+			return directions(s.MessagesSent, s.MessagesReceived)
+		},
+	},
+	{
+		name: "tunnel_stream_starts_total", typ: "counter",
+		help: "Total number of started streams and connections.",
+		values: func(s *Statistics, _ time.Time) []metricValue {
+			return single(float64(s.StreamsStarted))
+		},
+	},
+	{
+		name: "tunnel_stream_resets_total", typ: "counter",
+		help: "Total number of stream and connection resets.",
+		values: func(s *Statistics, _ time.Time) []metricValue {
+			return directions(s.StreamResetsSent, s.StreamResetsReceived)
+		},
+	},
+	{
+		name: "tunnel_dial_failures_total", typ: "counter",
+		help: "Total number of failures of dialing to the local destination.",
+		values: func(s *Statistics, _ time.Time) []metricValue {
+			return single(float64(s.DialFailures))
+		},
+	},
+	{
+		name: "tunnel_stream_rejections_total", typ: "counter",
+		help: "Total number of streams and connections rejected by the limits.",
+		values: func(s *Statistics, _ time.Time) []metricValue {
+			return single(float64(s.StreamsRejected))
+		},
+	},
+	{
+		name: "tunnel_reconnects_total", typ: "counter",
+		help: "Total number of reconnections to the proxy endpoint.",
+		values: func(s *Statistics, _ time.Time) []metricValue {
+			return single(float64(s.Reconnects))
+		},
+	},
+	{
+		name: "tunnel_ping_rtt_seconds", typ: "gauge",
+		help: "Round trip time of the last WebSocket ping.",
+		values: func(s *Statistics, _ time.Time) []metricValue {
+			return single(s.LastPingRTT.Seconds())
+		},
+	},
+	{
+		name: "tunnel_uptime_seconds", typ: "gauge",
+		help: "Uptime of the WebSocket connection. Zero if disconnected.",
+		values: func(s *Statistics, now time.Time) []metricValue {
+			if s.ConnectedAt.IsZero() {
+				return single(0)
+			}
+			return single(now.Sub(s.ConnectedAt).Seconds())
+		},
+	},
+	{
+		name: "tunnel_service_connections", typ: "gauge",
+		help: "Number of active local connections of the service.",
+		values: func(s *Statistics, _ time.Time) []metricValue {
+			return perService(s, func(streams []StreamStatistics) float64 {
+				return float64(len(streams))
+			})
+		},
+	},
+	{
+		name: "tunnel_service_oldest_connection_age_seconds", typ: "gauge",
+		help: "Elapsed time since the oldest active local connection of the service is started.",
+		values: func(s *Statistics, now time.Time) []metricValue {
+			return perService(s, func(streams []StreamStatistics) float64 {
+				var age time.Duration
+				for _, st := range streams {
+					if d := now.Sub(st.StartedAt); d > age {
+						age = d
+					}
+				}
+				return age.Seconds()
+			})
+		},
+	},
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeMetricValue(buf *bytes.Buffer, name string, labels [][2]string, v float64) {
+	buf.WriteString(name)
+	if len(labels) > 0 {
+		buf.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(buf, `%s="%s"`, l[0], labelValueEscaper.Replace(l[1]))
+		}
+		buf.WriteByte('}')
+	}
+	fmt.Fprintf(buf, " %g\n", v)
+}
+
+// ServeHTTP implements http.Handler.
+func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(h.stats))
+	for name := range h.stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	stats := make([]Statistics, len(names))
+	for i, name := range names {
+		stats[i] = h.stats[name].Statistics()
+	}
+	now := h.now()
+
+	buf := &bytes.Buffer{}
+	for _, m := range metrics {
+		fmt.Fprintf(buf, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(buf, "# TYPE %s %s\n", m.name, m.typ)
+		for i, name := range names {
+			for _, v := range m.values(&stats[i], now) {
+				labels := append([][2]string{{"tunnel", name}}, v.labels...)
+				writeMetricValue(buf, m.name, labels, v.value)
+			}
+		}
+	}
+	w.Header().Set("Content-Type", metricsContentType)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,133 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	now := time.Unix(1000, 0)
+	stat := NewStat()
+	stat.Update(func(s *Statistics) {
+		*s = Statistics{
+			NumConn:              3,
+			BytesSent:            100,
+			BytesReceived:        200,
+			MessagesSent:         3,
+			MessagesReceived:     4,
+			StreamsStarted:       2,
+			StreamResetsSent:     1,
+			StreamResetsReceived: 0,
+			DialFailures:         1,
+			StreamsRejected:      3,
+			Reconnects:           2,
+			ConnectedAt:          now.Add(-time.Minute),
+			LastPingRTT:          50 * time.Millisecond,
+			Streams: []StreamStatistics{
+				{
+					ServiceID:     `SSH"1`,
+					StreamID:      2,
+					ConnectionID:  1,
+					StartedAt:     now.Add(-10 * time.Second),
+					BytesSent:     10,
+					BytesReceived: 20,
+				},
+				{
+					ServiceID:    "HTTP",
+					StreamID:     1,
+					ConnectionID: 1,
+					StartedAt:    now.Add(-5 * time.Second),
+				},
+				{
+					ServiceID:    `SSH"1`,
+					StreamID:     2,
+					ConnectionID: 2,
+					StartedAt:    now.Add(-3 * time.Second),
+				},
+			},
+		}
+	})
+
+	h := NewMetricsHandler(map[string]Stat{"robot1": stat, "robot0": NewStat()})
+	h.now = func() time.Time { return now }
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != metricsContentType {
+		t.Errorf("Expected Content-Type: %s, got: %s", metricsContentType, ct)
+	}
+	expected := `# HELP tunnel_connections Number of active local connections.
+# TYPE tunnel_connections gauge
+tunnel_connections{tunnel="robot0"} 0
+tunnel_connections{tunnel="robot1"} 3
+# HELP tunnel_bytes_total Total payload bytes of the data messages.
+# TYPE tunnel_bytes_total counter
+tunnel_bytes_total{tunnel="robot0",direction="sent"} 0
+tunnel_bytes_total{tunnel="robot0",direction="received"} 0
+tunnel_bytes_total{tunnel="robot1",direction="sent"} 100
+tunnel_bytes_total{tunnel="robot1",direction="received"} 200
+# HELP tunnel_messages_total Total number of the tunnel messages.
+# TYPE tunnel_messages_total counter
+tunnel_messages_total{tunnel="robot0",direction="sent"} 0
+tunnel_messages_total{tunnel="robot0",direction="received"} 0
+tunnel_messages_total{tunnel="robot1",direction="sent"} 3
+tunnel_messages_total{tunnel="robot1",direction="received"} 4
+# HELP tunnel_stream_starts_total Total number of started streams and connections.
+# TYPE tunnel_stream_starts_total counter
+tunnel_stream_starts_total{tunnel="robot0"} 0
+tunnel_stream_starts_total{tunnel="robot1"} 2
+# HELP tunnel_stream_resets_total Total number of stream and connection resets.
+# TYPE tunnel_stream_resets_total counter
+tunnel_stream_resets_total{tunnel="robot0",direction="sent"} 0
+tunnel_stream_resets_total{tunnel="robot0",direction="received"} 0
+tunnel_stream_resets_total{tunnel="robot1",direction="sent"} 1
+tunnel_stream_resets_total{tunnel="robot1",direction="received"} 0
+# HELP tunnel_dial_failures_total Total number of failures of dialing to the local destination.
+# TYPE tunnel_dial_failures_total counter
+tunnel_dial_failures_total{tunnel="robot0"} 0
+tunnel_dial_failures_total{tunnel="robot1"} 1
+# HELP tunnel_stream_rejections_total Total number of streams and connections rejected by the limits.
+# TYPE tunnel_stream_rejections_total counter
+tunnel_stream_rejections_total{tunnel="robot0"} 0
+tunnel_stream_rejections_total{tunnel="robot1"} 3
+# HELP tunnel_reconnects_total Total number of reconnections to the proxy endpoint.
+# TYPE tunnel_reconnects_total counter
+tunnel_reconnects_total{tunnel="robot0"} 0
+tunnel_reconnects_total{tunnel="robot1"} 2
+# HELP tunnel_ping_rtt_seconds Round trip time of the last WebSocket ping.
+# TYPE tunnel_ping_rtt_seconds gauge
+tunnel_ping_rtt_seconds{tunnel="robot0"} 0
+tunnel_ping_rtt_seconds{tunnel="robot1"} 0.05
+# HELP tunnel_uptime_seconds Uptime of the WebSocket connection. Zero if disconnected.
+# TYPE tunnel_uptime_seconds gauge
+tunnel_uptime_seconds{tunnel="robot0"} 0
+tunnel_uptime_seconds{tunnel="robot1"} 60
+# HELP tunnel_service_connections Number of active local connections of the service.
+# TYPE tunnel_service_connections gauge
+tunnel_service_connections{tunnel="robot1",service_id="HTTP"} 1
+tunnel_service_connections{tunnel="robot1",service_id="SSH\"1"} 2
+# HELP tunnel_service_oldest_connection_age_seconds Elapsed time since the oldest active local connection of the service is started.
+# TYPE tunnel_service_oldest_connection_age_seconds gauge
+tunnel_service_oldest_connection_age_seconds{tunnel="robot1",service_id="HTTP"} 5
+tunnel_service_oldest_connection_age_seconds{tunnel="robot1",service_id="SSH\"1"} 10
+`
+	if out := w.Body.String(); out != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
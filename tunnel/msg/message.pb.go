@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.26.0
-// 	protoc        v3.6.1
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
 // source: message.proto
 
 package msg
@@ -11,6 +11,7 @@ import (
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	reflect "reflect"
 	sync "sync"
+	unsafe "unsafe"
 )
 
 const (
@@ -23,11 +24,14 @@ const (
 type Message_Type int32
 
 const (
-	Message_UNKNOWN       Message_Type = 0
-	Message_DATA          Message_Type = 1
-	Message_STREAM_START  Message_Type = 2
-	Message_STREAM_RESET  Message_Type = 3
-	Message_SESSION_RESET Message_Type = 4
+	Message_UNKNOWN          Message_Type = 0
+	Message_DATA             Message_Type = 1
+	Message_STREAM_START     Message_Type = 2
+	Message_STREAM_RESET     Message_Type = 3
+	Message_SESSION_RESET    Message_Type = 4
+	Message_SERVICE_IDS      Message_Type = 5
+	Message_CONNECTION_START Message_Type = 6
+	Message_CONNECTION_RESET Message_Type = 7
 )
 
 // Enum value maps for Message_Type.
@@ -38,13 +42,19 @@ var (
 		2: "STREAM_START",
 		3: "STREAM_RESET",
 		4: "SESSION_RESET",
+		5: "SERVICE_IDS",
+		6: "CONNECTION_START",
+		7: "CONNECTION_RESET",
 	}
 	Message_Type_value = map[string]int32{
-		"UNKNOWN":       0,
-		"DATA":          1,
-		"STREAM_START":  2,
-		"STREAM_RESET":  3,
-		"SESSION_RESET": 4,
+		"UNKNOWN":          0,
+		"DATA":             1,
+		"STREAM_START":     2,
+		"STREAM_RESET":     3,
+		"SESSION_RESET":    4,
+		"SERVICE_IDS":      5,
+		"CONNECTION_START": 6,
+		"CONNECTION_RESET": 7,
 	}
 )
 
@@ -76,23 +86,23 @@ func (Message_Type) EnumDescriptor() ([]byte, []int) {
 }
 
 type Message struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	Type      Message_Type `protobuf:"varint,1,opt,name=type,proto3,enum=msg.Message_Type" json:"type,omitempty"`
-	StreamId  int32        `protobuf:"varint,2,opt,name=streamId,proto3" json:"streamId,omitempty"`
-	Ignorable bool         `protobuf:"varint,3,opt,name=ignorable,proto3" json:"ignorable,omitempty"`
-	Payload   []byte       `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	Type                Message_Type           `protobuf:"varint,1,opt,name=type,proto3,enum=msg.Message_Type" json:"type,omitempty"`
+	StreamId            int32                  `protobuf:"varint,2,opt,name=streamId,proto3" json:"streamId,omitempty"`
+	Ignorable           bool                   `protobuf:"varint,3,opt,name=ignorable,proto3" json:"ignorable,omitempty"`
+	Payload             []byte                 `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`
+	ServiceId           string                 `protobuf:"bytes,5,opt,name=serviceId,proto3" json:"serviceId,omitempty"`
+	AvailableServiceIds []string               `protobuf:"bytes,6,rep,name=availableServiceIds,proto3" json:"availableServiceIds,omitempty"`
+	ConnectionId        uint32                 `protobuf:"varint,7,opt,name=connectionId,proto3" json:"connectionId,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
 }
 
 func (x *Message) Reset() {
 	*x = Message{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_message_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_message_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *Message) String() string {
@@ -103,7 +113,7 @@ func (*Message) ProtoMessage() {}
 
 func (x *Message) ProtoReflect() protoreflect.Message {
 	mi := &file_message_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -146,46 +156,65 @@ func (x *Message) GetPayload() []byte {
 	return nil
 }
 
-var File_message_proto protoreflect.FileDescriptor
+func (x *Message) GetServiceId() string {
+	if x != nil {
+		return x.ServiceId
+	}
+	return ""
+}
+
+func (x *Message) GetAvailableServiceIds() []string {
+	if x != nil {
+		return x.AvailableServiceIds
+	}
+	return nil
+}
 
-var file_message_proto_rawDesc = []byte{
-	0x0a, 0x0d, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
-	0x03, 0x6d, 0x73, 0x67, 0x22, 0xda, 0x01, 0x0a, 0x07, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
-	0x12, 0x25, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x11,
-	0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x2e, 0x54, 0x79, 0x70,
-	0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x74, 0x72, 0x65, 0x61,
-	0x6d, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x73, 0x74, 0x72, 0x65, 0x61,
-	0x6d, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x69, 0x67, 0x6e, 0x6f, 0x72, 0x61, 0x62, 0x6c, 0x65,
-	0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x69, 0x67, 0x6e, 0x6f, 0x72, 0x61, 0x62, 0x6c,
-	0x65, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x04, 0x20, 0x01,
-	0x28, 0x0c, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x22, 0x54, 0x0a, 0x04, 0x54,
-	0x79, 0x70, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00,
-	0x12, 0x08, 0x0a, 0x04, 0x44, 0x41, 0x54, 0x41, 0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x53, 0x54,
-	0x52, 0x45, 0x41, 0x4d, 0x5f, 0x53, 0x54, 0x41, 0x52, 0x54, 0x10, 0x02, 0x12, 0x10, 0x0a, 0x0c,
-	0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f, 0x52, 0x45, 0x53, 0x45, 0x54, 0x10, 0x03, 0x12, 0x11,
-	0x0a, 0x0d, 0x53, 0x45, 0x53, 0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x52, 0x45, 0x53, 0x45, 0x54, 0x10,
-	0x04, 0x42, 0x36, 0x5a, 0x34, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
-	0x73, 0x65, 0x71, 0x73, 0x65, 0x6e, 0x73, 0x65, 0x2f, 0x61, 0x77, 0x73, 0x2d, 0x69, 0x6f, 0x74,
-	0x2d, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x2d, 0x73, 0x64, 0x6b, 0x2d, 0x67, 0x6f, 0x2f, 0x74,
-	0x75, 0x6e, 0x6e, 0x65, 0x6c, 0x2f, 0x6d, 0x73, 0x67, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x33,
+func (x *Message) GetConnectionId() uint32 {
+	if x != nil {
+		return x.ConnectionId
+	}
+	return 0
 }
 
+var File_message_proto protoreflect.FileDescriptor
+
+const file_message_proto_rawDesc = "" +
+	"\n" +
+	"\rmessage.proto\x12\x03msg\"\x8c\x03\n" +
+	"\aMessage\x12%\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x11.msg.Message.TypeR\x04type\x12\x1a\n" +
+	"\bstreamId\x18\x02 \x01(\x05R\bstreamId\x12\x1c\n" +
+	"\tignorable\x18\x03 \x01(\bR\tignorable\x12\x18\n" +
+	"\apayload\x18\x04 \x01(\fR\apayload\x12\x1c\n" +
+	"\tserviceId\x18\x05 \x01(\tR\tserviceId\x120\n" +
+	"\x13availableServiceIds\x18\x06 \x03(\tR\x13availableServiceIds\x12\"\n" +
+	"\fconnectionId\x18\a \x01(\rR\fconnectionId\"\x91\x01\n" +
+	"\x04Type\x12\v\n" +
+	"\aUNKNOWN\x10\x00\x12\b\n" +
+	"\x04DATA\x10\x01\x12\x10\n" +
+	"\fSTREAM_START\x10\x02\x12\x10\n" +
+	"\fSTREAM_RESET\x10\x03\x12\x11\n" +
+	"\rSESSION_RESET\x10\x04\x12\x0f\n" +
+	"\vSERVICE_IDS\x10\x05\x12\x14\n" +
+	"\x10CONNECTION_START\x10\x06\x12\x14\n" +
+	"\x10CONNECTION_RESET\x10\aB6Z4github.com/seqsense/aws-iot-device-sdk-go/tunnel/msgb\x06proto3"
+
 var (
 	file_message_proto_rawDescOnce sync.Once
-	file_message_proto_rawDescData = file_message_proto_rawDesc
+	file_message_proto_rawDescData []byte
 )
 
 func file_message_proto_rawDescGZIP() []byte {
 	file_message_proto_rawDescOnce.Do(func() {
-		file_message_proto_rawDescData = protoimpl.X.CompressGZIP(file_message_proto_rawDescData)
+		file_message_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_message_proto_rawDesc), len(file_message_proto_rawDesc)))
 	})
 	return file_message_proto_rawDescData
 }
 
 var file_message_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
 var file_message_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
-var file_message_proto_goTypes = []interface{}{
+var file_message_proto_goTypes = []any{
 	(Message_Type)(0), // 0: msg.Message.Type
 	(*Message)(nil),   // 1: msg.Message
 }
@@ -203,25 +232,11 @@ func file_message_proto_init() {
 	if File_message_proto != nil {
 		return
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_message_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Message); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_message_proto_rawDesc,
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_message_proto_rawDesc), len(file_message_proto_rawDesc)),
 			NumEnums:      1,
 			NumMessages:   1,
 			NumExtensions: 0,
@@ -233,7 +248,6 @@ func file_message_proto_init() {
 		MessageInfos:      file_message_proto_msgTypes,
 	}.Build()
 	File_message_proto = out.File
-	file_message_proto_rawDesc = nil
 	file_message_proto_goTypes = nil
 	file_message_proto_depIdxs = nil
 }
@@ -15,6 +15,10 @@
 package tunnel
 
 import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -29,7 +33,137 @@ func pingUnmarshal(msg []byte, payloadType byte, v interface{}) (err error) {
 	return nil
 }
 
-func newPinger(ws *websocket.Conn, period time.Duration) func() {
+// pingTracker measures the round trip time of the ping.
+type pingTracker struct {
+	mu     sync.Mutex
+	sentAt time.Time
+	stat   Stat
+}
+
+func (t *pingTracker) sent() {
+	t.mu.Lock()
+	t.sentAt = time.Now()
+	t.mu.Unlock()
+}
+
+func (t *pingTracker) pong() {
+	t.mu.Lock()
+	sentAt := t.sentAt
+	t.sentAt = time.Time{}
+	t.mu.Unlock()
+	if sentAt.IsZero() || t.stat == nil {
+		return
+	}
+	rtt := time.Since(sentAt)
+	t.stat.Update(func(stat *Statistics) {
+		stat.LastPingRTT = rtt
+	})
+}
+
+// endOfHandshake terminates the HTTP response of the opening handshake.
+const endOfHandshake = "\r\n\r\n"
+
+// maxStatusLine is a maximum length of the stored status line.
+const maxStatusLine = 128
+
+// pongConn detects pong frames received on the WebSocket connection.
+// golang.org/x/net/websocket silently discards the control frames,
+// so the frame headers are parsed on the underlying connection.
+// The handshake response read before the first frame is skipped
+// except for the status line.
+type pongConn struct {
+	net.Conn
+	onPong func()
+
+	upgraded   bool
+	eoh        int // matched length of endOfHandshake
+	status     []byte
+	statusRead bool
+	hdr        []byte
+	remain     uint64
+}
+
+// statusCode returns the status code of the handshake response.
+// Zero is returned if the status line is not received.
+func (c *pongConn) statusCode() int {
+	f := strings.Fields(string(c.status))
+	if !c.statusRead || len(f) < 2 {
+		return 0
+	}
+	code, _ := strconv.Atoi(f[1])
+	return code
+}
+
+func (c *pongConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.parse(b[:n])
+	return n, err
+}
+
+func (c *pongConn) parse(b []byte) {
+	for !c.upgraded && len(b) > 0 {
+		switch {
+		case c.statusRead:
+		case b[0] == '\r' || b[0] == '\n':
+			c.statusRead = true
+		case len(c.status) < maxStatusLine:
+			c.status = append(c.status, b[0])
+		}
+		switch {
+		case b[0] == endOfHandshake[c.eoh]:
+			c.eoh++
+		case b[0] == endOfHandshake[0]:
+			c.eoh = 1
+		default:
+			c.eoh = 0
+		}
+		b = b[1:]
+		c.upgraded = c.eoh == len(endOfHandshake)
+	}
+	for len(b) > 0 {
+		if c.remain > 0 {
+			skip := uint64(len(b))
+			if skip > c.remain {
+				skip = c.remain
+			}
+			b = b[skip:]
+			c.remain -= skip
+			continue
+		}
+		c.hdr = append(c.hdr, b[0])
+		b = b[1:]
+		if len(c.hdr) < 2 {
+			continue
+		}
+		l := uint64(c.hdr[1] & 0x7f)
+		need := 2
+		switch l {
+		case 126:
+			need += 2
+		case 127:
+			need += 8
+		}
+		if c.hdr[1]&0x80 != 0 {
+			need += 4 // masking key
+		}
+		if len(c.hdr) < need {
+			continue
+		}
+		switch l {
+		case 126:
+			l = uint64(binary.BigEndian.Uint16(c.hdr[2:4]))
+		case 127:
+			l = binary.BigEndian.Uint64(c.hdr[2:10])
+		}
+		if c.hdr[0]&0x0f == websocket.PongFrame && c.onPong != nil {
+			c.onPong()
+		}
+		c.remain = l
+		c.hdr = c.hdr[:0]
+	}
+}
+
+func newPinger(ws *websocket.Conn, period time.Duration, tracker *pingTracker) func() {
 	var doneOnce sync.Once
 	done := make(chan struct{})
 
@@ -40,6 +174,9 @@ func newPinger(ws *websocket.Conn, period time.Duration) func() {
 			case <-done:
 				return
 			case <-time.After(period):
+				if tracker != nil {
+					tracker.sent()
+				}
 				_ = pingMessage.Send(ws, nil)
 			}
 		}
@@ -0,0 +1,156 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestPongConn(t *testing.T) {
+	long := make([]byte, 300)
+	frames := [][]byte{
+		{0x82, 0x03, 'a', 'b', 'c'}, // binary
+		{0x8A, 0x00},                // pong
+		append([]byte{0x82, 0x7E, 0x01, 0x2C}, long...), // binary with 16 bits length
+		{0x89, 0x81, 1, 2, 3, 4, 'x'},                   // masked ping
+		{0x8A, 0x01, 'p'},                               // pong with payload
+	}
+	stream := []byte("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=\r\n\r\n")
+	for _, f := range frames {
+		stream = append(stream, f...)
+	}
+
+	for _, chunk := range []int{1, 3, 7, len(stream)} {
+		var pongs int
+		c := &pongConn{onPong: func() { pongs++ }}
+		for p := 0; p < len(stream); p += chunk {
+			end := p + chunk
+			if end > len(stream) {
+				end = len(stream)
+			}
+			c.parse(stream[p:end])
+		}
+		if pongs != 2 {
+			t.Errorf("Chunk size %d: expected 2 pongs, got %d", chunk, pongs)
+		}
+	}
+}
+
+func TestPongConn_Handshake(t *testing.T) {
+	connCli, connSrv := net.Pipe()
+	defer connCli.Close()
+	defer connSrv.Close()
+	_ = connCli.SetDeadline(time.Now().Add(time.Second))
+	_ = connSrv.SetDeadline(time.Now().Add(time.Second))
+
+	go func() {
+		req, err := http.ReadRequest(bufio.NewReader(connSrv))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		h := sha1.New()
+		h.Write([]byte(req.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: websocket\r\n" +
+			"Connection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(h.Sum(nil)) + "\r\n\r\n"
+		// Send the handshake response and the frames at once.
+		b := append([]byte(resp),
+			0x82, 0x03, 'a', 'b', 'c', // binary
+			0x8A, 0x00, // pong
+			0x82, 0x02, 0x8A, 0x00, // binary looks like pong
+			0x8A, 0x01, 'p', // pong with payload
+			0x82, 0x01, 'z', // binary
+		)
+		if _, err := connSrv.Write(b); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	wsc, err := websocket.NewConfig("ws://localhost/", "http://localhost/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pongs int
+	ws, err := websocket.NewClient(wsc, &pongConn{Conn: connCli, onPong: func() { pongs++ }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"abc", "\x8A\x00", "z"} {
+		var msg []byte
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if string(msg) != expected {
+			t.Errorf("Expected message: %q, got: %q", expected, msg)
+		}
+	}
+	if pongs != 2 {
+		t.Errorf("Expected 2 pongs, got %d", pongs)
+	}
+}
+
+func TestPinger(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	endpoint := newTestTunnelServer(t, func(ws *websocket.Conn) {
+		_, _ = io.Copy(io.Discard, ws)
+	})
+
+	stat := NewStat()
+	opt, err := newProxyOptions(func(opt *ProxyOptions) error {
+		opt.Scheme = "ws"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker := &pingTracker{stat: stat}
+	ws, _, err := openProxyConn(ctx, endpoint, Destination, "token", opt, tracker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	go func() {
+		// Read to process control frames.
+		_, _ = io.Copy(io.Discard, ws)
+	}()
+
+	cancelPinger := newPinger(ws, 10*time.Millisecond, tracker)
+	defer cancelPinger()
+
+	for stat.Statistics().LastPingRTT == 0 {
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ProtocolVersion is a version of the secure tunneling protocol.
+type ProtocolVersion int
+
+// List of ProtocolVersions.
+const (
+	// ProtocolV1 supports single service and single connection per tunnel.
+	ProtocolV1 ProtocolVersion = 1
+	// ProtocolV2 supports multiple services multiplexed by the service ID.
+	ProtocolV2 ProtocolVersion = 2
+	// ProtocolV3 supports multiple simultaneous connections per service
+	// distinguished by the connection ID.
+	ProtocolV3 ProtocolVersion = 3
+)
+
+const subprotocolFormat = "aws.iot.securetunneling-%d.0"
+
+// ErrUnsupportedProtocol indicate that the requested protocol version is not supported.
+var ErrUnsupportedProtocol = errors.New("unsupported protocol version")
+
+// ErrMultipleServices indicate that multiple services are requested on
+// the protocol version supporting only single service.
+var ErrMultipleServices = errors.New("multiple services are not supported")
+
+// ErrUnknownService indicate that the requested service is not registered.
+var ErrUnknownService = errors.New("unknown service")
+
+var defaultProtocolVersions = []ProtocolVersion{ProtocolV3, ProtocolV2, ProtocolV1}
+
+// Subprotocol returns WebSocket subprotocol name of the version.
+func (v ProtocolVersion) Subprotocol() string {
+	return fmt.Sprintf(subprotocolFormat, int(v))
+}
+
+func (v ProtocolVersion) valid() bool {
+	return v >= ProtocolV1 && v <= ProtocolV3
+}
+
+// ParseSubprotocol returns ProtocolVersion of the WebSocket subprotocol name.
+func ParseSubprotocol(p string) (ProtocolVersion, bool) {
+	var v ProtocolVersion
+	if _, err := fmt.Sscanf(p, subprotocolFormat, &v); err != nil {
+		return 0, false
+	}
+	if !v.valid() || v.Subprotocol() != p {
+		return 0, false
+	}
+	return v, true
+}
+
+// negotiatedVersion returns the protocol version selected by the server.
+// Server not responding the subprotocol is treated as ProtocolV1.
+func negotiatedVersion(protocols []string) ProtocolVersion {
+	if len(protocols) == 1 {
+		if v, ok := ParseSubprotocol(protocols[0]); ok {
+			return v
+		}
+	}
+	return ProtocolV1
+}
@@ -0,0 +1,426 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/tunnel/msg"
+)
+
+func TestParseSubprotocol(t *testing.T) {
+	testCases := map[string]struct {
+		protocol string
+		expected ProtocolVersion
+		ok       bool
+	}{
+		"V1":          {protocol: "aws.iot.securetunneling-1.0", expected: ProtocolV1, ok: true},
+		"V2":          {protocol: "aws.iot.securetunneling-2.0", expected: ProtocolV2, ok: true},
+		"V3":          {protocol: "aws.iot.securetunneling-3.0", expected: ProtocolV3, ok: true},
+		"Unsupported": {protocol: "aws.iot.securetunneling-4.0"},
+		"Minor":       {protocol: "aws.iot.securetunneling-3.1"},
+		"Suffix":      {protocol: "aws.iot.securetunneling-3.0a"},
+		"Unknown":     {protocol: "chat"},
+		"Empty":       {},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			v, ok := ParseSubprotocol(tt.protocol)
+			if ok != tt.ok || v != tt.expected {
+				t.Errorf("Expected (%d, %v), got (%d, %v)", tt.expected, tt.ok, v, ok)
+			}
+			if ok && v.Subprotocol() != tt.protocol {
+				t.Errorf("Expected subprotocol: %s, got: %s", tt.protocol, v.Subprotocol())
+			}
+		})
+	}
+}
+
+func TestNegotiatedVersion(t *testing.T) {
+	testCases := map[string]struct {
+		protocols []string
+		expected  ProtocolVersion
+	}{
+		"NoResponse": {expected: ProtocolV1},
+		"V3":         {protocols: []string{"aws.iot.securetunneling-3.0"}, expected: ProtocolV3},
+		"V2":         {protocols: []string{"aws.iot.securetunneling-2.0"}, expected: ProtocolV2},
+		"Unknown":    {protocols: []string{"chat"}, expected: ProtocolV1},
+		"NotSelected": {
+			protocols: []string{"aws.iot.securetunneling-3.0", "aws.iot.securetunneling-2.0"},
+			expected:  ProtocolV1,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			if v := negotiatedVersion(tt.protocols); v != tt.expected {
+				t.Errorf("Expected version: %d, got: %d", tt.expected, v)
+			}
+		})
+	}
+}
+
+func writeTestMessage(t *testing.T, w io.Writer, m *msg.Message) {
+	t.Helper()
+	b, err := proto.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := len(b)
+	if _, err := w.Write(append([]byte{byte(l >> 8), byte(l)}, b...)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestMessage(t *testing.T, r io.Reader) *msg.Message {
+	t.Helper()
+	sz := make([]byte, 2)
+	if _, err := io.ReadFull(r, sz); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, int(sz[0])<<8|int(sz[1]))
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatal(err)
+	}
+	m := &msg.Message{}
+	if err := proto.Unmarshal(b, m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func expectTestMessage(t *testing.T, r io.Reader, expected *msg.Message) {
+	t.Helper()
+	if m := readTestMessage(t, r); !proto.Equal(expected, m) {
+		t.Errorf("Expected message: %v, got: %v", expected, m)
+	}
+}
+
+func expectTestPayload(t *testing.T, r io.Reader, expected string) {
+	t.Helper()
+	b := make([]byte, 100)
+	n, err := r.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[:n]) != expected {
+		t.Errorf("Expected payload: %s, got: %s", expected, string(b[:n]))
+	}
+}
+
+func TestProxyDestinationServices(t *testing.T) {
+	t.Run("MultipleServicesOnV1", func(t *testing.T) {
+		ca, _ := net.Pipe()
+		err := proxyDestinationServices(context.Background(), ca, ProtocolV1,
+			map[string]Dialer{"SSH": nil, "HTTP": nil}, nil, nil, nil,
+		)
+		if !errors.Is(err, ErrMultipleServices) {
+			t.Errorf("Expected error: %v, got: %v", ErrMultipleServices, err)
+		}
+	})
+	t.Run("V2", func(t *testing.T) {
+		tca, tcb := net.Pipe()
+		sshA, sshB := net.Pipe()
+		httpA, httpB := net.Pipe()
+		defer httpA.Close()
+
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := proxyDestinationServices(context.Background(), tca, ProtocolV2,
+				map[string]Dialer{
+					"SSH":  func() (io.ReadWriteCloser, error) { return sshB, nil },
+					"HTTP": func() (io.ReadWriteCloser, error) { return httpB, nil },
+				},
+				nil, nil, nil,
+			)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+
+		writeTestMessage(t, tcb, &msg.Message{Type: msg.Message_STREAM_START, ServiceId: "SSH", StreamId: 1})
+		writeTestMessage(t, tcb, &msg.Message{Type: msg.Message_STREAM_START, ServiceId: "HTTP", StreamId: 1})
+		writeTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_DATA, ServiceId: "HTTP", StreamId: 1, Payload: []byte("http payload"),
+		})
+		expectTestPayload(t, httpA, "http payload")
+		writeTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_DATA, ServiceId: "SSH", StreamId: 1, Payload: []byte("ssh payload"),
+		})
+		expectTestPayload(t, sshA, "ssh payload")
+
+		if _, err := httpA.Write([]byte("http response")); err != nil {
+			t.Fatal(err)
+		}
+		expectTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_DATA, ServiceId: "HTTP", StreamId: 1, Payload: []byte("http response"),
+		})
+
+		// Closing local connection resets the stream.
+		if err := sshA.Close(); err != nil {
+			t.Fatal(err)
+		}
+		expectTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_STREAM_RESET, ServiceId: "SSH", StreamId: 1,
+		})
+
+		if err := tcb.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("V3", func(t *testing.T) {
+		tca, tcb := net.Pipe()
+		c1A, c1B := net.Pipe()
+		c2A, c2B := net.Pipe()
+		defer c1A.Close()
+		conns := []net.Conn{c1B, c2B}
+
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := proxyDestinationServices(context.Background(), tca, ProtocolV3,
+				map[string]Dialer{
+					"SSH": func() (io.ReadWriteCloser, error) {
+						c := conns[0]
+						conns = conns[1:]
+						return c, nil
+					},
+				},
+				nil, nil, nil,
+			)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+
+		writeTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_STREAM_START, ServiceId: "SSH", StreamId: 1, ConnectionId: 1,
+		})
+		writeTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_CONNECTION_START, ServiceId: "SSH", StreamId: 1, ConnectionId: 2,
+		})
+		writeTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_DATA, ServiceId: "SSH", StreamId: 1, ConnectionId: 2, Payload: []byte("payload 2"),
+		})
+		expectTestPayload(t, c2A, "payload 2")
+		writeTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_DATA, ServiceId: "SSH", StreamId: 1, ConnectionId: 1, Payload: []byte("payload 1"),
+		})
+		expectTestPayload(t, c1A, "payload 1")
+
+		// Closing local connection resets only the connection.
+		if err := c2A.Close(); err != nil {
+			t.Fatal(err)
+		}
+		expectTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_CONNECTION_RESET, ServiceId: "SSH", StreamId: 1, ConnectionId: 2,
+		})
+
+		if _, err := c1A.Write([]byte("response 1")); err != nil {
+			t.Fatal(err)
+		}
+		expectTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_DATA, ServiceId: "SSH", StreamId: 1, ConnectionId: 1, Payload: []byte("response 1"),
+		})
+
+		if err := tcb.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("UnknownService", func(t *testing.T) {
+		dial := func() (io.ReadWriteCloser, error) { return nil, errConnect }
+		testCases := map[string]map[string]Dialer{
+			"MultipleServices": {"SSH": dial, "HTTP": dial},
+			"SingleService":    {"SSH": dial},
+		}
+		for name, dialers := range testCases {
+			dialers := dialers
+			t.Run(name, func(t *testing.T) {
+				tca, tcb := net.Pipe()
+
+				var wg sync.WaitGroup
+				defer wg.Wait()
+
+				chErr := make(chan error, 1)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := proxyDestinationServices(context.Background(), tca, ProtocolV2,
+						dialers,
+						nil,
+						ErrorHandlerFunc(func(err error) { chErr <- err }),
+						nil,
+					)
+					if err != nil {
+						t.Error(err)
+					}
+				}()
+
+				writeTestMessage(t, tcb, &msg.Message{Type: msg.Message_STREAM_START, ServiceId: "VNC", StreamId: 1})
+				expectTestMessage(t, tcb, &msg.Message{
+					Type: msg.Message_STREAM_RESET, ServiceId: "VNC", StreamId: 1,
+				})
+				select {
+				case <-time.After(time.Second):
+					t.Fatal("Timeout")
+				case err := <-chErr:
+					if !errors.Is(err, ErrUnknownService) {
+						t.Errorf("Expected error: %v, got: %v", ErrUnknownService, err)
+					}
+				}
+
+				if err := tcb.Close(); err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	})
+}
+
+func TestProxySourceServices(t *testing.T) {
+	t.Run("MultipleServicesOnV1", func(t *testing.T) {
+		ca, _ := net.Pipe()
+		err := proxySourceServices(context.Background(), ca, ProtocolV1,
+			map[string]net.Listener{"SSH": nil, "HTTP": nil}, nil, nil,
+		)
+		if !errors.Is(err, ErrMultipleServices) {
+			t.Errorf("Expected error: %v, got: %v", ErrMultipleServices, err)
+		}
+	})
+	t.Run("V2", func(t *testing.T) {
+		tca, tcb := net.Pipe()
+		ca, cb := net.Pipe()
+		defer ca.Close()
+
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		chDone := make(chan struct{})
+		defer close(chDone)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var i int
+			err := proxySourceServices(context.Background(), tca, ProtocolV2,
+				map[string]net.Listener{
+					"": acceptFunc(func() (net.Conn, error) {
+						if i > 0 {
+							<-chDone
+							return nil, errors.New("done")
+						}
+						i++
+						return cb, nil
+					}),
+				},
+				nil, nil,
+			)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+
+		// Listener without service ID is bound to the first available service.
+		writeTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_SERVICE_IDS, AvailableServiceIds: []string{"SSH", "HTTP"},
+		})
+		expectTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_STREAM_START, ServiceId: "SSH", StreamId: 1,
+		})
+		writeTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_DATA, ServiceId: "SSH", StreamId: 1, Payload: []byte("payload"),
+		})
+		expectTestPayload(t, ca, "payload")
+
+		if err := tcb.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("V3", func(t *testing.T) {
+		tca, tcb := net.Pipe()
+		c1A, c1B := net.Pipe()
+		c2A, c2B := net.Pipe()
+		defer c1A.Close()
+		defer c2A.Close()
+		conns := []net.Conn{c1B, c2B}
+
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		chDone := make(chan struct{})
+		defer close(chDone)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := proxySourceServices(context.Background(), tca, ProtocolV3,
+				map[string]net.Listener{
+					"SSH": acceptFunc(func() (net.Conn, error) {
+						if len(conns) == 0 {
+							<-chDone
+							return nil, errors.New("done")
+						}
+						c := conns[0]
+						conns = conns[1:]
+						return c, nil
+					}),
+				},
+				nil, nil,
+			)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+
+		// Second connection is added to the existing stream.
+		expectTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_STREAM_START, ServiceId: "SSH", StreamId: 1, ConnectionId: 1,
+		})
+		expectTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_CONNECTION_START, ServiceId: "SSH", StreamId: 1, ConnectionId: 2,
+		})
+		writeTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_DATA, ServiceId: "SSH", StreamId: 1, ConnectionId: 2, Payload: []byte("payload 2"),
+		})
+		expectTestPayload(t, c2A, "payload 2")
+
+		if _, err := c1A.Write([]byte("request 1")); err != nil {
+			t.Fatal(err)
+		}
+		expectTestMessage(t, tcb, &msg.Message{
+			Type: msg.Message_DATA, ServiceId: "SSH", StreamId: 1, ConnectionId: 1, Payload: []byte("request 1"),
+		})
+
+		if err := tcb.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
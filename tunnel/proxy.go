@@ -15,6 +15,7 @@
 package tunnel
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -31,13 +32,16 @@ import (
 const (
 	defaultEndpointHostFormat = "data.tunneling.iot.%s.amazonaws.com"
 	defaultPingPeriod         = 5 * time.Second
-	websocketProtocol         = "aws.iot.securetunneling-1.0"
 	userAgent                 = "aws-iot-device-sdk-go/tunnel"
 )
 
 // ErrUnsupportedScheme indicate that the requested protocol scheme is not supported.
 var ErrUnsupportedScheme = errors.New("unsupported scheme")
 
+// ErrHandshakeRejected indicates that the proxy endpoint rejected the
+// WebSocket handshake by 4xx status, e.g. the access token is no longer valid.
+var ErrHandshakeRejected = errors.New("handshake rejected")
+
 func endpointHost(region string) string {
 	return fmt.Sprintf(defaultEndpointHostFormat, region)
 }
@@ -49,53 +53,116 @@ type Dialer func() (io.ReadWriteCloser, error)
 // the local destination application via IoT secure tunneling.
 // This is usually used on IoT things.
 func ProxyDestination(dialer Dialer, endpoint, token string, opts ...ProxyOption) error {
-	ws, opt, err := openProxyConn(endpoint, "destination", token, opts...)
-	if err != nil {
-		return ioterr.New(err, "opening proxy destination")
-	}
+	return ProxyDestinationContext(context.Background(), dialer, endpoint, token, opts...)
+}
 
-	pingCancel := newPinger(ws, opt.PingPeriod)
-	defer pingCancel()
+// ProxyDestinationContext is ProxyDestination with context.
+// When ctx is canceled, active streams are reset and
+// all local connections are closed before returning ctx.Err().
+func ProxyDestinationContext(ctx context.Context, dialer Dialer, endpoint, token string, opts ...ProxyOption) error {
+	return ProxyDestinationServicesContext(ctx, map[string]Dialer{"": dialer}, endpoint, token, opts...)
+}
 
-	return proxyDestination(ws, dialer, opt.ErrorHandler, opt.Stat)
+// ProxyDestinationServices proxies TCP connections of multiple services
+// from remote source device to the local destination applications.
+// Dialer is selected by the service ID of the stream.
+// Dialer registered with empty service ID is used for the streams
+// of unregistered services.
+// Streams of other services are reset.
+// On ProtocolV1, the only Dialer is used regardless of the service ID.
+// Multiple services require ProtocolV2 or later.
+func ProxyDestinationServices(dialers map[string]Dialer, endpoint, token string, opts ...ProxyOption) error {
+	return ProxyDestinationServicesContext(context.Background(), dialers, endpoint, token, opts...)
+}
+
+// ProxyDestinationServicesContext is ProxyDestinationServices with context.
+func ProxyDestinationServicesContext(ctx context.Context, dialers map[string]Dialer, endpoint, token string, opts ...ProxyOption) error {
+	// Limiter is shared by the sessions on reconnect.
+	var limiter *streamLimiter
+	return runProxy(ctx, endpoint, Destination, token, opts,
+		func(ctx context.Context, ws *websocket.Conn, version ProtocolVersion, opt *ProxyOptions) error {
+			if limiter == nil {
+				limiter = newStreamLimiter(opt.StreamLimits)
+			}
+			return proxyDestinationServices(ctx, ws, version, dialers, limiter, opt.ErrorHandler, opt.Stat)
+		},
+	)
 }
 
 // ProxySource proxies TCP connection from local socket to
 // remote destination application via IoT secure tunneling.
 // This is usually used on a computer or bastion server.
+// The listener is closed on return.
 func ProxySource(listener net.Listener, endpoint, token string, opts ...ProxyOption) error {
-	ws, opt, err := openProxyConn(endpoint, "source", token, opts...)
-	if err != nil {
-		return ioterr.New(err, "opening proxy source")
-	}
+	return ProxySourceContext(context.Background(), listener, endpoint, token, opts...)
+}
 
-	pingCancel := newPinger(ws, opt.PingPeriod)
-	defer pingCancel()
+// ProxySourceContext is ProxySource with context.
+// When ctx is canceled, active streams are reset and
+// all local connections are closed before returning ctx.Err().
+func ProxySourceContext(ctx context.Context, listener net.Listener, endpoint, token string, opts ...ProxyOption) error {
+	return ProxySourceServicesContext(ctx, map[string]net.Listener{"": listener}, endpoint, token, opts...)
+}
+
+// ProxySourceServices proxies TCP connections from local sockets to
+// remote destination applications of the services.
+// Listener registered with empty service ID is used for the first service
+// notified by the server.
+// Multiple services require ProtocolV2 or later.
+// The listeners are closed on return.
+func ProxySourceServices(listeners map[string]net.Listener, endpoint, token string, opts ...ProxyOption) error {
+	return ProxySourceServicesContext(context.Background(), listeners, endpoint, token, opts...)
+}
 
-	return proxySource(ws, listener, opt.ErrorHandler, opt.Stat)
+// ProxySourceServicesContext is ProxySourceServices with context.
+func ProxySourceServicesContext(ctx context.Context, listeners map[string]net.Listener, endpoint, token string, opts ...ProxyOption) error {
+	// Listeners are shared by the sessions on reconnect and
+	// closed only once on return.
+	pls := make(map[string]*persistentListener, len(listeners))
+	for serviceID, l := range listeners {
+		pls[serviceID] = newPersistentListener(l)
+	}
+	defer func() {
+		for _, l := range pls {
+			_ = l.Close()
+		}
+	}()
+	return runProxy(ctx, endpoint, Source, token, opts,
+		func(ctx context.Context, ws *websocket.Conn, version ProtocolVersion, opt *ProxyOptions) error {
+			ls := make(map[string]net.Listener, len(pls))
+			for serviceID, l := range pls {
+				ls[serviceID] = l.session()
+			}
+			return proxySourceServices(ctx, ws, version, ls, opt.ErrorHandler, opt.Stat)
+		},
+	)
 }
 
-func openProxyConn(endpoint, mode, token string, opts ...ProxyOption) (*websocket.Conn, *ProxyOptions, error) {
+func newProxyOptions(opts ...ProxyOption) (*ProxyOptions, error) {
 	opt := &ProxyOptions{
-		Scheme:     "wss",
-		PingPeriod: defaultPingPeriod,
+		Scheme:           "wss",
+		PingPeriod:       defaultPingPeriod,
+		ProtocolVersions: defaultProtocolVersions,
 	}
 	for _, o := range opts {
 		if err := o(opt); err != nil {
-			return nil, nil, ioterr.New(err, "applying options")
+			return nil, ioterr.New(err, "applying options")
 		}
 	}
 
 	if err := opt.validate(); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
+	return opt, nil
+}
 
+func openProxyConn(ctx context.Context, endpoint string, mode ClientMode, token string, opt *ProxyOptions, tracker *pingTracker) (*websocket.Conn, ProtocolVersion, error) {
 	wsc, err := websocket.NewConfig(
 		fmt.Sprintf("%s://%s/tunnel?local-proxy-mode=%s", opt.Scheme, endpoint, mode),
 		fmt.Sprintf("https://%s", endpoint),
 	)
 	if err != nil {
-		return nil, nil, ioterr.New(err, "creating ws config")
+		return nil, 0, ioterr.New(err, "creating ws config")
 	}
 	if opt.Scheme == "wss" {
 		wsc.TlsConfig = &tls.Config{
@@ -108,14 +175,63 @@ func openProxyConn(endpoint, mode, token string, opts ...ProxyOption) (*websocke
 		"Access-Token": []string{token},
 		"User-Agent":   []string{userAgent},
 	}
-	wsc.Protocol = append(wsc.Protocol, websocketProtocol)
-	ws, err := websocket.DialConfig(wsc)
+	for _, v := range opt.ProtocolVersions {
+		wsc.Protocol = append(wsc.Protocol, v.Subprotocol())
+	}
+	ws, err := dialWebSocket(ctx, wsc, tracker)
 	if err != nil {
-		return nil, nil, ioterr.New(err, "creating ws dial config")
+		return nil, 0, ioterr.New(err, "creating ws dial config")
 	}
 	ws.PayloadType = websocket.BinaryFrame
 
-	return ws, opt, nil
+	return ws, negotiatedVersion(ws.Config().Protocol), nil
+}
+
+// dialWebSocket is websocket.Config.DialContext with pong frame detection.
+func dialWebSocket(ctx context.Context, wsc *websocket.Config, tracker *pingTracker) (*websocket.Conn, error) {
+	host := wsc.Location.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		switch wsc.Location.Scheme {
+		case "wss":
+			host += ":443"
+		default:
+			host += ":80"
+		}
+	}
+	var conn net.Conn
+	var err error
+	switch wsc.Location.Scheme {
+	case "wss":
+		conn, err = (&tls.Dialer{Config: wsc.TlsConfig}).DialContext(ctx, "tcp", host)
+	default:
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, &websocket.DialError{Config: wsc, Err: err}
+	}
+
+	// Abort the handshake on context cancel.
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Unix(1, 0))
+	})
+	pc := &pongConn{Conn: conn}
+	if tracker != nil {
+		pc.onPong = tracker.pong
+	}
+	ws, err := websocket.NewClient(wsc, pc)
+	if !stop() && err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		_ = conn.Close()
+		if code := pc.statusCode(); err == websocket.ErrBadStatus && code >= 400 && code < 500 {
+			return nil, fmt.Errorf("%w (status %d): %w",
+				ErrHandshakeRejected, code, &websocket.DialError{Config: wsc, Err: err},
+			)
+		}
+		return nil, &websocket.DialError{Config: wsc, Err: err}
+	}
+	return ws, nil
 }
 
 // ErrorHandler is an interface to handler error.
@@ -141,6 +257,17 @@ type ProxyOptions struct {
 	ErrorHandler       ErrorHandler
 	PingPeriod         time.Duration
 	Stat               Stat
+	// ProtocolVersions is a list of the protocol versions offered to the server
+	// in order of preference.
+	// If empty, no subprotocol is requested and ProtocolV1 is used.
+	ProtocolVersions []ProtocolVersion
+	// Reconnect enables reconnection on WebSocket disconnection if not nil.
+	Reconnect *ReconnectPolicy
+	// StateHandler is called on the proxy state change.
+	StateHandler func(ProxyState)
+	// StreamLimits stores the limits of the streams of each service
+	// on the destination.
+	StreamLimits map[string]StreamLimit
 }
 
 func (o *ProxyOptions) validate() error {
@@ -149,6 +276,14 @@ func (o *ProxyOptions) validate() error {
 	default:
 		return ioterr.New(ErrUnsupportedScheme, o.Scheme)
 	}
+	for _, v := range o.ProtocolVersions {
+		if !v.valid() {
+			return ioterr.Newf(ErrUnsupportedProtocol, "version %d", v)
+		}
+	}
+	if o.Reconnect != nil && o.Reconnect.Wait <= 0 {
+		return ioterr.New(ErrInvalidReconnectPolicy, "wait must be positive")
+	}
 	return nil
 }
 
@@ -175,3 +310,12 @@ func WithStat(stat Stat) ProxyOption {
 		return nil
 	}
 }
+
+// WithProtocolVersions sets the protocol versions offered to the server
+// in order of preference.
+func WithProtocolVersions(v ...ProtocolVersion) ProxyOption {
+	return func(opt *ProxyOptions) error {
+		opt.ProtocolVersions = v
+		return nil
+	}
+}
@@ -15,6 +15,7 @@
 package tunnel
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net"
@@ -118,6 +119,16 @@ func TestProxyDestination(t *testing.T) {
 				t.Errorf("message differes, expected: %v, got: %v", msgExpected, m)
 			}
 
+			if stat != nil {
+				s := stat.Statistics()
+				if s.NumConn != 1 {
+					t.Errorf("Expected NumConn: 1, got: %d", s.NumConn)
+				}
+				if len(s.Streams) != 1 || s.Streams[0].StreamID != 1 {
+					t.Errorf("Expected stream 1, got: %+v", s.Streams)
+				}
+			}
+
 			if err := tcb.Close(); err != nil {
 				t.Fatal(err)
 			}
@@ -129,15 +140,58 @@ func TestProxyDestination(t *testing.T) {
 			stat := NewStat()
 			test(t, stat)
 
+			// Connections are closed on the session end.
 			s := stat.Statistics()
 			expected := Statistics{
-				NumConn: 1,
+				NumConn:          0,
+				BytesSent:        13,
+				BytesReceived:    13,
+				MessagesSent:     1,
+				MessagesReceived: 2,
+				StreamsStarted:   1,
 			}
 			if !reflect.DeepEqual(expected, s) {
 				t.Errorf("Expected stat: %+v, got: %+v", expected, s)
 			}
 		})
 	})
+	t.Run("Cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tca, tcb := net.Pipe()
+		ca, cb := net.Pipe()
+
+		chErr := make(chan error, 1)
+		go func() {
+			chErr <- proxyDestinationServices(ctx, tca, ProtocolV1,
+				map[string]Dialer{"": func() (io.ReadWriteCloser, error) { return cb, nil }},
+				nil, nil, nil,
+			)
+		}()
+
+		writeTestMessage(t, tcb, &msg.Message{Type: msg.Message_STREAM_START, StreamId: 1})
+		writeTestMessage(t, tcb, &msg.Message{Type: msg.Message_DATA, StreamId: 1, Payload: []byte("payload")})
+		expectTestPayload(t, ca, "payload")
+
+		cancel()
+		expectTestMessage(t, tcb, &msg.Message{Type: msg.Message_STREAM_RESET, StreamId: 1})
+
+		select {
+		case <-time.After(time.Second):
+			t.Fatal("Timeout")
+		case err := <-chErr:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected error: %v, got: %v", context.Canceled, err)
+			}
+		}
+		if _, err := ca.Read(make([]byte, 1)); err != io.EOF {
+			t.Errorf("Local connection must be closed, got: %v", err)
+		}
+		if _, err := tcb.Read(make([]byte, 1)); err != io.EOF {
+			t.Errorf("Tunnel connection must be closed, got: %v", err)
+		}
+	})
 	t.Run("DialError", func(t *testing.T) {
 		tca, tcb := net.Pipe()
 
@@ -414,6 +468,16 @@ func TestProxySource(t *testing.T) {
 				t.Errorf("payload differs, expected: %s, got: %s", payload2, string(bRecv[:n]))
 			}
 
+			if stat != nil {
+				s := stat.Statistics()
+				if s.NumConn != 1 {
+					t.Errorf("Expected NumConn: 1, got: %d", s.NumConn)
+				}
+				if len(s.Streams) != 1 || s.Streams[0].StreamID != 1 {
+					t.Errorf("Expected stream 1, got: %+v", s.Streams)
+				}
+			}
+
 			// Check EOF
 			if err := tcb.Close(); err != nil {
 				t.Fatal(err)
@@ -426,15 +490,58 @@ func TestProxySource(t *testing.T) {
 			stat := NewStat()
 			test(t, stat)
 
+			// Connections are closed on the session end.
 			s := stat.Statistics()
 			expected := Statistics{
-				NumConn: 1,
+				NumConn:          0,
+				BytesSent:        13,
+				BytesReceived:    13,
+				MessagesSent:     2,
+				MessagesReceived: 1,
+				StreamsStarted:   1,
 			}
 			if !reflect.DeepEqual(expected, s) {
 				t.Errorf("Expected stat: %+v, got: %+v", expected, s)
 			}
 		})
 	})
+	t.Run("Cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tca, tcb := net.Pipe()
+		ca, cb := net.Pipe()
+
+		l := &closeListener{conns: []net.Conn{cb}, chClosed: make(chan struct{})}
+		chErr := make(chan error, 1)
+		go func() {
+			chErr <- proxySourceServices(ctx, tca, ProtocolV2,
+				map[string]net.Listener{"SSH": l}, nil, nil,
+			)
+		}()
+
+		expectTestMessage(t, tcb, &msg.Message{Type: msg.Message_STREAM_START, ServiceId: "SSH", StreamId: 1})
+
+		cancel()
+		expectTestMessage(t, tcb, &msg.Message{Type: msg.Message_STREAM_RESET, ServiceId: "SSH", StreamId: 1})
+
+		select {
+		case <-time.After(time.Second):
+			t.Fatal("Timeout")
+		case err := <-chErr:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected error: %v, got: %v", context.Canceled, err)
+			}
+		}
+		if _, err := ca.Read(make([]byte, 1)); err != io.EOF {
+			t.Errorf("Local connection must be closed, got: %v", err)
+		}
+		select {
+		case <-l.chClosed:
+		default:
+			t.Error("Listener must be closed")
+		}
+	})
 	t.Run("AcceptError", func(t *testing.T) {
 		tca, tcb := net.Pipe()
 
@@ -643,11 +750,38 @@ func (acceptFunc) Addr() net.Addr {
 	panic("not implemented")
 }
 
+// closeListener returns the given connections and then blocks until closed.
+type closeListener struct {
+	chClosed  chan struct{}
+	conns     []net.Conn
+	closeOnce sync.Once
+}
+
+func (l *closeListener) Accept() (net.Conn, error) {
+	if len(l.conns) > 0 {
+		c := l.conns[0]
+		l.conns = l.conns[1:]
+		return c, nil
+	}
+	<-l.chClosed
+	return nil, net.ErrClosed
+}
+
+func (l *closeListener) Close() error {
+	l.closeOnce.Do(func() { close(l.chClosed) })
+	return nil
+}
+
+func (*closeListener) Addr() net.Addr {
+	panic("not implemented")
+}
+
 func TestProxyOption_validate(t *testing.T) {
 	t.Run("Valid", func(t *testing.T) {
 		opts := []*ProxyOptions{
 			{Scheme: "ws"},
 			{Scheme: "wss"},
+			{Scheme: "wss", ProtocolVersions: []ProtocolVersion{ProtocolV3, ProtocolV1}},
 		}
 		for _, o := range opts {
 			o := o
@@ -662,6 +796,7 @@ func TestProxyOption_validate(t *testing.T) {
 		opts := []*ProxyOptions{
 			{Scheme: "http"},
 			{Scheme: ""},
+			{Scheme: "wss", ProtocolVersions: []ProtocolVersion{4}},
 		}
 		for _, o := range opts {
 			o := o
@@ -15,13 +15,494 @@
 package tunnel
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 
+	"google.golang.org/protobuf/proto"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
 	"github.com/seqsense/aws-iot-device-sdk-go/v6/tunnel/msg"
 )
 
-func readProxy(ws, conn io.ReadWriter, streamID int32, eh ErrorHandler) {
+// connKey identifies the local connection in the tunnel.
+// serviceID is empty and connectionID is zero on ProtocolV1.
+// connectionID is zero on ProtocolV2.
+type connKey struct {
+	serviceID    string
+	streamID     int32
+	connectionID uint32
+}
+
+var errSessionClosed = errors.New("session closed")
+
+// ErrSessionReset indicates that the session is reset by the service,
+// e.g. the tunnel is closed.
+var ErrSessionReset = errors.New("session reset")
+
+// session stores the state of the local connections multiplexed
+// over the tunnel.
+type session struct {
+	ws      io.ReadWriteCloser
+	version ProtocolVersion
+	eh      ErrorHandler
+	stat    Stat
+
+	// wmu serializes the messages sent to the tunnel.
+	wmu    sync.Mutex
+	closed bool
+
+	// done is closed when the session is stopped.
+	done    chan struct{}
+	wg      sync.WaitGroup
+	closers []io.Closer
+
+	mu      sync.Mutex
+	stopped bool
+	conns   map[connKey]io.ReadWriteCloser
+	// streams stores the active stream ID of the services on ProtocolV2 and later.
+	streams map[string]int32
+
+	serviceIDs     []string
+	chServiceIDs   chan struct{}
+	serviceIDsOnce sync.Once
+}
+
+func newSession(ws io.ReadWriteCloser, version ProtocolVersion, eh ErrorHandler, stat Stat) *session {
+	return &session{
+		ws:           ws,
+		version:      version,
+		eh:           eh,
+		stat:         stat,
+		conns:        make(map[connKey]io.ReadWriteCloser),
+		streams:      make(map[string]int32),
+		chServiceIDs: make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+func (s *session) handleError(err error) {
+	if s.eh != nil {
+		s.eh.HandleError(err)
+	}
+}
+
+func (s *session) updateStat() {
+	if s.stat != nil {
+		s.mu.Lock()
+		n := len(s.conns)
+		active := make(map[connKey]struct{}, n)
+		for k := range s.conns {
+			active[k] = struct{}{}
+		}
+		s.mu.Unlock()
+		s.stat.Update(func(stat *Statistics) {
+			stat.NumConn = n
+			streams := stat.Streams[:0]
+			for _, st := range stat.Streams {
+				k := connKey{serviceID: st.ServiceID, streamID: st.StreamID, connectionID: st.ConnectionID}
+				if _, ok := active[k]; ok {
+					streams = append(streams, st)
+				}
+			}
+			stat.Streams = streams
+		})
+	}
+}
+
+func (s *session) updateStatFunc(fn func(*Statistics)) {
+	if s.stat != nil {
+		s.stat.Update(fn)
+	}
+}
+
+// countSent updates the statistics of the message sent to the tunnel.
+func (s *session) countSent(m *msg.Message) {
+	s.updateStatFunc(func(stat *Statistics) {
+		stat.MessagesSent++
+		switch m.Type {
+		case msg.Message_DATA:
+			n := uint64(len(m.Payload))
+			stat.BytesSent += n
+			if st := stat.stream(s.key(m)); st != nil {
+				st.BytesSent += n
+			}
+		case msg.Message_STREAM_RESET, msg.Message_CONNECTION_RESET:
+			stat.StreamResetsSent++
+		}
+	})
+}
+
+// key returns connKey of the message normalized by the protocol version.
+func (s *session) key(m *msg.Message) connKey {
+	switch s.version {
+	case ProtocolV1:
+		return connKey{streamID: m.StreamId}
+	case ProtocolV2:
+		return connKey{serviceID: m.ServiceId, streamID: m.StreamId}
+	}
+	k := connKey{serviceID: m.ServiceId, streamID: m.StreamId, connectionID: m.ConnectionId}
+	if k.connectionID == 0 {
+		// Peers of older protocol versions don't set the connection ID.
+		k.connectionID = 1
+	}
+	return k
+}
+
+func (s *session) send(m *msg.Message) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	if s.closed {
+		return errSessionClosed
+	}
+	return s.sendLocked(m)
+}
+
+func (s *session) sendLocked(m *msg.Message) error {
+	if err := msg.WriteMessage(s.ws, m); err != nil {
+		return err
+	}
+	s.countSent(m)
+	return nil
+}
+
+// add registers the connection.
+// On ProtocolV2 and later, connections of the previous stream of
+// the service are closed when the new stream is started.
+// It returns false and closes the connection if the session is stopped.
+func (s *session) add(k connKey, conn io.ReadWriteCloser) bool {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		_ = conn.Close()
+		return false
+	}
+	if s.version >= ProtocolV2 {
+		if id, ok := s.streams[k.serviceID]; ok && id != k.streamID {
+			s.closeStreamLocked(k.serviceID, id)
+		}
+		s.streams[k.serviceID] = k.streamID
+	}
+	if old, ok := s.conns[k]; ok {
+		// Peer reused the stream ID without resetting the previous one.
+		_ = old.Close()
+	}
+	s.conns[k] = conn
+	s.mu.Unlock()
+
+	s.updateStatFunc(func(stat *Statistics) {
+		stat.StreamsStarted++
+		st := StreamStatistics{
+			ServiceID:    k.serviceID,
+			StreamID:     k.streamID,
+			ConnectionID: k.connectionID,
+			StartedAt:    time.Now(),
+		}
+		if old := stat.stream(k); old != nil {
+			*old = st
+		} else {
+			stat.Streams = append(stat.Streams, st)
+		}
+	})
+	return true
+}
+
+// start starts proxying the data from the registered connection.
+func (s *session) start(k connKey, conn io.ReadWriteCloser) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.readProxy(conn, k)
+		if s.removeConn(k, conn) {
+			s.reset(k)
+		}
+		s.updateStat()
+	}()
+}
+
+// active returns true if the stream has any connection.
+func (s *session) active(serviceID string, streamID int32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k := range s.conns {
+		if k.serviceID == serviceID && k.streamID == streamID {
+			return true
+		}
+	}
+	return false
+}
+
+// remove closes and unregisters the connection.
+// It returns false if the connection is already removed.
+func (s *session) remove(k connKey) bool {
+	return s.removeConn(k, nil)
+}
+
+// removeConn closes and unregisters the connection if the key is
+// associated with the given connection.
+// Any connection of the key is removed if conn is nil.
+func (s *session) removeConn(k connKey, conn io.ReadWriteCloser) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.conns[k]
+	if !ok || (conn != nil && c != conn) {
+		return false
+	}
+	_ = c.Close()
+	delete(s.conns, k)
+	return true
+}
+
+// reset notifies the peer that the local connection is closed.
+// ProtocolV1 doesn't notify to keep the compatibility.
+func (s *session) reset(k connKey) {
+	var m *msg.Message
+	switch s.version {
+	case ProtocolV1:
+		return
+	case ProtocolV2:
+		m = &msg.Message{
+			Type:      msg.Message_STREAM_RESET,
+			ServiceId: k.serviceID,
+			StreamId:  k.streamID,
+		}
+	default:
+		m = &msg.Message{
+			Type:         msg.Message_CONNECTION_RESET,
+			ServiceId:    k.serviceID,
+			StreamId:     k.streamID,
+			ConnectionId: k.connectionID,
+		}
+	}
+	if err := s.send(m); err != nil && !errors.Is(err, errSessionClosed) {
+		s.handleError(ioterr.New(err, "sending reset message"))
+	}
+}
+
+// reject resets the stream or the connection requested by the peer.
+// Unlike reset, the message is sent regardless of the protocol version.
+func (s *session) reject(k connKey, typ msg.Message_Type) {
+	m := &msg.Message{
+		Type:     msg.Message_STREAM_RESET,
+		StreamId: k.streamID,
+	}
+	if s.version >= ProtocolV2 {
+		m.ServiceId = k.serviceID
+	}
+	if typ == msg.Message_CONNECTION_START {
+		m.Type = msg.Message_CONNECTION_RESET
+		m.ConnectionId = k.connectionID
+	}
+	if err := s.send(m); err != nil && !errors.Is(err, errSessionClosed) {
+		s.handleError(ioterr.New(err, "sending reset message"))
+	}
+}
+
+// numConns returns a number of the connections of the service
+// remaining after starting the requested stream or connection.
+func (s *session) numConns(k connKey, typ msg.Message_Type) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	for c := range s.conns {
+		if c.serviceID != k.serviceID {
+			continue
+		}
+		if s.version >= ProtocolV2 && typ == msg.Message_STREAM_START && c.streamID != k.streamID {
+			// Previous stream is replaced by the new stream.
+			continue
+		}
+		n++
+	}
+	return n
+}
+
+func (s *session) closeStreamLocked(serviceID string, streamID int32) {
+	for k, c := range s.conns {
+		if k.serviceID == serviceID && k.streamID == streamID {
+			_ = c.Close()
+			delete(s.conns, k)
+		}
+	}
+}
+
+func (s *session) closeAll() {
+	s.mu.Lock()
+	for k, c := range s.conns {
+		_ = c.Close()
+		delete(s.conns, k)
+	}
+	s.mu.Unlock()
+}
+
+// shutdown resets all active streams and closes the local connections.
+// Messages being sent are completed before the reset messages and
+// no message is sent after the reset.
+func (s *session) shutdown() {
+	s.mu.Lock()
+	s.stopped = true
+	resets := make(map[connKey]struct{})
+	for k := range s.conns {
+		resets[connKey{serviceID: k.serviceID, streamID: k.streamID}] = struct{}{}
+	}
+	s.mu.Unlock()
+
+	s.wmu.Lock()
+	if !s.closed {
+		for k := range resets {
+			m := &msg.Message{Type: msg.Message_STREAM_RESET, StreamId: k.streamID}
+			if s.version >= ProtocolV2 {
+				m.ServiceId = k.serviceID
+			}
+			if err := s.sendLocked(m); err != nil {
+				s.handleError(ioterr.New(err, "sending reset message"))
+				break
+			}
+		}
+		s.closed = true
+	}
+	s.wmu.Unlock()
+
+	s.closeAll()
+}
+
+// run serves the session until the tunnel is closed or ctx is canceled.
+// All goroutines of the session are stopped before returning.
+func (s *session) run(ctx context.Context, handler func(*msg.Message)) error {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			s.shutdown()
+			_ = s.ws.Close()
+		case <-done:
+		}
+	}()
+
+	err := s.serve(handler)
+	close(done)
+	<-stopped
+
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	close(s.done)
+	s.wmu.Lock()
+	s.closed = true
+	s.wmu.Unlock()
+	s.closeAll()
+	for _, c := range s.closers {
+		_ = c.Close()
+	}
+	s.wg.Wait()
+	s.updateStat()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// waitServiceIDs waits SERVICE_IDS message and returns the available service IDs.
+// It returns false if the session is closed before receiving the message.
+func (s *session) waitServiceIDs(done <-chan struct{}) ([]string, bool) {
+	select {
+	case <-s.chServiceIDs:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.serviceIDs, true
+	case <-done:
+		return nil, false
+	}
+}
+
+// serve reads the messages from the tunnel and handles the messages
+// common to the source and the destination.
+// Other messages are passed to the handler.
+func (s *session) serve(handler func(*msg.Message)) error {
+	sz := make([]byte, 2)
+	b := make([]byte, 8192)
+	for {
+		if _, err := io.ReadFull(s.ws, sz); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return ioterr.New(err, "reading length header")
+		}
+		l := int(sz[0])<<8 | int(sz[1])
+		if cap(b) < l {
+			b = make([]byte, l)
+		}
+		b = b[:l]
+		if _, err := io.ReadFull(s.ws, b); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return ioterr.New(err, "reading message")
+		}
+		m := &msg.Message{}
+		if err := proto.Unmarshal(b, m); err != nil {
+			s.handleError(ioterr.New(err, "unmarshaling message"))
+			continue
+		}
+		s.updateStatFunc(func(stat *Statistics) {
+			stat.MessagesReceived++
+			switch m.Type {
+			case msg.Message_STREAM_RESET, msg.Message_CONNECTION_RESET:
+				stat.StreamResetsReceived++
+			}
+		})
+		switch m.Type {
+		case msg.Message_STREAM_RESET:
+			k := s.key(m)
+			s.mu.Lock()
+			s.closeStreamLocked(k.serviceID, k.streamID)
+			s.mu.Unlock()
+
+		case msg.Message_CONNECTION_RESET:
+			s.remove(s.key(m))
+
+		case msg.Message_SESSION_RESET:
+			s.closeAll()
+			return ErrSessionReset
+
+		case msg.Message_SERVICE_IDS:
+			s.mu.Lock()
+			s.serviceIDs = append([]string(nil), m.AvailableServiceIds...)
+			s.mu.Unlock()
+			s.serviceIDsOnce.Do(func() { close(s.chServiceIDs) })
+
+		case msg.Message_DATA:
+			s.mu.Lock()
+			conn, ok := s.conns[s.key(m)]
+			s.mu.Unlock()
+			if ok {
+				if _, err := conn.Write(m.Payload); err != nil {
+					s.handleError(ioterr.New(err, "writing message"))
+				} else {
+					k, n := s.key(m), uint64(len(m.Payload))
+					s.updateStatFunc(func(stat *Statistics) {
+						stat.BytesReceived += n
+						if st := stat.stream(k); st != nil {
+							st.BytesReceived += n
+						}
+					})
+				}
+			}
+
+		default:
+			handler(m)
+		}
+		s.updateStat()
+	}
+}
+
+func (s *session) readProxy(conn io.Reader, k connKey) {
 	b := make([]byte, 8192)
 	for {
 		n, err := conn.Read(b)
@@ -29,18 +510,23 @@ func readProxy(ws, conn io.ReadWriter, streamID int32, eh ErrorHandler) {
 			if err == io.EOF {
 				return
 			}
-			if eh != nil {
-				eh.HandleError(fmt.Errorf("connection closed: %v", err))
+			s.mu.Lock()
+			stopped := s.stopped
+			s.mu.Unlock()
+			if !stopped {
+				s.handleError(fmt.Errorf("connection closed: %v", err))
 			}
 			return
 		}
-		if err := msg.WriteMessage(ws, &msg.Message{
-			Type:     msg.Message_DATA,
-			StreamId: streamID,
-			Payload:  b[:n],
+		if err := s.send(&msg.Message{
+			Type:         msg.Message_DATA,
+			ServiceId:    k.serviceID,
+			StreamId:     k.streamID,
+			ConnectionId: k.connectionID,
+			Payload:      b[:n],
 		}); err != nil {
-			if eh != nil {
-				eh.HandleError(fmt.Errorf("message send failed: %v", err))
+			if !errors.Is(err, errSessionClosed) {
+				s.handleError(fmt.Errorf("message send failed: %v", err))
 			}
 			return
 		}
@@ -0,0 +1,218 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
+)
+
+// ErrInvalidReconnectPolicy indicate that the reconnect policy is invalid.
+var ErrInvalidReconnectPolicy = errors.New("invalid reconnect policy")
+
+// ErrReconnecting is reported to ErrorHandler when the proxy is going to reconnect.
+// Reported error wraps the cause of the disconnection.
+var ErrReconnecting = errors.New("reconnecting")
+
+// ProxyState represents a state of the proxy connection.
+type ProxyState int
+
+// List of ProxyStates.
+const (
+	// ProxyConnecting is a state dialing to the proxy endpoint.
+	ProxyConnecting ProxyState = iota + 1
+	// ProxyConnected is a state proxying the data.
+	ProxyConnected
+	// ProxyDisconnected is a state waiting for the next reconnect.
+	ProxyDisconnected
+	// ProxyClosed is a final state of the proxy.
+	ProxyClosed
+)
+
+func (s ProxyState) String() string {
+	switch s {
+	case ProxyConnecting:
+		return "connecting"
+	case ProxyConnected:
+		return "connected"
+	case ProxyDisconnected:
+		return "disconnected"
+	case ProxyClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// ReconnectPolicy represents a reconnect policy of the proxy.
+type ReconnectPolicy struct {
+	// Wait is a wait duration before the first reconnect.
+	// The wait is doubled on each consecutive failure.
+	Wait time.Duration
+	// MaxWait is an upper limit of the wait duration.
+	// Zero means one minute.
+	MaxWait time.Duration
+	// MaxRetries is a maximum number of consecutive dial failures.
+	// Zero means unlimited.
+	MaxRetries int
+	// Lifetime is a lifetime of the tunnel counted from the proxy start.
+	// Proxy is not reconnected after the lifetime.
+	// Zero means unlimited.
+	Lifetime time.Duration
+}
+
+const defaultMaxReconnectWait = time.Minute
+
+// DefaultReconnectPolicy is a default reconnect policy.
+// Lifetime is set to the default maximum lifetime of the AWS IoT secure tunnel.
+var DefaultReconnectPolicy = ReconnectPolicy{
+	Wait:     time.Second,
+	MaxWait:  defaultMaxReconnectWait,
+	Lifetime: 12 * time.Hour,
+}
+
+func (p ReconnectPolicy) wait(retry int) time.Duration {
+	maxWait := p.MaxWait
+	if maxWait <= 0 {
+		maxWait = defaultMaxReconnectWait
+	}
+	d := p.Wait
+	for i := 0; i < retry; i++ {
+		d *= 2
+		if d >= maxWait {
+			return maxWait
+		}
+	}
+	return d
+}
+
+// WithReconnect enables reconnection with the same access token
+// when the WebSocket connection is dropped.
+// Local connections are closed on the disconnection.
+// Proxy is not reconnected if the session is reset by the service
+// (ErrSessionReset) or the handshake is rejected (ErrHandshakeRejected)
+// since the tunnel is closed.
+func WithReconnect(p ReconnectPolicy) ProxyOption {
+	return func(opt *ProxyOptions) error {
+		opt.Reconnect = &p
+		return nil
+	}
+}
+
+// WithStateHandler sets a handler called on the proxy state change.
+func WithStateHandler(h func(ProxyState)) ProxyOption {
+	return func(opt *ProxyOptions) error {
+		opt.StateHandler = h
+		return nil
+	}
+}
+
+type proxyFunc func(ctx context.Context, ws *websocket.Conn, version ProtocolVersion, opt *ProxyOptions) error
+
+// runProxy connects to the endpoint and runs the proxy.
+// If reconnect is enabled, the proxy is restarted until ctx is canceled,
+// the reconnect policy is exceeded or the tunnel is closed.
+func runProxy(ctx context.Context, endpoint string, mode ClientMode, token string, opts []ProxyOption, fn proxyFunc) error {
+	opt, err := newProxyOptions(opts...)
+	if err != nil {
+		return ioterr.New(err, "opening proxy "+mode.String())
+	}
+
+	setState := func(s ProxyState) {
+		if opt.StateHandler != nil {
+			opt.StateHandler(s)
+		}
+	}
+	defer setState(ProxyClosed)
+
+	var deadline time.Time
+	if opt.Reconnect != nil && opt.Reconnect.Lifetime > 0 {
+		deadline = time.Now().Add(opt.Reconnect.Lifetime)
+	}
+
+	updateStat := func(fn func(*Statistics)) {
+		if opt.Stat != nil {
+			opt.Stat.Update(fn)
+		}
+	}
+	var tracker *pingTracker
+	if opt.Stat != nil {
+		tracker = &pingTracker{stat: opt.Stat}
+	}
+
+	var retry int
+	for first := true; ; first = false {
+		if !first {
+			updateStat(func(stat *Statistics) {
+				stat.Reconnects++
+			})
+		}
+		setState(ProxyConnecting)
+		ws, version, err := openProxyConn(ctx, endpoint, mode, token, opt, tracker)
+		if err != nil {
+			err = ioterr.New(err, "opening proxy "+mode.String())
+			if opt.Reconnect == nil || ctx.Err() != nil || errors.Is(err, ErrHandshakeRejected) {
+				return err
+			}
+			retry++
+			if opt.Reconnect.MaxRetries > 0 && retry > opt.Reconnect.MaxRetries {
+				return err
+			}
+		} else {
+			retry = 0
+			connectedAt := time.Now()
+			updateStat(func(stat *Statistics) {
+				stat.ConnectedAt = connectedAt
+			})
+			setState(ProxyConnected)
+			pingCancel := newPinger(ws, opt.PingPeriod, tracker)
+			err = fn(ctx, ws, version, opt)
+			pingCancel()
+			_ = ws.Close()
+			updateStat(func(stat *Statistics) {
+				stat.ConnectedAt = time.Time{}
+			})
+			if opt.Reconnect == nil || ctx.Err() != nil || errors.Is(err, ErrSessionReset) {
+				return err
+			}
+		}
+
+		wait := opt.Reconnect.wait(retry)
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			return err
+		}
+		setState(ProxyDisconnected)
+		if opt.ErrorHandler != nil {
+			cause := err
+			if cause == nil {
+				cause = errors.New("connection closed")
+			}
+			opt.ErrorHandler.HandleError(ioterr.Newf(
+				fmt.Errorf("%w: %w", ErrReconnecting, cause), "in %v", wait,
+			))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,419 @@
+// Copyright 2026 SEQSENSE, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/seqsense/aws-iot-device-sdk-go/v6/tunnel/msg"
+)
+
+func TestReconnectPolicy_wait(t *testing.T) {
+	testCases := map[string]struct {
+		policy   ReconnectPolicy
+		expected map[int]time.Duration
+	}{
+		"MaxWait": {
+			policy: ReconnectPolicy{Wait: time.Second, MaxWait: 5 * time.Second},
+			expected: map[int]time.Duration{
+				0: time.Second, 1: 2 * time.Second, 2: 4 * time.Second, 3: 5 * time.Second, 4: 5 * time.Second,
+			},
+		},
+		"ZeroMaxWait": {
+			policy: ReconnectPolicy{Wait: time.Second},
+			expected: map[int]time.Duration{
+				0: time.Second, 5: 32 * time.Second, 6: time.Minute, 40: time.Minute, 100: time.Minute,
+			},
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			for retry, e := range tt.expected {
+				if w := tt.policy.wait(retry); w != e {
+					t.Errorf("Retry %d: expected wait %v, got %v", retry, e, w)
+				}
+			}
+		})
+	}
+}
+
+func newTestTunnelServer(t *testing.T, handler func(ws *websocket.Conn)) string {
+	t.Helper()
+	ts := httptest.NewServer(websocket.Server{
+		Handshake: func(cfg *websocket.Config, r *http.Request) error {
+			cfg.Protocol = []string{ProtocolV1.Subprotocol()}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			ws.PayloadType = websocket.BinaryFrame
+			handler(ws)
+		},
+	})
+	t.Cleanup(ts.Close)
+	return strings.TrimPrefix(ts.URL, "http://")
+}
+
+type stateRecorder struct {
+	mu     sync.Mutex
+	states []ProxyState
+}
+
+func (r *stateRecorder) handle(s ProxyState) {
+	r.mu.Lock()
+	r.states = append(r.states, s)
+	r.mu.Unlock()
+}
+
+func (r *stateRecorder) get() []ProxyState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ProxyState(nil), r.states...)
+}
+
+func TestProxyDestination_Reconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	chConn := make(chan *websocket.Conn)
+	endpoint := newTestTunnelServer(t, func(ws *websocket.Conn) {
+		chConn <- ws
+		_, _ = io.Copy(io.Discard, ws)
+	})
+
+	chLocal := make(chan net.Conn, 2)
+	dialer := func() (io.ReadWriteCloser, error) {
+		ca, cb := net.Pipe()
+		chLocal <- ca
+		return cb, nil
+	}
+	recorder := &stateRecorder{}
+	chErr := make(chan error, 10)
+	chRet := make(chan error, 1)
+	go func() {
+		chRet <- ProxyDestinationContext(ctx, dialer, endpoint, "token",
+			func(opt *ProxyOptions) error {
+				opt.Scheme = "ws"
+				return nil
+			},
+			WithReconnect(ReconnectPolicy{Wait: 10 * time.Millisecond}),
+			WithStateHandler(recorder.handle),
+			WithErrorHandler(ErrorHandlerFunc(func(err error) { chErr <- err })),
+		)
+	}()
+
+	recv := func() *websocket.Conn {
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		case ws := <-chConn:
+			return ws
+		}
+		return nil
+	}
+	recvLocal := func() net.Conn {
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		case c := <-chLocal:
+			return c
+		}
+		return nil
+	}
+
+	ws := recv()
+	writeTestMessage(t, ws, &msg.Message{Type: msg.Message_STREAM_START, StreamId: 1})
+	writeTestMessage(t, ws, &msg.Message{Type: msg.Message_DATA, StreamId: 1, Payload: []byte("payload")})
+	local := recvLocal()
+	expectTestPayload(t, local, "payload")
+
+	// Drop the connection.
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ws = recv()
+	if _, err := local.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Local connection must be closed on disconnection, got: %v", err)
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatal("Timeout")
+	case err := <-chErr:
+		if !errors.Is(err, ErrReconnecting) {
+			t.Errorf("Expected error: %v, got: %v", ErrReconnecting, err)
+		}
+	}
+
+	// Proxy works after reconnect.
+	writeTestMessage(t, ws, &msg.Message{Type: msg.Message_STREAM_START, StreamId: 1})
+	writeTestMessage(t, ws, &msg.Message{Type: msg.Message_DATA, StreamId: 1, Payload: []byte("payload 2")})
+	local = recvLocal()
+	expectTestPayload(t, local, "payload 2")
+
+	cancel()
+	select {
+	case <-time.After(time.Second):
+		t.Fatal("Timeout")
+	case err := <-chRet:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected error: %v, got: %v", context.Canceled, err)
+		}
+	}
+
+	expected := []ProxyState{
+		ProxyConnecting, ProxyConnected, ProxyDisconnected,
+		ProxyConnecting, ProxyConnected, ProxyClosed,
+	}
+	if states := recorder.get(); !reflect.DeepEqual(expected, states) {
+		t.Errorf("Expected states: %v, got: %v", expected, states)
+	}
+}
+
+func TestProxySource_Reconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	chConn := make(chan *websocket.Conn)
+	endpoint := newTestTunnelServer(t, func(ws *websocket.Conn) {
+		chConn <- ws
+		<-ctx.Done()
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+
+	chErr := make(chan error, 10)
+	chRet := make(chan error, 1)
+	go func() {
+		chRet <- ProxySourceContext(ctx, listener, endpoint, "token",
+			func(opt *ProxyOptions) error {
+				opt.Scheme = "ws"
+				return nil
+			},
+			WithReconnect(ReconnectPolicy{Wait: 10 * time.Millisecond}),
+			WithErrorHandler(ErrorHandlerFunc(func(err error) { chErr <- err })),
+		)
+	}()
+
+	recv := func() *websocket.Conn {
+		select {
+		case <-ctx.Done():
+			t.Fatal("Timeout")
+		case ws := <-chConn:
+			return ws
+		}
+		return nil
+	}
+	dial := func() net.Conn {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+
+	ws := recv()
+	local := dial()
+	expectTestMessage(t, ws, &msg.Message{Type: msg.Message_STREAM_START, StreamId: 1})
+	if _, err := local.Write([]byte("payload")); err != nil {
+		t.Fatal(err)
+	}
+	expectTestMessage(t, ws, &msg.Message{Type: msg.Message_DATA, StreamId: 1, Payload: []byte("payload")})
+
+	// Drop the connection.
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ws = recv()
+	if _, err := local.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Local connection must be closed on disconnection, got: %v", err)
+	}
+	_ = local.Close()
+	select {
+	case <-ctx.Done():
+		t.Fatal("Timeout")
+	case err := <-chErr:
+		if !errors.Is(err, ErrReconnecting) {
+			t.Errorf("Expected error: %v, got: %v", ErrReconnecting, err)
+		}
+	}
+
+	// Listener accepts connections after reconnect.
+	local = dial()
+	defer local.Close()
+	expectTestMessage(t, ws, &msg.Message{Type: msg.Message_STREAM_START, StreamId: 1})
+	if _, err := local.Write([]byte("payload 2")); err != nil {
+		t.Fatal(err)
+	}
+	expectTestMessage(t, ws, &msg.Message{Type: msg.Message_DATA, StreamId: 1, Payload: []byte("payload 2")})
+
+	cancel()
+	select {
+	case <-time.After(time.Second):
+		t.Fatal("Timeout")
+	case err := <-chRet:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected error: %v, got: %v", context.Canceled, err)
+		}
+	}
+	if conn, err := net.Dial("tcp", addr); err == nil {
+		_ = conn.Close()
+		t.Error("Listener must be closed on return")
+	}
+	select {
+	case err := <-chErr:
+		t.Errorf("Unexpected error: %v", err)
+	default:
+	}
+}
+
+func TestProxyDestination_ReconnectLimit(t *testing.T) {
+	var mu sync.Mutex
+	var cnt int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		cnt++
+		mu.Unlock()
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	testCases := map[string]struct {
+		policy   ReconnectPolicy
+		expected int
+	}{
+		"MaxRetries": {
+			policy:   ReconnectPolicy{Wait: time.Millisecond, MaxRetries: 2},
+			expected: 3,
+		},
+		"Lifetime": {
+			// Second reconnect is not attempted since it exceeds the lifetime.
+			policy:   ReconnectPolicy{Wait: 20 * time.Millisecond, Lifetime: 50 * time.Millisecond},
+			expected: 2,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			mu.Lock()
+			cnt = 0
+			mu.Unlock()
+
+			err := ProxyDestination(nil, strings.TrimPrefix(ts.URL, "http://"), "token",
+				func(opt *ProxyOptions) error {
+					opt.Scheme = "ws"
+					return nil
+				},
+				WithReconnect(tt.policy),
+			)
+			var de *websocket.DialError
+			if !errors.As(err, &de) || de.Err != websocket.ErrBadStatus {
+				t.Errorf("Expected error: %v, got: %v", websocket.ErrBadStatus, err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if cnt != tt.expected {
+				t.Errorf("Expected %d attempts, got %d", tt.expected, cnt)
+			}
+		})
+	}
+}
+
+func TestProxyDestination_ReconnectStop(t *testing.T) {
+	testCases := map[string]struct {
+		handler http.Handler
+		err     error
+	}{
+		"SessionReset": {
+			handler: websocket.Server{
+				Handshake: func(cfg *websocket.Config, r *http.Request) error {
+					cfg.Protocol = []string{ProtocolV1.Subprotocol()}
+					return nil
+				},
+				Handler: func(ws *websocket.Conn) {
+					ws.PayloadType = websocket.BinaryFrame
+					writeTestMessage(t, ws, &msg.Message{Type: msg.Message_SESSION_RESET})
+					_, _ = io.Copy(io.Discard, ws)
+				},
+			},
+			err: ErrSessionReset,
+		},
+		"HandshakeRejected": {
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			}),
+			err: ErrHandshakeRejected,
+		},
+	}
+	for name, tt := range testCases {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			var mu sync.Mutex
+			var cnt int
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				cnt++
+				mu.Unlock()
+				tt.handler.ServeHTTP(w, r)
+			}))
+			defer ts.Close()
+
+			recorder := &stateRecorder{}
+			err := ProxyDestinationContext(ctx,
+				func() (io.ReadWriteCloser, error) {
+					return nil, errors.New("unexpected dial")
+				},
+				strings.TrimPrefix(ts.URL, "http://"), "token",
+				func(opt *ProxyOptions) error {
+					opt.Scheme = "ws"
+					return nil
+				},
+				WithReconnect(ReconnectPolicy{Wait: 10 * time.Millisecond}),
+				WithStateHandler(recorder.handle),
+			)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected error: %v, got: %v", tt.err, err)
+			}
+			mu.Lock()
+			if cnt != 1 {
+				t.Errorf("Expected 1 attempt, got %d", cnt)
+			}
+			mu.Unlock()
+			if states := recorder.get(); states[len(states)-1] != ProxyClosed {
+				t.Errorf("Expected final state: %v, got: %v", ProxyClosed, states)
+			}
+		})
+	}
+}
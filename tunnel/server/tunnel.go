@@ -135,7 +135,17 @@ func (h *TunnelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	s := &websocket.Server{
 		Handshake: func(cfg *websocket.Config, r *http.Request) error {
-			return nil
+			if len(cfg.Protocol) == 0 {
+				// Old clients don't request the subprotocol.
+				return nil
+			}
+			for _, p := range cfg.Protocol {
+				if _, ok := tunnel.ParseSubprotocol(p); ok {
+					cfg.Protocol = []string{p}
+					return nil
+				}
+			}
+			return tunnel.ErrUnsupportedProtocol
 		},
 		Handler: websocket.Handler(func(ws *websocket.Conn) {
 			ws.PayloadType = websocket.BinaryFrame
@@ -144,6 +154,20 @@ func (h *TunnelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				_ = ws.Close()
 			}()
 
+			version := tunnel.ProtocolV1
+			if p := ws.Config().Protocol; len(p) == 1 {
+				version, _ = tunnel.ParseSubprotocol(p[0])
+			}
+			if version >= tunnel.ProtocolV2 {
+				if err := msg.WriteMessage(ws, &msg.Message{
+					Type:                msg.Message_SERVICE_IDS,
+					AvailableServiceIds: ti.services,
+				}); err != nil {
+					log.Print(err)
+					return
+				}
+			}
+
 			chWsClosed := make(chan struct{})
 			go func() {
 				defer func() {
@@ -15,126 +15,192 @@
 package tunnel
 
 import (
+	"context"
+	"errors"
 	"io"
 	"net"
 	"sync"
 
-	"google.golang.org/protobuf/proto"
-
 	"github.com/seqsense/aws-iot-device-sdk-go/v6/internal/ioterr"
 	"github.com/seqsense/aws-iot-device-sdk-go/v6/tunnel/msg"
 )
 
-func proxySource(ws io.ReadWriter, listener net.Listener, eh ErrorHandler, stat Stat) error {
-	var muConns sync.Mutex
-	conns := make(map[int32]io.ReadWriteCloser)
-
-	updateStat := func() {
-		if stat != nil {
-			muConns.Lock()
-			n := len(conns)
-			muConns.Unlock()
-			stat.Update(func(stat *Statistics) {
-				stat.NumConn = n
-			})
+func proxySource(ws io.ReadWriteCloser, listener net.Listener, eh ErrorHandler, stat Stat) error {
+	return proxySourceServices(context.Background(), ws, ProtocolV1, map[string]net.Listener{"": listener}, eh, stat)
+}
+
+func proxySourceServices(ctx context.Context, ws io.ReadWriteCloser, version ProtocolVersion, listeners map[string]net.Listener, eh ErrorHandler, stat Stat) error {
+	if version == ProtocolV1 && len(listeners) > 1 {
+		return ioterr.Newf(ErrMultipleServices, "%d services on protocol version %d", len(listeners), version)
+	}
+	s := newSession(ws, version, eh, stat)
+
+	for serviceID, listener := range listeners {
+		// Listeners are closed on return to stop accepting.
+		s.closers = append(s.closers, listener)
+		s.wg.Add(1)
+		go func(serviceID string, listener net.Listener) {
+			defer s.wg.Done()
+			s.accept(serviceID, listener, s.done)
+		}(serviceID, listener)
+	}
+	return s.run(ctx, func(*msg.Message) {})
+}
+
+// accept accepts the local connections and starts the streams of the service.
+// On ProtocolV2 and later, empty service ID is replaced by the first
+// service ID notified by SERVICE_IDS message.
+func (s *session) accept(serviceID string, listener net.Listener, done <-chan struct{}) {
+	switch {
+	case s.version == ProtocolV1:
+		serviceID = ""
+	case serviceID == "":
+		ids, ok := s.waitServiceIDs(done)
+		if !ok {
+			return
+		}
+		if len(ids) > 0 {
+			serviceID = ids[0]
 		}
 	}
 
-	go func() {
-		var streamID int32 = 1
-		for {
-			conn, err := listener.Accept()
-			if err != nil {
-				if eh != nil {
-					eh.HandleError(ioterr.New(err, "accepting source connection"))
+	var streamID int32
+	var connectionID uint32
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-done:
+				if errors.Is(err, net.ErrClosed) {
+					// Listener is closed on the session stop.
+					return
 				}
-				return
+			default:
 			}
+			s.handleError(ioterr.New(err, "accepting source connection"))
+			return
+		}
 
-			muConns.Lock()
-			id := streamID
-			conns[id] = conn
+		m := &msg.Message{
+			Type:      msg.Message_STREAM_START,
+			ServiceId: serviceID,
+		}
+		if s.version >= ProtocolV3 && streamID != 0 && s.active(serviceID, streamID) {
+			// Add the connection to the existing stream.
+			connectionID++
+			m.Type = msg.Message_CONNECTION_START
+		} else {
 			streamID++
-			muConns.Unlock()
-
-			if err := msg.WriteMessage(ws, &msg.Message{
-				Type:     msg.Message_STREAM_START,
-				StreamId: id,
-			}); err != nil {
-				if eh != nil {
-					eh.HandleError(ioterr.New(err, "sending message"))
-				}
-				continue
+			if s.version >= ProtocolV3 {
+				connectionID = 1
 			}
+		}
+		m.StreamId = streamID
+		m.ConnectionId = connectionID
 
-			go func() {
-				readProxy(ws, conn, id, eh)
-				muConns.Lock()
-				if conn, ok := conns[id]; ok {
-					_ = conn.Close()
-					delete(conns, id)
-				}
-				muConns.Unlock()
-				updateStat()
-			}()
+		k := s.key(m)
+		if !s.add(k, conn) {
+			return
 		}
+		s.updateStat()
+		if err := s.send(m); err != nil {
+			s.handleError(ioterr.New(err, "sending message"))
+			s.removeConn(k, conn)
+			continue
+		}
+		s.start(k, conn)
+	}
+}
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+// persistentListener keeps accepting the connections on the listener
+// across the sessions.
+// Connections accepted while no session is running are passed
+// to the next session.
+type persistentListener struct {
+	net.Listener
+	ch     chan acceptResult
+	err    error
+	closed chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+}
+
+func newPersistentListener(l net.Listener) *persistentListener {
+	pl := &persistentListener{
+		Listener: l,
+		ch:       make(chan acceptResult),
+		closed:   make(chan struct{}),
+	}
+	pl.wg.Add(1)
+	go func() {
+		defer pl.wg.Done()
+		pl.run()
 	}()
+	return pl
+}
 
-	sz := make([]byte, 2)
-	b := make([]byte, 8192)
+func (l *persistentListener) run() {
+	defer close(l.ch)
 	for {
-		if _, err := io.ReadFull(ws, sz); err != nil {
-			if err == io.EOF {
-				return nil
-			}
-			return ioterr.New(err, "reading length header")
-		}
-		l := int(sz[0])<<8 | int(sz[1])
-		if cap(b) < l {
-			b = make([]byte, l)
-		}
-		b = b[:l]
-		if _, err := io.ReadFull(ws, b); err != nil {
-			if err == io.EOF {
-				return nil
-			}
-			return ioterr.New(err, "reading message")
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			l.err = err
+			return
 		}
-		m := &msg.Message{}
-		if err := proto.Unmarshal(b, m); err != nil {
-			if eh != nil {
-				eh.HandleError(ioterr.New(err, "unmarshaling message"))
-			}
-			continue
+		select {
+		case l.ch <- acceptResult{conn: conn}:
+		case <-l.closed:
+			_ = conn.Close()
+			return
 		}
-		switch m.Type {
-		case msg.Message_STREAM_RESET:
-			muConns.Lock()
-			if conn, ok := conns[m.StreamId]; ok {
-				_ = conn.Close()
-				delete(conns, m.StreamId)
-			}
-			muConns.Unlock()
+	}
+}
 
-		case msg.Message_SESSION_RESET:
-			muConns.Lock()
-			for id, c := range conns {
-				_ = c.Close()
-				delete(conns, id)
-			}
-			muConns.Unlock()
-			return io.EOF
-
-		case msg.Message_DATA:
-			muConns.Lock()
-			conn, ok := conns[m.StreamId]
-			muConns.Unlock()
-			if ok {
-				if _, err := conn.Write(m.Payload); err != nil {
-					eh.HandleError(ioterr.New(err, "writing message"))
-				}
-			}
+// Close closes the underlying listener.
+func (l *persistentListener) Close() error {
+	var err error
+	l.once.Do(func() {
+		close(l.closed)
+		err = l.Listener.Close()
+		l.wg.Wait()
+	})
+	return err
+}
+
+// session returns a listener to be used by one session.
+// Closing it stops accepting on the session without closing
+// the underlying listener.
+func (l *persistentListener) session() net.Listener {
+	return &sessionListener{
+		persistentListener: l,
+		closed:             make(chan struct{}),
+	}
+}
+
+type sessionListener struct {
+	*persistentListener
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (l *sessionListener) Accept() (net.Conn, error) {
+	select {
+	case r, ok := <-l.ch:
+		if !ok {
+			return nil, l.err
 		}
-		updateStat()
+		return r.conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
 	}
 }
+
+func (l *sessionListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
@@ -16,6 +16,7 @@ package tunnel
 
 import (
 	"sync"
+	"time"
 )
 
 // Stat is an interface to get and update the statistics of the proxy.
@@ -26,8 +27,71 @@ type Stat interface {
 }
 
 // Statistics stores proxy statistics data.
+// Sent and received are counted from the viewpoint of the local proxy:
+// sent is from the local connections to the tunnel and
+// received is from the tunnel to the local connections.
 type Statistics struct {
+	// NumConn is a number of active local connections.
 	NumConn int
+
+	// BytesSent and BytesReceived are total payload bytes of the data messages.
+	BytesSent     uint64
+	BytesReceived uint64
+	// MessagesSent and MessagesReceived are total numbers of the tunnel messages.
+	MessagesSent     uint64
+	MessagesReceived uint64
+
+	// StreamsStarted is a number of started streams and connections.
+	StreamsStarted uint64
+	// StreamResetsSent and StreamResetsReceived are numbers of
+	// stream and connection reset messages.
+	StreamResetsSent     uint64
+	StreamResetsReceived uint64
+	// DialFailures is a number of failures of dialing to the local destination.
+	DialFailures uint64
+	// StreamsRejected is a number of streams and connections rejected by StreamLimit.
+	StreamsRejected uint64
+
+	// Reconnects is a number of reconnections to the proxy endpoint.
+	Reconnects uint64
+	// ConnectedAt is a time when the WebSocket connection is established.
+	// Zero if disconnected.
+	ConnectedAt time.Time
+	// LastPingRTT is a round trip time of the last WebSocket ping.
+	LastPingRTT time.Duration
+
+	// Streams stores details of the active local connections.
+	Streams []StreamStatistics
+}
+
+// StreamStatistics stores statistics data of the local connection.
+type StreamStatistics struct {
+	ServiceID     string
+	StreamID      int32
+	ConnectionID  uint32
+	StartedAt     time.Time
+	BytesSent     uint64
+	BytesReceived uint64
+}
+
+func (s *Statistics) stream(k connKey) *StreamStatistics {
+	for i := range s.Streams {
+		st := &s.Streams[i]
+		if st.ServiceID == k.serviceID && st.StreamID == k.streamID && st.ConnectionID == k.connectionID {
+			return st
+		}
+	}
+	return nil
+}
+
+func (s *Statistics) removeStream(k connKey) {
+	for i := range s.Streams {
+		st := &s.Streams[i]
+		if st.ServiceID == k.serviceID && st.StreamID == k.streamID && st.ConnectionID == k.connectionID {
+			s.Streams = append(s.Streams[:i], s.Streams[i+1:]...)
+			return
+		}
+	}
 }
 
 // NewStat creates new Stat.
@@ -43,7 +107,12 @@ type stat struct {
 func (s *stat) Statistics() Statistics {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.stat
+	ret := s.stat
+	ret.Streams = nil
+	if len(s.stat.Streams) > 0 {
+		ret.Streams = append([]StreamStatistics(nil), s.stat.Streams...)
+	}
+	return ret
 }
 
 func (s *stat) Update(fn func(*Statistics)) {
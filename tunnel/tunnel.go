@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/at-wat/mqtt-go"
@@ -30,6 +31,10 @@ import (
 type Tunnel interface {
 	mqtt.Handler
 	OnError(func(error))
+	// Close stops all proxies started by the notifications
+	// and waits for them to be closed.
+	// Notifications received after Close are ignored.
+	Close() error
 }
 
 type tunnel struct {
@@ -39,6 +44,10 @@ type tunnel struct {
 	onError   func(err error)
 	dialerMap map[string]Dialer
 	opts      *Options
+
+	ctx    context.Context
+	cancel func()
+	wg     sync.WaitGroup
 }
 
 // Options stores options of the tunnel.
@@ -49,12 +58,28 @@ type Options struct {
 	TopicFunc func(operation string) string
 
 	// ProxyOptions stores slice of ProxyOptions for each service.
+	// Since the services share the connection, options of all requested
+	// services are applied in order of the services in the notification.
 	ProxyOptions map[string][]ProxyOption
+	// DefaultProxyOptions is applied to all services before ProxyOptions.
+	DefaultProxyOptions []ProxyOption
+	// NotificationFilters are called in order before starting the proxy.
+	NotificationFilters []NotificationFilter
 }
 
 // Option is a type of functional options.
 type Option func(*Options) error
 
+// WithProxyOptions appends ProxyOptions applied to all services.
+// e.g. WithProxyOptions(WithReconnect(DefaultReconnectPolicy)) enables
+// reconnection of the proxies started by the notification.
+func WithProxyOptions(opts ...ProxyOption) Option {
+	return func(o *Options) error {
+		o.DefaultProxyOptions = append(o.DefaultProxyOptions, opts...)
+		return nil
+	}
+}
+
 // ErrInvalidClientMode indicate that the requested client mode is not valid for the tunnel.
 var ErrInvalidClientMode = errors.New("invalid client mode")
 
@@ -68,6 +93,7 @@ func New(ctx context.Context, cli awsiotdev.Device, dialer map[string]Dialer, op
 		thingName: cli.ThingName(),
 		dialerMap: dialer,
 	}
+	t.ctx, t.cancel = context.WithCancel(context.Background())
 	t.opts = &Options{
 		TopicFunc:        t.topic,
 		EndpointHostFunc: endpointHost,
@@ -102,26 +128,60 @@ func (t *tunnel) notify(msg *mqtt.Message) {
 		t.handleError(ioterr.Newf(ErrInvalidClientMode, "requested %s", n.ClientMode))
 		return
 	}
+	dialers := make(map[string]Dialer)
+	opts := append(
+		[]ProxyOption{WithErrorHandler(ErrorHandlerFunc(t.handleError))},
+		t.opts.DefaultProxyOptions...,
+	)
 	for _, srv := range n.Services {
-		srv := srv
 		if d, ok := t.dialerMap[srv]; ok {
-			go func() {
-				opts := append(
-					[]ProxyOption{WithErrorHandler(ErrorHandlerFunc(t.handleError))},
-					t.opts.ProxyOptions[srv]...,
-				)
-				err := ProxyDestination(
-					d,
-					t.opts.EndpointHostFunc(n.Region),
-					n.ClientAccessToken,
-					opts...,
-				)
-				if err != nil {
-					t.handleError(ioterr.New(err, "creating proxy destination"))
-				}
-			}()
+			dialers[srv] = d
+			opts = append(opts, t.opts.ProxyOptions[srv]...)
 		}
 	}
+	if len(dialers) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ctx.Err() != nil {
+		return
+	}
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		for _, f := range t.opts.NotificationFilters {
+			if err := f(t.ctx, n); err != nil {
+				if t.ctx.Err() == nil {
+					t.handleError(ioterr.New(
+						fmt.Errorf("%w: %w", ErrTunnelRejected, err), "filtering notification",
+					))
+				}
+				return
+			}
+		}
+		// Services are multiplexed over the single connection
+		// on ProtocolV2 and later.
+		err := ProxyDestinationServicesContext(
+			t.ctx,
+			dialers,
+			t.opts.EndpointHostFunc(n.Region),
+			n.ClientAccessToken,
+			opts...,
+		)
+		if err != nil && t.ctx.Err() == nil {
+			t.handleError(ioterr.New(err, "creating proxy destination"))
+		}
+	}()
+}
+
+func (t *tunnel) Close() error {
+	t.mu.Lock()
+	t.cancel()
+	t.mu.Unlock()
+	t.wg.Wait()
+	return nil
 }
 
 func (t *tunnel) OnError(cb func(err error)) {
@@ -18,9 +18,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"golang.org/x/net/websocket"
+
 	mqtt "github.com/at-wat/mqtt-go"
 	mockmqtt "github.com/at-wat/mqtt-go/mock"
 
@@ -187,3 +193,78 @@ func TestHandlers_InvalidResponse(t *testing.T) {
 		t.Fatal("Timeout")
 	}
 }
+
+func TestTunnel_Close(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	chConnected := make(chan struct{}, 2)
+	chClosed := make(chan struct{}, 2)
+	ts := httptest.NewServer(websocket.Server{
+		Handshake: func(cfg *websocket.Config, r *http.Request) error {
+			cfg.Protocol = []string{ProtocolV1.Subprotocol()}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			chConnected <- struct{}{}
+			_, _ = io.Copy(io.Discard, ws)
+			chClosed <- struct{}{}
+		},
+	})
+	defer ts.Close()
+
+	cli := &mockDevice{mockClient: &mockmqtt.Client{}}
+	tu, err := New(ctx, cli,
+		map[string]Dialer{
+			"ssh": func() (io.ReadWriteCloser, error) { return nil, errConnect },
+		},
+		func(opts *Options) error {
+			opts.EndpointHostFunc = func(string) string {
+				return strings.TrimPrefix(ts.URL, "http://")
+			}
+			opts.ProxyOptions["ssh"] = []ProxyOption{
+				func(opt *ProxyOptions) error {
+					opt.Scheme = "ws"
+					return nil
+				},
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tu.OnError(func(err error) { t.Errorf("Unexpected error: %v", err) })
+	cli.Handle(tu)
+
+	notify := func() {
+		cli.Serve(&mqtt.Message{
+			Topic:   "$aws/things/test/tunnels/notify",
+			Payload: []byte(`{"clientMode": "destination", "services": ["ssh"]}`),
+		})
+	}
+	notify()
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("Timeout")
+	case <-chConnected:
+	}
+
+	if err := tu.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+		t.Fatal("Timeout")
+	case <-chClosed:
+	}
+
+	// Notification after Close must be ignored.
+	notify()
+	select {
+	case <-time.After(50 * time.Millisecond):
+	case <-chConnected:
+		t.Error("Proxy must not be started after Close")
+	}
+}